@@ -3,6 +3,7 @@ package functions
 import (
 	"SiteChecker/models"
 	"context"
+	"strings"
 	"time"
 
 	"github.com/chromedp/cdproto/emulation"
@@ -11,7 +12,31 @@ import (
 )
 
 func RunScan(req models.ScanRequest) (*models.ScanResponse, error) {
-	browserCtx, cancelBrowser := newBrowserCtx(context.Background())
+	return RunScanContext(context.Background(), req)
+}
+
+// resolveScanContext: پروکسی و پروفایل احراز هویت یک‌بار resolve و روی ctx گذاشته می‌شوند تا
+// همهٔ مراحل (صفحه، sinks، verify، prototype و صفحات crawl) از همان استفاده کنند
+func resolveScanContext(ctx context.Context, req models.ScanRequest) (context.Context, error) {
+	if scanProxyFrom(ctx) == nil {
+		proxy, err := ResolveProxy(ctx, req)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = withScanProxy(ctx, proxy)
+	}
+	return resolveScanAuth(ctx, req)
+}
+
+// RunScanContext مثل RunScan است ولی با لغو شدن ctx مرورگر هم بسته می‌شود
+func RunScanContext(ctx context.Context, req models.ScanRequest) (*models.ScanResponse, error) {
+	ctx, err := resolveScanContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	proxy := scanProxyFrom(ctx)
+
+	browserCtx, cancelBrowser := newBrowserCtx(ctx, proxy)
 	defer cancelBrowser()
 
 	if req.WaitSec <= 0 {
@@ -27,17 +52,27 @@ func RunScan(req models.ScanRequest) (*models.ScanResponse, error) {
 	var (
 		resourcesJS []string
 		pageHTML    string
+		pageTitle   string
 		scriptSrcs  []string
 	)
 
-	scriptsMap, err := CollectScripts(timeoutCtx)
+	netLog := startNetworkCapture(timeoutCtx, req.HARBodies)
+
+	var scriptCol *ScriptCollector
+	err = scanStage(ctx, "browser", func() error {
+		var err error
+		scriptCol, err = CollectScripts(timeoutCtx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	ua := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
 
-	err = chromedp.Run(timeoutCtx,
+	// هدرهای پروفایل فقط روی درخواست‌های هم‌سایت (نه CDN/آنالیتیکس شخص ثالث)
+	auth := scanAuthFrom(ctx)
+	prep := append(tabSetup(timeoutCtx, scanTabOptions(timeoutCtx, req.URL)),
 		network.Enable(),
 		network.SetExtraHTTPHeaders(network.Headers{
 			"Accept-Language":           "en-US,en;q=0.9",
@@ -55,44 +90,140 @@ func RunScan(req models.ScanRequest) (*models.ScanResponse, error) {
 					Mobile:          false,
 				}).Do(c)
 		}),
+	)
 
-		chromedp.Evaluate(`Object.defineProperty(navigator,'webdriver',{get:()=>undefined})`, nil),
+	if auth != nil && (len(auth.Cookies) > 0 || len(auth.LoginSteps) > 0) {
+		err = scanStage(ctx, "login", func() error {
+			return chromedp.Run(timeoutCtx, append(prep, authActions(auth, req.URL)...)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		InstallSourceURLHooks(),
-		InstallRuntimePostMessageHook(),
-		chromedp.Navigate(req.URL),
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(time.Duration(req.WaitSec)*time.Second),
+	err = scanStage(ctx, "navigate", func() error {
+		return chromedp.Run(timeoutCtx, append(prep,
+			chromedp.Evaluate(`Object.defineProperty(navigator,'webdriver',{get:()=>undefined})`, nil),
 
-		chromedp.EvaluateAsDevTools(`performance.getEntriesByType('resource').map(r => r.name)`, &resourcesJS),
-		chromedp.OuterHTML("html", &pageHTML, chromedp.ByQuery),
-		chromedp.EvaluateAsDevTools(`Array.from(document.querySelectorAll('script[src]')).map(s => new URL(s.src, location.href).href)`, &scriptSrcs),
-	)
+			InstallSourceURLHooks(),
+			chromedp.Navigate(req.URL),
+			chromedp.WaitReady("body", chromedp.ByQuery),
+		)...)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	paths := extractPathsFromHTML(pageHTML)
+	err = scanStage(ctx, "wait", func() error {
+		return chromedp.Run(timeoutCtx, chromedp.Sleep(time.Duration(req.WaitSec)*time.Second))
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	for _, code := range scriptsMap {
-		if code != "" {
-			paths = append(paths, extractPathsFromHTML(code)...)
+	err = scanStage(ctx, "collect", func() error {
+		return chromedp.Run(timeoutCtx,
+			chromedp.EvaluateAsDevTools(`performance.getEntriesByType('resource').map(r => r.name)`, &resourcesJS),
+			chromedp.OuterHTML("html", &pageHTML, chromedp.ByQuery),
+			chromedp.Title(&pageTitle),
+			chromedp.EvaluateAsDevTools(`Array.from(document.querySelectorAll('script[src]')).map(s => new URL(s.src, location.href).href)`, &scriptSrcs),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// لاگ شبکه قبل از fetch_scripts گرفته می‌شود تا fetchهای خود اسکنر قاطی نشوند
+	if req.HARBodies {
+		netLog.waitBodies(5 * time.Second)
+	}
+	requests := netLog.entries()
+	pageTimings := netLog.timings()
+	scriptsMap, reportedMaps := scriptCol.snapshot(5 * time.Second)
+	for _, nr := range requests {
+		if strings.HasPrefix(nr.URL, "http://") || strings.HasPrefix(nr.URL, "https://") {
+			resourcesJS = append(resourcesJS, nr.URL)
 		}
 	}
 
-	extraPaths, errorsList := fetchAndExtractFromScripts(timeoutCtx, scriptSrcs, req.JSFetchTimeout)
-	paths = append(paths, extraPaths...)
+	hints := endpointHints{}
+	_ = scanStage(ctx, "extract", func() error {
+		for _, p := range extractPathsFromHTML(pageHTML) {
+			hints.add(p, ExtractorRegex, "", "")
+		}
+		for _, code := range scriptsMap {
+			if code != "" {
+				_ = extractEndpointsFromJS(code, hints)
+			}
+		}
+		return nil
+	})
+
+	var smRes sourceMapResult
+	_ = scanStage(ctx, "sourcemaps", func() error {
+		siteID, urlNorm, _ := PageKeys(req.URL)
+		smRes = analyzeSourceMaps(ctx, urlNorm, siteID, scriptsMap, reportedMaps, requests, req.JSFetchTimeout)
+		hints.merge(smRes.Hints)
+		return nil
+	})
+
+	var errorsList []string
+	_ = scanStage(ctx, "fetch_scripts", func() error {
+		var extraPaths []string
+		extraPaths, errorsList = fetchAndExtractFromScripts(timeoutCtx, scriptSrcs, req.JSFetchTimeout)
+		for _, p := range extraPaths {
+			hints.add(p, ExtractorRegex, "", "")
+		}
+		return nil
+	})
+	paths := hints.paths()
+
+	var secrets []models.SecretDoc
+	_ = scanStage(ctx, "secrets", func() error {
+		siteID, urlNorm, _ := PageKeys(req.URL)
+		secrets = ScanSecrets(pageHTML, scriptsMap, urlNorm, siteID)
+		return nil
+	})
+
+	findings := smRes.Findings
+	_ = scanStage(ctx, "prototype", func() error {
+		siteID, urlNorm, _ := PageKeys(req.URL)
+		findings = append(findings, scanProtoPollutionPatterns(scriptsMap, urlNorm, siteID)...)
+		return nil
+	})
+	var gql *models.GraphQLResult
+	_ = scanStage(ctx, "graphql", func() error {
+		gql = analyzeGraphQL(req.URL, scriptsMap, requests)
+		if gql == nil || !req.GraphQLIntrospect {
+			return nil
+		}
+		siteID, _, _ := PageKeys(req.URL)
+		schemas, gqlFindings := introspectGraphQL(ctx, siteID, gql.Endpoints, auth, req.JSFetchTimeout)
+		gql.Schemas = schemas
+		findings = append(findings, gqlFindings...)
+		return nil
+	})
 
 	// Dedup
-	paths = uniqueStrings(paths)
 	resourcesJS = uniqueStrings(resourcesJS)
 	scriptSrcs = uniqueStrings(scriptSrcs)
 
 	return &models.ScanResponse{
-		URL:         req.URL,
-		Resources:   resourcesJS,
-		UniquePaths: paths,
-		AllScripts:  scriptSrcs,
-		Errors:      errorsList,
+		URL:           req.URL,
+		Resources:     resourcesJS,
+		UniquePaths:   paths,
+		EndpointHints: hints,
+		AllScripts:    scriptSrcs,
+		Errors:        errorsList,
+		Requests:      requests,
+		Title:         pageTitle,
+		PageTimings:   &pageTimings,
+		Proxy:         RedactProxy(proxy),
+		SourceMaps:    smRes.Infos,
+		Findings:      findings,
+		MappedSinks:   smRes.Sinks,
+		GraphQL:       gql,
+		Secrets:       secrets,
+		HTML:          pageHTML,
+		ScriptBodies:  scriptsMap,
 	}, nil
 }
@@ -0,0 +1,192 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetAuthProfile: پروفایل کامل (بدون ماسک) برای استفاده در اسکن
+func GetAuthProfile(ctx context.Context, id string) (*models.AuthProfileDoc, error) {
+	var p models.AuthProfileDoc
+	if err := models.AuthProfilesColl().FindOne(ctx, bson.M{"_id": id}).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ErrAuthProfileSite: پروفایل برای سایت دیگری تعریف شده و نباید به این URL فرستاده شود
+var ErrAuthProfileSite = errors.New("auth profile belongs to another site")
+
+// AuthProfileForURL: پروفایل به شرطی که site_id آن با سایت URL اسکن یکی باشد
+func AuthProfileForURL(ctx context.Context, id, rawURL string) (*models.AuthProfileDoc, error) {
+	p, err := GetAuthProfile(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	siteID, _, err := PageKeys(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if !sameETLDPlusOne(p.SiteID, siteID) {
+		return nil, fmt.Errorf("%w (%s, scanning %s)", ErrAuthProfileSite, p.SiteID, siteID)
+	}
+	return p, nil
+}
+
+type scanAuthKey struct{}
+
+// resolveScanAuth: پروفایل درخواست یک‌بار خوانده و روی ctx گذاشته می‌شود تا همهٔ تب‌های اسکن
+// (صفحه، sinks، verify، prototype) از همان استفاده کنند
+func resolveScanAuth(ctx context.Context, req models.ScanRequest) (context.Context, error) {
+	if req.AuthProfileID == "" || scanAuthFrom(ctx) != nil {
+		return ctx, nil
+	}
+	p, err := AuthProfileForURL(ctx, req.AuthProfileID, req.URL)
+	if err != nil {
+		return ctx, fmt.Errorf("auth profile %s: %w", req.AuthProfileID, err)
+	}
+	return context.WithValue(ctx, scanAuthKey{}, p), nil
+}
+
+func scanAuthFrom(ctx context.Context) *models.AuthProfileDoc {
+	p, _ := ctx.Value(scanAuthKey{}).(*models.AuthProfileDoc)
+	return p
+}
+
+// ValidateAuthProfile: فیلدهای لازم هر قدم و کوکی را بررسی می‌کند
+func ValidateAuthProfile(p *models.AuthProfileDoc) error {
+	if strings.TrimSpace(p.SiteID) == "" {
+		return errors.New("site_id is required")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	for i, c := range p.Cookies {
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("cookies[%d]: name is required", i)
+		}
+		switch c.SameSite {
+		case "", "Strict", "Lax", "None":
+		default:
+			return fmt.Errorf("cookies[%d]: invalid same_site %q", i, c.SameSite)
+		}
+	}
+	for i, s := range p.LoginSteps {
+		switch s.Action {
+		case models.StepNavigate:
+			if s.URL == "" {
+				return fmt.Errorf("login_steps[%d]: url is required", i)
+			}
+		case models.StepFill:
+			if s.Selector == "" {
+				return fmt.Errorf("login_steps[%d]: selector is required", i)
+			}
+		case models.StepClick, models.StepWait:
+			if s.Selector == "" {
+				return fmt.Errorf("login_steps[%d]: selector is required", i)
+			}
+		case models.StepSleep:
+			if s.TimeoutSec <= 0 {
+				return fmt.Errorf("login_steps[%d]: timeout_sec is required", i)
+			}
+		default:
+			return fmt.Errorf("login_steps[%d]: invalid action %q", i, s.Action)
+		}
+	}
+	return nil
+}
+
+// applyAuthHeaders: هدرهای پروفایل (و Authorization برای bearer) روی هدرهای پیش‌فرض اسکن
+func applyAuthHeaders(h network.Headers, p *models.AuthProfileDoc) {
+	if p == nil {
+		return
+	}
+	for k, v := range p.Headers {
+		h[k] = v
+	}
+	if p.BearerToken != "" {
+		h["Authorization"] = "Bearer " + p.BearerToken
+	}
+}
+
+// authActions: کوکی‌ها و سپس قدم‌های لاگین؛ باید قبل از Navigate اصلی اجرا شود
+func authActions(p *models.AuthProfileDoc, targetURL string) []chromedp.Action {
+	var acts []chromedp.Action
+	if len(p.Cookies) > 0 {
+		params := make([]*network.CookieParam, 0, len(p.Cookies))
+		for _, c := range p.Cookies {
+			cp := &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HTTPOnly,
+				SameSite: network.CookieSameSite(c.SameSite),
+			}
+			if cp.Domain == "" {
+				cp.URL = targetURL
+			}
+			if cp.Path == "" {
+				cp.Path = "/"
+			}
+			params = append(params, cp)
+		}
+		acts = append(acts, network.SetCookies(params))
+	}
+
+	base, _ := url.Parse(targetURL)
+	for i, s := range p.LoginSteps {
+		acts = append(acts, loginStepAction(i, s, base))
+	}
+	return acts
+}
+
+func loginStepAction(i int, s models.LoginStep, base *url.URL) chromedp.Action {
+	timeout := time.Duration(s.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	var inner []chromedp.Action
+	switch s.Action {
+	case models.StepNavigate:
+		target := s.URL
+		if ref, err := url.Parse(s.URL); err == nil && base != nil {
+			target = base.ResolveReference(ref).String()
+		}
+		inner = []chromedp.Action{chromedp.Navigate(target), chromedp.WaitReady("body", chromedp.ByQuery)}
+	case models.StepFill:
+		inner = []chromedp.Action{
+			chromedp.WaitVisible(s.Selector, chromedp.ByQuery),
+			chromedp.Clear(s.Selector, chromedp.ByQuery),
+			chromedp.SendKeys(s.Selector, s.Value, chromedp.ByQuery),
+		}
+	case models.StepClick:
+		inner = []chromedp.Action{chromedp.Click(s.Selector, chromedp.ByQuery)}
+	case models.StepWait:
+		inner = []chromedp.Action{chromedp.WaitVisible(s.Selector, chromedp.ByQuery)}
+	case models.StepSleep:
+		inner = []chromedp.Action{chromedp.Sleep(timeout)}
+		timeout += 5 * time.Second
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		for _, a := range inner {
+			if err := a.Do(stepCtx); err != nil {
+				return fmt.Errorf("login step %d (%s %s): %w", i+1, s.Action, s.Selector+s.URL, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,258 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// BrowserPool چند پروسهٔ Chromium بلندمدت نگه می‌دارد و برای هر اسکن یک
+// BrowserContext ایزوله (مثل incognito) + تب جدید روی یکی از آن‌ها می‌سازد.
+type BrowserPool struct {
+	ctx     context.Context
+	size    int
+	maxUses int
+
+	mu        sync.Mutex
+	browsers  []*pooledBrowser
+	nextID    int
+	stats     BrowserPoolCounters
+	launching int        // مرورگرهایی که بیرون از قفل در حال بالا آمدن‌اند
+	launched  *sync.Cond // بعد از پایان هر launch (موفق یا ناموفق) broadcast می‌شود
+}
+
+type pooledBrowser struct {
+	id        int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	uses      int
+	active    int
+	startedAt time.Time
+	retiring  bool
+	closing   bool
+}
+
+type BrowserPoolCounters struct {
+	Launched  int64 `json:"launched"`
+	Acquired  int64 `json:"acquired"`
+	Recycled  int64 `json:"recycled"`
+	Crashes   int64 `json:"crashes"`
+	Fallbacks int64 `json:"fallbacks"`
+}
+
+type BrowserStats struct {
+	ID        int       `json:"id"`
+	Uses      int       `json:"uses"`
+	Active    int       `json:"active"`
+	Retiring  bool      `json:"retiring"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type BrowserPoolStats struct {
+	Enabled  bool                `json:"enabled"`
+	Size     int                 `json:"size"`
+	MaxUses  int                 `json:"max_uses"`
+	Browsers []BrowserStats      `json:"browsers"`
+	Counters BrowserPoolCounters `json:"counters"`
+}
+
+var browserPool *BrowserPool
+
+// StartBrowserPool: پول را فعال می‌کند؛ مرورگرها lazy و در اولین درخواست بالا می‌آیند
+func StartBrowserPool(ctx context.Context) {
+	browserPool = &BrowserPool{
+		ctx:     ctx,
+		size:    envInt("BROWSER_POOL_SIZE", 2),
+		maxUses: envInt("BROWSER_MAX_USES", 50),
+	}
+	browserPool.launched = sync.NewCond(&browserPool.mu)
+	log.Printf("[browser-pool] size=%d max_uses=%d", browserPool.size, browserPool.maxUses)
+}
+
+// GetBrowserPoolStats: وضعیت فعلی پول برای API
+func GetBrowserPoolStats() BrowserPoolStats {
+	p := browserPool
+	if p == nil {
+		return BrowserPoolStats{Browsers: []BrowserStats{}}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := BrowserPoolStats{
+		Enabled:  true,
+		Size:     p.size,
+		MaxUses:  p.maxUses,
+		Browsers: make([]BrowserStats, 0, len(p.browsers)),
+		Counters: p.stats,
+	}
+	for _, b := range p.browsers {
+		out.Browsers = append(out.Browsers, BrowserStats{
+			ID: b.id, Uses: b.uses, Active: b.active, Retiring: b.retiring, StartedAt: b.startedAt,
+		})
+	}
+	return out
+}
+
+// acquire: یک تب ایزوله روی کم‌مشغله‌ترین مرورگر سالم
+func (p *BrowserPool) acquire(parent context.Context, opts ...chromedp.CreateBrowserContextOption) (context.Context, context.CancelFunc, error) {
+	b, err := p.pick()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tabCtx, cancelTab := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext(opts...))
+	// لغو و chromedp از تب، بقیهٔ مقادیر (مثل شناسهٔ job) از ctx فراخواننده
+	merged := valueFallbackCtx{Context: tabCtx, fallback: parent}
+	stop := context.AfterFunc(parent, cancelTab)
+
+	var once sync.Once
+	return merged, func() {
+		once.Do(func() {
+			stop()
+			cancelTab()
+			p.release(b)
+		})
+	}, nil
+}
+
+func (p *BrowserPool) pick() (*pooledBrowser, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		best, alive := p.bestLocked()
+		// مرورگر موجود، مگر همه مشغول باشند و جا برای مرورگر تازه داشته باشیم
+		if best != nil && (best.active == 0 || alive+p.launching >= p.size) {
+			return p.claimLocked(best), nil
+		}
+		if best == nil && p.launching > 0 {
+			// مرورگر سالمی نیست ولی یکی در حال بالا آمدن است؛ منتظرش می‌مانیم
+			p.launched.Wait()
+			continue
+		}
+
+		// بالا آمدن Chromium چند ثانیه طول می‌کشد؛ قفل را نگه نمی‌داریم تا acquire/release بقیه بلاک نشود
+		p.launching++
+		p.mu.Unlock()
+		nb, err := p.launch()
+		p.mu.Lock()
+		p.launching--
+		p.launched.Broadcast()
+		if err == nil {
+			p.publishLocked(nb)
+			return p.claimLocked(nb), nil
+		}
+		// وضعیت پول در این فاصله عوض شده؛ best قبلی ممکن است دیگر سالم نباشد
+		if best, _ = p.bestLocked(); best == nil {
+			return nil, err
+		}
+		return p.claimLocked(best), nil
+	}
+}
+
+// bestLocked: کم‌مشغله‌ترین مرورگر سالم و تعداد مرورگرهای سالم
+func (p *BrowserPool) bestLocked() (*pooledBrowser, int) {
+	var best *pooledBrowser
+	alive := 0
+	for _, b := range p.browsers {
+		if b.retiring || b.closing || b.ctx.Err() != nil {
+			continue
+		}
+		alive++
+		if best == nil || b.active < best.active {
+			best = b
+		}
+	}
+	return best, alive
+}
+
+func (p *BrowserPool) claimLocked(b *pooledBrowser) *pooledBrowser {
+	b.uses++
+	b.active++
+	p.stats.Acquired++
+	if b.uses >= p.maxUses {
+		b.retiring = true
+	}
+	return b
+}
+
+// launch: پروسهٔ تازه را بدون p.mu بالا می‌آورد؛ publishLocked آن را به پول اضافه می‌کند
+func (p *BrowserPool) launch() (*pooledBrowser, error) {
+	if p.ctx.Err() != nil {
+		return nil, errors.New("browser pool stopped")
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(p.ctx, browserAllocOptions()...)
+	bctx, cancelB := chromedp.NewContext(allocCtx)
+	// اولین Run خود پروسه را بالا می‌آورد
+	if err := chromedp.Run(bctx); err != nil {
+		cancelB()
+		cancelAlloc()
+		return nil, err
+	}
+	return &pooledBrowser{
+		ctx:       bctx,
+		startedAt: time.Now(),
+		cancel: func() {
+			cancelB()
+			cancelAlloc()
+		},
+	}, nil
+}
+
+// publishLocked: باید با p.mu گرفته‌شده صدا زده شود
+func (p *BrowserPool) publishLocked(b *pooledBrowser) {
+	p.nextID++
+	b.id = p.nextID
+	p.browsers = append(p.browsers, b)
+	p.stats.Launched++
+	go p.watch(b)
+}
+
+// watch: اگر پروسهٔ مرورگر بدون درخواست ما بسته شد (crash)، آن را از پول حذف می‌کند
+func (p *BrowserPool) watch(b *pooledBrowser) {
+	<-b.ctx.Done()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !b.closing && p.ctx.Err() == nil {
+		p.stats.Crashes++
+		log.Printf("[browser-pool] browser #%d exited unexpectedly (uses=%d)", b.id, b.uses)
+	}
+	b.cancel()
+	p.removeLocked(b)
+}
+
+func (p *BrowserPool) release(b *pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.active--
+	if b.retiring && b.active <= 0 && !b.closing {
+		b.closing = true
+		p.stats.Recycled++
+		p.removeLocked(b)
+		go b.cancel()
+	}
+}
+
+func (p *BrowserPool) removeLocked(b *pooledBrowser) {
+	for i, x := range p.browsers {
+		if x == b {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			return
+		}
+	}
+}
+
+type valueFallbackCtx struct {
+	context.Context
+	fallback context.Context
+}
+
+func (c valueFallbackCtx) Value(key any) any {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.fallback.Value(key)
+}
@@ -0,0 +1,321 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// دسته‌هایی از مسیرهای کشف‌شده که ارزش باز کردن به‌عنوان صفحه را دارند
+var crawlCategories = map[string]bool{"routes": true, "html": true, "php": true, "aspx": true}
+
+type crawlFilter struct {
+	opts     models.CrawlOptions
+	rootHost string
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+}
+
+// NormalizeCrawlOptions: مقادیر پیش‌فرض و سقف‌ها را اعمال و regexها را اعتبارسنجی می‌کند
+func NormalizeCrawlOptions(o *models.CrawlOptions) error {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = 2
+	}
+	if o.MaxDepth > 5 {
+		o.MaxDepth = 5
+	}
+	if o.MaxPages <= 0 {
+		o.MaxPages = 20
+	}
+	if o.MaxPages > 200 {
+		o.MaxPages = 200
+	}
+	switch o.Scope {
+	case "":
+		o.Scope = models.CrawlScopeHost
+	case models.CrawlScopeHost, models.CrawlScopeSite:
+	default:
+		return fmt.Errorf("invalid crawl scope %q (host|site)", o.Scope)
+	}
+	for _, p := range append(append([]string{}, o.Include...), o.Exclude...) {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid crawl pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func newCrawlFilter(rootURL string, o models.CrawlOptions) (*crawlFilter, error) {
+	u, err := url.Parse(rootURL)
+	if err != nil {
+		return nil, err
+	}
+	f := &crawlFilter{opts: o, rootHost: strings.ToLower(u.Hostname())}
+	for _, p := range o.Include {
+		f.include = append(f.include, regexp.MustCompile(p))
+	}
+	for _, p := range o.Exclude {
+		f.exclude = append(f.exclude, regexp.MustCompile(p))
+	}
+	return f, nil
+}
+
+func (f *crawlFilter) allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	h := strings.ToLower(u.Hostname())
+	if f.opts.Scope == models.CrawlScopeSite {
+		if !sameETLDPlusOne(h, f.rootHost) {
+			return false
+		}
+	} else if h != f.rootHost {
+		return false
+	}
+	target := u.RequestURI()
+	for _, re := range f.exclude {
+		if re.MatchString(target) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlLinks: مسیرهای قابل پیمایش یک صفحه به‌صورت URL مطلق (بدون fragment)
+func (f *crawlFilter) crawlLinks(pageURL string, paths []string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, p := range paths {
+		if !crawlCategories[categorize(base.Hostname(), p)] {
+			continue
+		}
+		ref, err := url.Parse(p)
+		if err != nil {
+			continue
+		}
+		abs := base.ResolveReference(ref)
+		abs.Fragment = ""
+		abs.RawFragment = ""
+		if !f.allowed(abs) {
+			continue
+		}
+		out = append(out, abs.String())
+	}
+	return uniqueStrings(out)
+}
+
+type crawlItem struct {
+	url, parent string
+	depth       int
+}
+
+// runCrawlJob: پیمایش BFS از URL ریشه؛ هر صفحه PageDoc خودش را دارد و CrawlDoc همه را به هم وصل می‌کند
+func runCrawlJob(ctx context.Context, job *models.ScanJobDoc) {
+	req := job.Request
+	opts := *req.Crawl
+	if err := NormalizeCrawlOptions(&opts); err != nil {
+		failCrawlJob(ctx, job, err.Error())
+		return
+	}
+	filter, err := newCrawlFilter(req.URL, opts)
+	if err != nil {
+		failCrawlJob(ctx, job, err.Error())
+		return
+	}
+
+	crawl, err := startCrawlDoc(ctx, job, opts)
+	if err != nil {
+		failCrawlJob(ctx, job, "crawl start: "+err.Error())
+		return
+	}
+	if crawl.ID != job.CrawlID {
+		job.CrawlID = crawl.ID
+		setScanJob(ctx, job.ID, bson.M{"crawl_id": crawl.ID})
+	}
+	// خاموش شدن سرور: job دوباره صف می‌شود ولی CrawlDoc تا آن موقع running نمی‌ماند
+	defer func() {
+		if ctx.Err() != nil {
+			setCrawlFailed(ctx, crawl.ID, "interrupted: "+ctx.Err().Error())
+		}
+	}()
+
+	_, rootNorm, _ := PageKeys(req.URL)
+	visited := map[string]bool{rootNorm: true}
+	queue := []crawlItem{{url: req.URL}}
+	result := models.ScanJobResult{}
+	var scanErrors []string
+	scanned := 0
+
+	for len(queue) > 0 && scanned < opts.MaxPages {
+		if ctx.Err() != nil {
+			return
+		}
+		it := queue[0]
+		queue = queue[1:]
+		scanned++
+
+		_, norm, _ := PageKeys(it.url)
+		page := models.CrawlPage{URL: it.url, URLNorm: norm, Parent: it.parent, Depth: it.depth, Status: models.JobDone}
+		start := time.Now()
+
+		var resp *models.ScanResponse
+		err := scanStageURL(ctx, "crawl_page", it.url, func() error {
+			pageReq := req
+			pageReq.URL = it.url
+			pageReq.Crawl = nil
+			var err error
+			resp, err = RunScanContext(ctx, pageReq)
+			if err != nil {
+				return err
+			}
+			resp.CrawlID = crawl.ID
+			resp.ProcessedAt = time.Now().Format(time.RFC3339)
+			resp.PageDuration = time.Since(start).String()
+
+			saveCtx, cancelSave := context.WithTimeout(ctx, 10*time.Second)
+			defer cancelSave()
+			if err := SaveScanResponse(saveCtx, resp); err != nil {
+				return fmt.Errorf("save error: %w", err)
+			}
+			sinksCtx, cancelSinks := context.WithTimeout(ctx, 60*time.Second)
+			defer cancelSinks()
+			result.Sinks += ScanAndPersistSinks(sinksCtx, it.url, job.SiteID, norm, resp)
+			if req.VerifyXSS {
+				verifyCtx, cancelVerify := context.WithTimeout(ctx, 3*time.Minute)
+				defer cancelVerify()
+				result.Verified += VerifyAndPersistXSS(verifyCtx, it.url, job.SiteID, norm)
+			}
+			if req.PrototypePollution {
+				ppCtx, cancelPP := context.WithTimeout(ctx, 2*time.Minute)
+				defer cancelPP()
+				result.Polluted += CheckAndPersistPrototypePollution(ppCtx, it.url, job.SiteID, norm)
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		page.DurationMs = time.Since(start).Milliseconds()
+
+		inc := bson.M{"pages_done": 1}
+		discovered, skipped := 0, 0
+		if err != nil {
+			page.Status = models.JobFailed
+			page.Error = err.Error()
+			inc = bson.M{"pages_failed": 1}
+			log.Printf("[crawl] page error crawl=%s url=%s err=%v", crawl.ID, it.url, err)
+		} else {
+			page.Endpoints = len(resp.UniquePaths)
+			result.Resources += len(resp.Resources)
+			result.Endpoints += len(resp.UniquePaths)
+			result.Scripts += len(resp.AllScripts)
+			scanErrors = append(scanErrors, resp.Errors...)
+
+			for _, link := range filter.crawlLinks(it.url, resp.UniquePaths) {
+				_, ln, err := PageKeys(link)
+				if err != nil || visited[ln] {
+					continue
+				}
+				visited[ln] = true
+				discovered++
+				if it.depth+1 > opts.MaxDepth || len(queue)+scanned >= opts.MaxPages {
+					skipped++
+					continue
+				}
+				queue = append(queue, crawlItem{url: link, parent: it.url, depth: it.depth + 1})
+				page.Found++
+			}
+			inc["discovered"] = discovered
+			inc["skipped"] = skipped
+		}
+
+		_, _ = models.CrawlsColl().UpdateByID(ctx, crawl.ID, bson.M{
+			"$push": bson.M{"pages": page},
+			"$inc":  inc,
+		})
+	}
+
+	finished := time.Now()
+	_, _ = models.CrawlsColl().UpdateByID(ctx, crawl.ID, bson.M{"$set": bson.M{
+		"state":       models.JobDone,
+		"finished_at": finished,
+	}})
+
+	result.Duration = finished.Sub(job.StartedAt).String()
+	setScanJob(ctx, job.ID, bson.M{
+		"state":       models.JobDone,
+		"result":      result,
+		"scan_errors": scanErrors,
+		"finished_at": finished,
+		"timings":     models.ScanJobTimings{QueuedMs: job.StartedAt.Sub(job.CreatedAt).Milliseconds(), TotalMs: finished.Sub(job.StartedAt).Milliseconds()},
+		"page_link":   "/api/crawls/" + crawl.ID,
+	})
+}
+
+// startCrawlDoc: هر job یک CrawlDoc دارد؛ job که بعد از ری‌استارت دوباره صف شده همان سند را از نو شروع می‌کند
+func startCrawlDoc(ctx context.Context, job *models.ScanJobDoc, opts models.CrawlOptions) (models.CrawlDoc, error) {
+	var crawl models.CrawlDoc
+	err := models.CrawlsColl().FindOneAndUpdate(ctx,
+		bson.M{"job_id": job.ID},
+		bson.M{
+			"$set": bson.M{
+				"site_id":      job.SiteID,
+				"root_url":     job.Request.URL,
+				"options":      opts,
+				"state":        models.JobRunning,
+				"pages":        []models.CrawlPage{},
+				"pages_done":   0,
+				"pages_failed": 0,
+				"discovered":   0,
+				"skipped":      0,
+				"started_at":   time.Now(),
+			},
+			"$unset":       bson.M{"finished_at": "", "error": ""},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID().Hex()},
+		},
+		mopts.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(mopts.After),
+	).Decode(&crawl)
+	return crawl, err
+}
+
+// setCrawlFailed: با ctx لغوشده هم ثبت می‌شود
+func setCrawlFailed(ctx context.Context, crawlID, msg string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_, err := models.CrawlsColl().UpdateByID(ctx, crawlID, bson.M{"$set": bson.M{
+		"state":       models.JobFailed,
+		"error":       msg,
+		"finished_at": time.Now(),
+	}})
+	if err != nil {
+		log.Printf("[crawl] update crawl=%s err=%v", crawlID, err)
+	}
+}
+
+// failCrawlJob: job و (اگر از اجرای قبلی مانده) CrawlDoc آن هر دو failed می‌شوند
+func failCrawlJob(ctx context.Context, job *models.ScanJobDoc, msg string) {
+	if job.CrawlID != "" {
+		setCrawlFailed(ctx, job.CrawlID, msg)
+	}
+	setScanJob(ctx, job.ID, bson.M{"state": models.JobFailed, "error": msg, "finished_at": time.Now()})
+}
@@ -0,0 +1,144 @@
+package functions
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	tmplPlaceholderRe = regexp.MustCompile(`^\{([A-Za-z_$][\w$]*)\}$`)
+	tmplNumericRe     = regexp.MustCompile(`^\d+$`)
+	tmplUUIDRe        = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	tmplHashRe        = regexp.MustCompile(`^[0-9a-fA-F]{16,128}$`)
+	tmplDateRe        = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[T_ ]\d{2}:?\d{2}(?::?\d{2})?(?:\.\d+)?Z?)?$`)
+	tmplSlugRe        = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+){2,}$`)
+	tmplDigitRe       = regexp.MustCompile(`\d`)
+)
+
+// EndpointTemplate: قالب یک مسیر (مثل /api/users/{id}) به‌همراه پارامترها
+type EndpointTemplate struct {
+	Template    string            // بدون query string
+	PathParams  []string          // به ترتیب ظاهر شدن
+	QueryParams []string          // مرتب‌شده
+	Examples    map[string]string // param → مقدار نمونه (برای پارامترهای مسیر و query)
+}
+
+// TemplateEndpoint: شناسه‌ها (عدد، UUID، hash، تاریخ، slug) را با {name} جایگزین می‌کند
+func TemplateEndpoint(ep string) EndpointTemplate {
+	out := EndpointTemplate{Examples: map[string]string{}}
+
+	prefix, rest := "", ep
+	if strings.HasPrefix(ep, "http://") || strings.HasPrefix(ep, "https://") {
+		if u, err := url.Parse(ep); err == nil {
+			prefix = u.Scheme + "://" + u.Host
+			rest = u.RequestURI()
+			if u.Path == "" && u.RawQuery == "" {
+				rest = ""
+			}
+		}
+	}
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		rest = rest[:i]
+	}
+	pathPart, rawQuery := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		pathPart, rawQuery = rest[:i], rest[i+1:]
+	}
+
+	used := map[string]int{}
+	segs := strings.Split(pathPart, "/")
+	for i, seg := range segs {
+		name := templateSegment(seg)
+		if name == "" {
+			continue
+		}
+		used[name]++
+		if used[name] > 1 {
+			name += itoa(used[name])
+		}
+		if m := tmplPlaceholderRe.FindStringSubmatch(seg); m == nil {
+			out.Examples[name] = seg
+		}
+		out.PathParams = append(out.PathParams, name)
+		segs[i] = "{" + name + "}"
+	}
+	out.Template = prefix + strings.Join(segs, "/")
+
+	if rawQuery != "" {
+		seen := map[string]struct{}{}
+		for _, kv := range strings.Split(rawQuery, "&") {
+			if kv == "" {
+				continue
+			}
+			k, v, _ := strings.Cut(kv, "=")
+			if uk, err := url.QueryUnescape(k); err == nil {
+				k = uk
+			}
+			if k == "" {
+				continue
+			}
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				out.QueryParams = append(out.QueryParams, k)
+			}
+			if v != "" && !tmplPlaceholderRe.MatchString(v) {
+				if _, ok := out.Examples[k]; !ok {
+					out.Examples[k] = v
+				}
+			}
+		}
+		sort.Strings(out.QueryParams)
+	}
+	return out
+}
+
+// templateSegment: نام پارامتر اگر قطعهٔ مسیر متغیر است، وگرنه ""
+func templateSegment(seg string) string {
+	if seg == "" {
+		return ""
+	}
+	if m := tmplPlaceholderRe.FindStringSubmatch(seg); m != nil {
+		return m[1]
+	}
+	switch {
+	case tmplNumericRe.MatchString(seg):
+		return "id"
+	case tmplUUIDRe.MatchString(seg):
+		return "uuid"
+	case tmplDateRe.MatchString(seg):
+		return "date"
+	case tmplHashRe.MatchString(seg) && tmplDigitRe.MatchString(seg):
+		return "hash"
+	case tmplSlugRe.MatchString(seg) && (tmplDigitRe.MatchString(seg) || len(seg) >= 30):
+		// slug فقط وقتی عدد دارد یا خیلی بلند است؛ "reset-password-form" یک route ثابت است
+		return "slug"
+	}
+	return ""
+}
+
+// examplePairs: "name=value" برای ذخیره در آرایه (merge با $setUnion)
+func (t EndpointTemplate) examplePairs() []string {
+	out := make([]string, 0, len(t.Examples))
+	for k, v := range t.Examples {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// setTemplateFields: فیلدهای قالب را به $set یک pipeline آپدیت endpoint اضافه می‌کند
+func setTemplateFields(set bson.M, ep string) {
+	t := TemplateEndpoint(ep)
+	set["template"] = bson.M{"$literal": t.Template}
+	set["path_params"] = bson.M{"$literal": t.PathParams}
+	if len(t.QueryParams) > 0 {
+		set["query_params"] = setUnionField("query_params", t.QueryParams)
+	}
+	if pairs := t.examplePairs(); len(pairs) > 0 {
+		set["param_examples"] = bson.M{"$slice": bson.A{setUnionField("param_examples", pairs), 20}}
+	}
+}
@@ -0,0 +1,14 @@
+package functions
+
+import (
+	"os"
+	"strconv"
+)
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
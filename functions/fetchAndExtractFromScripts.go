@@ -42,14 +42,15 @@ func fetchAndExtractFromScripts(ctx context.Context, srcs []string, timeoutSec i
 		err := chromedp.Run(ctx, chromedp.EvaluateAsDevTools(code, &r))
 		if err != nil {
 			errs = append(errs, u+" -> "+err.Error())
+			emitScanEvent(ctx, ScanEvent{Type: EvScriptError, Stage: "fetch_scripts", URL: u, Error: err.Error()})
 			continue
 		}
 		if !r.Ok {
-			if r.Err != "" {
-				errs = append(errs, r.Err)
-			} else {
-				errs = append(errs, u+" -> unknown error")
+			if r.Err == "" {
+				r.Err = u + " -> unknown error"
 			}
+			errs = append(errs, r.Err)
+			emitScanEvent(ctx, ScanEvent{Type: EvScriptError, Stage: "fetch_scripts", URL: u, Error: r.Err})
 			continue
 		}
 		for _, p := range r.Arr {
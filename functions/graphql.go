@@ -0,0 +1,557 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	graphqlPathRe = regexp.MustCompile(`(?i)(?:^|/)(?:graphql|gql)(?:/|$)`)
+
+	// سند متنی: query GetUser($id: ID!) { ... } — در کد فقط عملیات نام‌دار (عبارت بی‌نام در JS زیاد است)
+	graphqlOpRe      = regexp.MustCompile(`\b(query|mutation|subscription)\s+([A-Za-z_]\w*)\s*(?:\([^)]{0,1000}\))?\s*(?:@\w+\s*)*\{`)
+	graphqlAnonOpRe  = regexp.MustCompile(`^\s*(?:(query|mutation|subscription)\s*(?:\([^)]{0,1000}\))?\s*)?\{`)
+	graphqlLiteralRe = regexp.MustCompile("(?i)[\"'`]([^\"'`\\s]{0,200}(?:graphql|gql)[^\"'`\\s]{0,100})[\"'`]")
+
+	// سند کامپایل‌شده (graphql-tag / Apollo codegen): {kind:"OperationDefinition",operation:"query",name:{kind:"Name",value:"X"}
+	graphqlASTOpRe    = regexp.MustCompile(`["']?kind["']?\s*:\s*["']OperationDefinition["']\s*,\s*["']?operation["']?\s*:\s*["'](query|mutation|subscription)["']\s*,\s*["']?name["']?\s*:\s*\{\s*["']?kind["']?\s*:\s*["']Name["']\s*,\s*["']?value["']?\s*:\s*["'](\w+)["']`)
+	graphqlASTFieldRe = regexp.MustCompile(`["']?kind["']?\s*:\s*["']Field["']\s*,\s*(?:["']?alias["']?\s*:\s*(?:void 0|undefined|null|\{[^{}]*\{[^{}]*\}[^{}]*\})\s*,\s*)?["']?name["']?\s*:\s*\{\s*["']?kind["']?\s*:\s*["']Name["']\s*,\s*["']?value["']?\s*:\s*["'](\w+)["']`)
+
+	// persisted queries: Apollo (sha256Hash) و Relay (params.id + operationKind)
+	graphqlApolloHashRe = regexp.MustCompile(`["']?sha256Hash["']?\s*:\s*["']([0-9a-f]{64})["']`)
+	graphqlRelayRe      = regexp.MustCompile(`["']?id["']?\s*:\s*["']([0-9a-f]{32,64})["']\s*,\s*["']?metadata["']?\s*:\s*\{[^{}]*\}\s*,\s*["']?name["']?\s*:\s*["'](\w+)["']\s*,\s*["']?operationKind["']?\s*:\s*["'](query|mutation|subscription)["']`)
+)
+
+const graphqlMaxFields = 20
+
+// isGraphQLPath: مسیر (یا URL) به endpoint گراف‌کیوال اشاره دارد (/graphql، /api/gql/...)
+func isGraphQLPath(p string) bool {
+	if u, err := url.Parse(p); err == nil {
+		p = u.Path
+	}
+	return graphqlPathRe.MatchString(p)
+}
+
+// gqlCollector: ددوپ عملیات (type + name + hash + source) و endpointها
+type gqlCollector struct {
+	pageURL   *url.URL
+	endpoints []string
+	ops       []models.GraphQLOperation
+	index     map[string]int
+}
+
+func (c *gqlCollector) endpoint(raw string) string {
+	if c.pageURL != nil {
+		if ref, err := url.Parse(raw); err == nil {
+			raw = c.pageURL.ResolveReference(ref).String()
+		}
+	}
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+	c.endpoints = appendUnique(c.endpoints, raw)
+	return raw
+}
+
+func (c *gqlCollector) add(op models.GraphQLOperation) {
+	if op.Type == "" {
+		op.Type = "query"
+	}
+	key := op.Type + "\x1f" + op.Name + "\x1f" + op.Hash + "\x1f" + op.Source
+	if i, ok := c.index[key]; ok {
+		for _, f := range op.Fields {
+			if len(c.ops[i].Fields) < graphqlMaxFields {
+				c.ops[i].Fields = appendUnique(c.ops[i].Fields, f)
+			}
+		}
+		if c.ops[i].Endpoint == "" {
+			c.ops[i].Endpoint = op.Endpoint
+		}
+		return
+	}
+	c.index[key] = len(c.ops)
+	c.ops = append(c.ops, op)
+}
+
+// analyzeGraphQL: endpointها، عملیات و hashهای persisted از اسکریپت‌ها و ترافیک صفحه؛ nil اگر چیزی نبود
+func analyzeGraphQL(pageURL string, scripts map[string]string, requests []models.NetworkRequest) *models.GraphQLResult {
+	c := &gqlCollector{index: map[string]int{}}
+	c.pageURL, _ = url.Parse(pageURL)
+
+	for _, nr := range requests {
+		graphqlFromRequest(c, nr)
+	}
+	srcs := make([]string, 0, len(scripts))
+	for src := range scripts {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	for _, src := range srcs {
+		graphqlFromScript(c, src, scripts[src])
+	}
+
+	if len(c.endpoints) == 0 && len(c.ops) == 0 {
+		return nil
+	}
+	sort.Strings(c.endpoints)
+	return &models.GraphQLResult{Endpoints: c.endpoints, Operations: c.ops}
+}
+
+// graphqlFromScript: سندهای متنی/کامپایل‌شده، hashهای Relay/Apollo و رشته‌های شبیه /graphql
+func graphqlFromScript(c *gqlCollector, src, code string) {
+	if code == "" {
+		return
+	}
+	for _, m := range graphqlLiteralRe.FindAllStringSubmatch(code, -1) {
+		if jsPathLike(m[1]) && isGraphQLPath(m[1]) {
+			c.endpoint(m[1])
+		}
+	}
+	for _, m := range graphqlOpRe.FindAllStringSubmatchIndex(code, -1) {
+		c.add(models.GraphQLOperation{
+			Type:      code[m[2]:m[3]],
+			Name:      code[m[4]:m[5]],
+			Fields:    graphqlTopFields(code, m[1]-1),
+			Source:    "script",
+			SourceURL: src,
+		})
+	}
+	locs := graphqlASTOpRe.FindAllStringSubmatchIndex(code, -1)
+	for i, m := range locs {
+		// اولین Field بعد از تعریف (تا تعریف بعدی) = فیلد ریشه
+		end := len(code)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		if end-m[1] > 4000 {
+			end = m[1] + 4000
+		}
+		op := models.GraphQLOperation{Type: code[m[2]:m[3]], Name: code[m[4]:m[5]], Source: "script", SourceURL: src}
+		if f := graphqlASTFieldRe.FindStringSubmatch(code[m[1]:end]); f != nil {
+			op.Fields = []string{f[1]}
+		}
+		c.add(op)
+	}
+	for _, m := range graphqlRelayRe.FindAllStringSubmatch(code, -1) {
+		c.add(models.GraphQLOperation{Type: m[3], Name: m[2], Hash: m[1], Source: "script", SourceURL: src})
+	}
+	for _, m := range graphqlApolloHashRe.FindAllStringSubmatch(code, -1) {
+		c.add(models.GraphQLOperation{Hash: m[1], Source: "script", SourceURL: src})
+	}
+}
+
+// gqlPayload: بدنهٔ استاندارد درخواست GraphQL (POST JSON یا پارامترهای GET)
+type gqlPayload struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+	Extensions    struct {
+		PersistedQuery struct {
+			Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+	// Relay: {id, variables}
+	ID string `json:"id"`
+}
+
+func graphqlFromRequest(c *gqlCollector, nr models.NetworkRequest) {
+	u, err := url.Parse(nr.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	var payloads []gqlPayload
+	if body := strings.TrimSpace(nr.PostData); body != "" {
+		if strings.HasPrefix(body, "[") {
+			_ = json.Unmarshal([]byte(body), &payloads) // batch
+		} else if strings.HasPrefix(body, "{") {
+			var p gqlPayload
+			if json.Unmarshal([]byte(body), &p) == nil {
+				payloads = append(payloads, p)
+			}
+		}
+	}
+	if q := u.Query(); q.Get("query") != "" || q.Get("extensions") != "" {
+		p := gqlPayload{Query: q.Get("query"), OperationName: q.Get("operationName")}
+		_ = json.Unmarshal([]byte(q.Get("extensions")), &p.Extensions)
+		payloads = append(payloads, p)
+	}
+
+	isGQL := isGraphQLPath(u.Path)
+	var ops []models.GraphQLOperation
+	for _, p := range payloads {
+		hash := p.Extensions.PersistedQuery.Hash
+		if p.ID != "" && p.Query == "" && isGQL {
+			hash = p.ID
+		}
+		found := false
+		for _, op := range graphqlParseDocument(p.Query) {
+			if op.Name == "" {
+				op.Name = p.OperationName
+			}
+			op.Hash = hash
+			ops = append(ops, op)
+			found = true
+		}
+		if !found && hash != "" {
+			ops = append(ops, models.GraphQLOperation{Name: p.OperationName, Hash: hash})
+		}
+	}
+	if !isGQL && len(ops) == 0 {
+		return
+	}
+	ep := c.endpoint(nr.URL)
+	for _, op := range ops {
+		op.Source = "network"
+		op.SourceURL = nr.InitiatorURL // اسکریپت فرستنده (URL خود درخواست ممکن است کل سند را در query داشته باشد)
+		op.Endpoint = ep
+		c.add(op)
+	}
+}
+
+// graphqlParseDocument: عملیات یک سند کامل (از payload شبکه؛ عملیات بی‌نام هم معتبر است)
+func graphqlParseDocument(doc string) []models.GraphQLOperation {
+	if strings.TrimSpace(doc) == "" {
+		return nil
+	}
+	var out []models.GraphQLOperation
+	for _, m := range graphqlOpRe.FindAllStringSubmatchIndex(doc, -1) {
+		out = append(out, models.GraphQLOperation{
+			Type:   doc[m[2]:m[3]],
+			Name:   doc[m[4]:m[5]],
+			Fields: graphqlTopFields(doc, m[1]-1),
+		})
+	}
+	if len(out) == 0 {
+		if m := graphqlAnonOpRe.FindStringSubmatchIndex(doc); m != nil {
+			typ := "query"
+			if m[2] >= 0 {
+				typ = doc[m[2]:m[3]]
+			}
+			out = append(out, models.GraphQLOperation{Type: typ, Fields: graphqlTopFields(doc, m[1]-1)})
+		}
+	}
+	return out
+}
+
+// graphqlTopFields: نام فیلدهای سطح اول selection set که از s[open] == '{' شروع می‌شود.
+// alias (a: field)، آرگومان‌ها، directiveها و fragment spread رد می‌شوند؛ \n داخل رشتهٔ JS فاصله است
+func graphqlTopFields(s string, open int) []string {
+	var out []string
+	depth := 0
+	skipIdent := 0
+	for i := open; i < len(s) && len(out) < graphqlMaxFields; i++ {
+		ch := s[i]
+		switch {
+		case ch == '\\':
+			i++ // \n \t \" ...
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth <= 0 {
+				return out
+			}
+		case ch == '(':
+			for n := 0; i < len(s); i++ {
+				if s[i] == '(' {
+					n++
+				} else if s[i] == ')' {
+					if n--; n == 0 {
+						break
+					}
+				}
+			}
+		case ch == '.' && strings.HasPrefix(s[i:], "..."):
+			i += 2
+			skipIdent = 1 // نام fragment یا "on"
+		case ch == '@':
+			skipIdent = 1
+		case ch == '"' || ch == '`' || ch == '\'':
+			// پایان literal جاوااسکریپتی که سند داخلش است
+			if depth <= 1 {
+				return out
+			}
+		case ch == '_' || (ch|0x20 >= 'a' && ch|0x20 <= 'z'):
+			j := i
+			for j < len(s) && (s[j] == '_' || (s[j]|0x20 >= 'a' && s[j]|0x20 <= 'z') || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			name := s[i:j]
+			i = j - 1
+			if depth != 1 {
+				continue
+			}
+			if skipIdent > 0 {
+				skipIdent--
+				if name == "on" {
+					skipIdent = 1 // ... on Type
+				}
+				continue
+			}
+			// alias: نام بعدی فیلد واقعی است
+			k := j
+			for k < len(s) && (s[k] == ' ' || s[k] == '\t' || s[k] == '\n' || s[k] == '\r') {
+				k++
+			}
+			if k < len(s) && s[k] == ':' {
+				continue
+			}
+			out = appendUnique(out, name)
+		}
+	}
+	return out
+}
+
+// graphqlIntrospectionQuery: نسخهٔ سبک‌تر کوئری استاندارد (بدون description)
+const graphqlIntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind name
+      fields(includeDeprecated: true) {
+        name
+        args { name type { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }
+        type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+      }
+      inputFields { name type { kind name ofType { kind name ofType { kind name } } } }
+      enumValues(includeDeprecated: true) { name }
+      possibleTypes { name }
+    }
+    directives { name locations }
+  }
+}`
+
+type gqlIntrospection struct {
+	Data *struct {
+		Schema json.RawMessage `json:"__schema"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type gqlSchemaSummary struct {
+	QueryType        *struct{ Name string } `json:"queryType"`
+	MutationType     *struct{ Name string } `json:"mutationType"`
+	SubscriptionType *struct{ Name string } `json:"subscriptionType"`
+	Types            []struct {
+		Name   string `json:"name"`
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+	} `json:"types"`
+}
+
+// introspectGraphQL: کوئری introspection روی endpointهای هم‌سایت (حداکثر GRAPHQL_INTROSPECT_MAX).
+// endpointی که کمتر از GRAPHQL_INTROSPECT_TTL_MIN دقیقه پیش بررسی شده دوباره درخواست نمی‌گیرد.
+func introspectGraphQL(ctx context.Context, siteID string, endpoints []string, auth *models.AuthProfileDoc, timeoutSec int) ([]models.GraphQLSchemaDoc, []models.FindingDoc) {
+	limit := envInt("GRAPHQL_INTROSPECT_MAX", 3)
+	ttl := time.Duration(envInt("GRAPHQL_INTROSPECT_TTL_MIN", 60)) * time.Minute
+	headers := network.Headers{}
+	applyAuthHeaders(headers, auth)
+	client := ScanHTTPClient(ctx, time.Duration(timeoutSec)*time.Second)
+
+	var (
+		schemas  []models.GraphQLSchemaDoc
+		findings []models.FindingDoc
+	)
+	for _, ep := range endpoints {
+		if len(schemas) >= limit {
+			break
+		}
+		u, err := url.Parse(ep)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !sameETLDPlusOne(u.Hostname(), siteID) {
+			continue
+		}
+		id := graphqlSchemaID(siteID, ep)
+		var prev models.GraphQLSchemaDoc
+		err = models.GraphQLSchemasColl().FindOne(ctx, bson.M{"_id": id}, mopts.FindOne().SetProjection(bson.M{"fetched_at": 1})).Decode(&prev)
+		if err == nil && time.Since(prev.FetchedAt) < ttl {
+			continue
+		}
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+
+		doc := fetchGraphQLSchema(ctx, client, ep, headers)
+		doc.ID, doc.SiteID = id, siteID
+		schemas = append(schemas, doc)
+		if doc.Enabled {
+			findings = append(findings, graphqlIntrospectionFinding(siteID, doc))
+		}
+	}
+	return schemas, findings
+}
+
+func fetchGraphQLSchema(ctx context.Context, client *http.Client, ep string, headers network.Headers) models.GraphQLSchemaDoc {
+	doc := models.GraphQLSchemaDoc{Endpoint: ep, FetchedAt: time.Now()}
+	body, _ := json.Marshal(map[string]string{"query": graphqlIntrospectionQuery, "operationName": "IntrospectionQuery"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep, bytes.NewReader(body))
+	if err != nil {
+		doc.Error = err.Error()
+		return doc
+	}
+	for k, v := range headers {
+		req.Header.Set(k, fmt.Sprint(v))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		doc.Error = err.Error()
+		return doc
+	}
+	defer res.Body.Close()
+	doc.Status = res.StatusCode
+
+	raw, err := io.ReadAll(io.LimitReader(res.Body, int64(envInt("GRAPHQL_SCHEMA_MAX_BYTES", 5<<20))))
+	if err != nil {
+		doc.Error = err.Error()
+		return doc
+	}
+	var out gqlIntrospection
+	if err := json.Unmarshal(raw, &out); err != nil {
+		doc.Error = "non-JSON response (HTTP " + res.Status + ")"
+		return doc
+	}
+	if out.Data == nil || len(out.Data.Schema) == 0 || string(out.Data.Schema) == "null" {
+		doc.Error = "introspection disabled"
+		if len(out.Errors) > 0 {
+			doc.Error = truncateBytes(out.Errors[0].Message, 300)
+		}
+		return doc
+	}
+
+	var sum gqlSchemaSummary
+	if err := json.Unmarshal(out.Data.Schema, &sum); err != nil {
+		doc.Error = err.Error()
+		return doc
+	}
+	doc.Enabled = true
+	doc.Schema = string(out.Data.Schema)
+	doc.TypeCount = len(sum.Types)
+	rootFields := func(t *struct{ Name string }) []string {
+		if t == nil {
+			return nil
+		}
+		for _, ty := range sum.Types {
+			if ty.Name == t.Name {
+				names := make([]string, 0, len(ty.Fields))
+				for _, f := range ty.Fields {
+					names = append(names, f.Name)
+				}
+				sort.Strings(names)
+				return names
+			}
+		}
+		return nil
+	}
+	doc.QueryFields = rootFields(sum.QueryType)
+	doc.MutationFields = rootFields(sum.MutationType)
+	doc.SubscriptionFields = rootFields(sum.SubscriptionType)
+	return doc
+}
+
+func graphqlSchemaID(siteID, ep string) string {
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + ep))
+	return hex.EncodeToString(sum[:16])
+}
+
+func graphqlIntrospectionFinding(siteID string, doc models.GraphQLSchemaDoc) models.FindingDoc {
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + models.FindingGraphQLIntrospection + "\x1f" + doc.Endpoint))
+	severity := models.SeverityLow
+	if len(doc.MutationFields) > 0 {
+		severity = models.SeverityMedium
+	}
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   siteID,
+		Type:     models.FindingGraphQLIntrospection,
+		Severity: severity,
+		Title:    "GraphQL introspection enabled on " + doc.Endpoint,
+		URL:      doc.Endpoint,
+		Details: map[string]any{
+			"types":         doc.TypeCount,
+			"queries":       len(doc.QueryFields),
+			"mutations":     len(doc.MutationFields),
+			"subscriptions": len(doc.SubscriptionFields),
+		},
+	}
+}
+
+// persistGraphQL: عملیات (upsert با sig) و schemaها (یک سند برای هر endpoint)
+func persistGraphQL(ctx context.Context, siteID string, res *models.GraphQLResult) error {
+	if res == nil {
+		return nil
+	}
+	now := time.Now()
+	bw := make([]mongo.WriteModel, 0, len(res.Operations))
+	for _, op := range res.Operations {
+		sum := sha256.Sum256([]byte(siteID + "\x1f" + op.Type + "\x1f" + op.Name + "\x1f" + op.Hash))
+		addToSet := bson.M{"sources": op.Source}
+		if len(op.Fields) > 0 {
+			addToSet["fields"] = bson.M{"$each": op.Fields}
+		}
+		if op.SourceURL != "" {
+			addToSet["source_urls"] = op.SourceURL
+		}
+		if op.Endpoint != "" {
+			addToSet["endpoints"] = op.Endpoint
+		}
+		bw = append(bw, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sig": hex.EncodeToString(sum[:])}).
+			SetUpdate(bson.M{
+				"$setOnInsert": bson.M{
+					"sig": hex.EncodeToString(sum[:]), "site_id": siteID,
+					"type": op.Type, "name": op.Name, "hash": op.Hash, "first_seen": now,
+				},
+				"$set":      bson.M{"last_seen": now},
+				"$addToSet": addToSet,
+				"$inc":      bson.M{"hits": 1},
+			}).
+			SetUpsert(true))
+	}
+	if len(bw) > 0 {
+		if _, err := models.GraphQLOpsColl().BulkWrite(ctx, bw, mopts.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	for _, s := range res.Schemas {
+		s.SiteID = siteID
+		if !s.Enabled {
+			// introspection این بار جواب نداد؛ schema خوب قبلی نگه داشته و فقط وضعیت آخرین تلاش ثبت می‌شود
+			_, err := models.GraphQLSchemasColl().UpdateByID(ctx, s.ID, bson.M{
+				"$set":         bson.M{"fetched_at": s.FetchedAt, "status": s.Status, "error": s.Error},
+				"$setOnInsert": bson.M{"site_id": siteID, "endpoint": s.Endpoint, "enabled": false},
+			}, mopts.Update().SetUpsert(true))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := models.GraphQLSchemasColl().ReplaceOne(ctx, bson.M{"_id": s.ID}, s, mopts.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
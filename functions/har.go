@@ -0,0 +1,213 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const harTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// BuildHAR: لاگ شبکهٔ ذخیره‌شدهٔ یک صفحه را به HAR 1.2 تبدیل می‌کند (قابل import در Burp/ZAP/DevTools)
+func BuildHAR(doc *models.NetworkLogDoc, withBodies bool) *models.HAR {
+	const pageID = "page_1"
+
+	started := doc.CapturedAt
+	if len(doc.Requests) > 0 && !doc.Requests[0].StartedAt.IsZero() {
+		started = doc.Requests[0].StartedAt
+	}
+	title := doc.Title
+	if title == "" {
+		title = doc.PageURL
+	}
+	onContentLoad, onLoad := -1.0, -1.0
+	if doc.Timings.OnContentLoadMs > 0 {
+		onContentLoad = doc.Timings.OnContentLoadMs
+	}
+	if doc.Timings.OnLoadMs > 0 {
+		onLoad = doc.Timings.OnLoadMs
+	}
+
+	har := &models.HAR{Log: models.HARLog{
+		Version: "1.2",
+		Creator: models.HARCreator{Name: "SiteChecker", Version: "1.0"},
+		Pages: []models.HARPage{{
+			StartedDateTime: started.Format(harTimeLayout),
+			ID:              pageID,
+			Title:           title,
+			PageTimings:     models.HARPageTimings{OnContentLoad: onContentLoad, OnLoad: onLoad},
+		}},
+		Entries: make([]models.HAREntry, 0, len(doc.Requests)),
+	}}
+
+	for _, nr := range doc.Requests {
+		har.Log.Entries = append(har.Log.Entries, harEntry(pageID, nr, withBodies))
+	}
+	return har
+}
+
+func harEntry(pageID string, nr models.NetworkRequest, withBodies bool) models.HAREntry {
+	httpVersion := harHTTPVersion(nr.Protocol)
+	reqHeaders := nonNilHeaders(nr.RequestHeaders)
+	respHeaders := nonNilHeaders(nr.ResponseHeaders)
+
+	req := models.HARRequest{
+		Method:      nr.Method,
+		URL:         nr.URL,
+		HTTPVersion: httpVersion,
+		Cookies:     harRequestCookies(reqHeaders),
+		Headers:     reqHeaders,
+		QueryString: harQueryString(nr.URL),
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if nr.PostData != "" {
+		req.PostData = &models.HARPostData{
+			MimeType: headerValue(reqHeaders, "Content-Type"),
+			Text:     nr.PostData,
+		}
+		req.BodySize = len(nr.PostData)
+	}
+
+	content := models.HARContent{Size: nr.BodySize, MimeType: nr.MIMEType}
+	if content.Size == 0 {
+		content.Size = nr.EncodedSize
+	}
+	if withBodies && nr.Body != "" {
+		content.Text = nr.Body
+		if nr.BodyBase64 {
+			content.Encoding = "base64"
+		}
+		if nr.BodyTruncated {
+			content.Comment = "truncated"
+		}
+	}
+	bodySize := int64(-1)
+	if nr.EncodedSize > 0 {
+		bodySize = nr.EncodedSize
+	}
+
+	timings := models.HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: nr.DurationMs}
+	if t := nr.Timing; t != nil {
+		timings = models.HARTimings{
+			Blocked: t.Blocked, DNS: t.DNS, Connect: t.Connect, SSL: t.SSL,
+			Send: t.Send, Wait: t.Wait, Receive: t.Receive,
+		}
+	}
+
+	started := nr.StartedAt
+	if started.IsZero() {
+		started = time.Unix(0, 0)
+	}
+	e := models.HAREntry{
+		PageRef:         pageID,
+		StartedDateTime: started.Format(harTimeLayout),
+		Time:            nr.DurationMs,
+		Request:         req,
+		Response: models.HARResponse{
+			Status:      nr.Status,
+			StatusText:  nr.StatusText,
+			HTTPVersion: httpVersion,
+			Cookies:     harResponseCookies(respHeaders),
+			Headers:     respHeaders,
+			Content:     content,
+			RedirectURL: nr.RedirectTo,
+			HeadersSize: -1,
+			BodySize:    bodySize,
+		},
+		Timings:      timings,
+		ResourceType: strings.ToLower(nr.Type),
+		Initiator:    nr.InitiatorURL,
+		Error:        nr.ErrorText,
+	}
+	if ip := strings.Trim(nr.RemoteIP, "[]"); ip != "" {
+		e.ServerIPAddress = ip
+	}
+	return e
+}
+
+func harHTTPVersion(proto string) string {
+	switch strings.ToLower(proto) {
+	case "h2":
+		return "HTTP/2"
+	case "h3", "http/3":
+		return "HTTP/3"
+	case "":
+		return "HTTP/1.1"
+	default:
+		return strings.ToUpper(proto)
+	}
+}
+
+func harQueryString(raw string) []models.HTTPHeader {
+	out := []models.HTTPHeader{}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return out
+	}
+	for _, kv := range strings.Split(u.RawQuery, "&") {
+		if kv == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		if dk, err := url.QueryUnescape(k); err == nil {
+			k = dk
+		}
+		if dv, err := url.QueryUnescape(v); err == nil {
+			v = dv
+		}
+		out = append(out, models.HTTPHeader{Name: k, Value: v})
+	}
+	return out
+}
+
+func harRequestCookies(headers []models.HTTPHeader) []models.HARCookie {
+	r := &http.Request{Header: toHTTPHeader(headers)}
+	out := []models.HARCookie{}
+	for _, c := range r.Cookies() {
+		out = append(out, models.HARCookie{Name: c.Name, Value: c.Value})
+	}
+	return out
+}
+
+func harResponseCookies(headers []models.HTTPHeader) []models.HARCookie {
+	r := &http.Response{Header: toHTTPHeader(headers)}
+	out := []models.HARCookie{}
+	for _, c := range r.Cookies() {
+		hc := models.HARCookie{
+			Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain,
+			HTTPOnly: c.HttpOnly, Secure: c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			hc.Expires = c.Expires.Format(harTimeLayout)
+		}
+		out = append(out, hc)
+	}
+	return out
+}
+
+func toHTTPHeader(headers []models.HTTPHeader) http.Header {
+	h := http.Header{}
+	for _, x := range headers {
+		h.Add(x.Name, x.Value)
+	}
+	return h
+}
+
+func headerValue(headers []models.HTTPHeader, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func nonNilHeaders(h []models.HTTPHeader) []models.HTTPHeader {
+	if h == nil {
+		return []models.HTTPHeader{}
+	}
+	return h
+}
@@ -0,0 +1,480 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HARFile: فایل‌های HAR ابزارهای مختلف (DevTools، Burp، ZAP) فیلدهای سفارشی متفاوتی دارند؛
+// فقط بخش استاندارد + _resourceType را می‌خوانیم و بقیه را نادیده می‌گیریم.
+type HARFile struct {
+	Log struct {
+		Pages []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"pages"`
+		Entries []harFileEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harFileEntry struct {
+	PageRef         string  `json:"pageref"`
+	StartedDateTime string  `json:"startedDateTime"`
+	Time            float64 `json:"time"`
+	Request         struct {
+		Method   string              `json:"method"`
+		URL      string              `json:"url"`
+		Headers  []models.HTTPHeader `json:"headers"`
+		PostData *models.HARPostData `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status      int                 `json:"status"`
+		StatusText  string              `json:"statusText"`
+		HTTPVersion string              `json:"httpVersion"`
+		Headers     []models.HTTPHeader `json:"headers"`
+		Content     struct {
+			Size     float64 `json:"size"`
+			MimeType string  `json:"mimeType"`
+			Text     string  `json:"text"`
+			Encoding string  `json:"encoding"`
+		} `json:"content"`
+		RedirectURL string  `json:"redirectURL"`
+		BodySize    float64 `json:"bodySize"`
+	} `json:"response"`
+	ServerIPAddress string             `json:"serverIPAddress"`
+	Timings         *models.HARTimings `json:"timings"`
+	ResourceType    string             `json:"_resourceType"`
+}
+
+// HARImportOptions: تنظیمات import
+type HARImportOptions struct {
+	KeepBodies bool // بدنهٔ پاسخ‌ها در لاگ شبکهٔ صفحه هم ذخیره شود
+}
+
+// harPage: یک سند HTML به‌همراه درخواست‌هایی که به آن نسبت داده شده‌اند
+type harPage struct {
+	doc     *harFileEntry
+	entries []*harFileEntry
+}
+
+// ParseHAR: فقط ساختار را می‌خواند؛ اعتبارسنجی حداقلی
+func ParseHAR(r io.Reader) (*HARFile, error) {
+	var h HARFile
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %w", err)
+	}
+	if len(h.Log.Entries) == 0 {
+		return nil, errors.New("HAR has no entries")
+	}
+	return &h, nil
+}
+
+// ImportHAR: صفحه‌ها، اسکریپت‌ها و پاسخ‌ها را از HAR بازسازی و همان پایپ‌لاین استخراج اسکن را بدون Chromium اجرا می‌کند
+func ImportHAR(ctx context.Context, h *HARFile, opts HARImportOptions) (*models.HARImportResult, error) {
+	res := &models.HARImportResult{
+		ImportID: primitive.NewObjectID().Hex(),
+		Entries:  len(h.Log.Entries),
+		Pages:    []models.HARImportPage{},
+	}
+
+	pages, skipped := groupHARPages(h)
+	res.Skipped = skipped
+	if len(pages) == 0 {
+		return nil, errors.New("HAR contains no HTML documents")
+	}
+
+	for _, pg := range pages {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		res.Pages = append(res.Pages, importHARPage(ctx, res.ImportID, pg, opts))
+	}
+	log.Printf("[har-import] id=%s entries=%d pages=%d skipped=%d", res.ImportID, res.Entries, len(res.Pages), res.Skipped)
+	return res, nil
+}
+
+// harImportDir: فایل‌های HAR آپلودشده تا اجرای job اینجا می‌مانند (HAR_IMPORT_DIR)
+func harImportDir() string {
+	if d := strings.TrimSpace(os.Getenv("HAR_IMPORT_DIR")); d != "" {
+		return d
+	}
+	return filepath.Join(os.TempDir(), "sitechecker-har")
+}
+
+// EnqueueHARImport: HAR (تا ۱۰۰MB) در سند job جا نمی‌شود؛ روی دیسک نوشته و job import در صف اسکن گذاشته می‌شود
+func EnqueueHARImport(ctx context.Context, src io.Reader, name string, keepBodies bool) (*models.ScanJobDoc, error) {
+	dir := harImportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "import-*.har")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.ScanJobDoc{
+		ID: primitive.NewObjectID().Hex(),
+		Request: models.ScanRequest{
+			HARImport: &models.HARImportSpec{File: f.Name(), Name: name, KeepBodies: keepBodies},
+		},
+		State:     models.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := insertScanJob(ctx, job); err != nil {
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	return job, nil
+}
+
+// runHARImportJob: اجرای job import؛ فایل بعد از اتمام (موفق یا ناموفق) پاک می‌شود،
+// ولی با خاموش شدن سرور می‌ماند تا job دوباره صف شود
+func runHARImportJob(ctx context.Context, job *models.ScanJobDoc) {
+	spec := job.Request.HARImport
+	timings := models.ScanJobTimings{QueuedMs: job.StartedAt.Sub(job.CreatedAt).Milliseconds()}
+	fail := func(msg string) {
+		_ = os.Remove(spec.File)
+		timings.TotalMs = time.Since(job.StartedAt).Milliseconds()
+		setScanJob(ctx, job.ID, bson.M{"state": models.JobFailed, "error": msg, "timings": timings, "finished_at": time.Now()})
+	}
+
+	f, err := os.Open(spec.File)
+	if err != nil {
+		fail("har file: " + err.Error())
+		return
+	}
+	h, err := ParseHAR(f)
+	f.Close()
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	start := time.Now()
+	importCtx, cancel := context.WithTimeout(ctx, time.Duration(envInt("HAR_IMPORT_TIMEOUT_MIN", 30))*time.Minute)
+	defer cancel()
+	res, err := ImportHAR(importCtx, h, HARImportOptions{KeepBodies: spec.KeepBodies})
+	if ctx.Err() != nil {
+		return
+	}
+	if res == nil {
+		fail(err.Error())
+		return
+	}
+	_ = os.Remove(spec.File)
+	timings.SaveMs = time.Since(start).Milliseconds()
+	timings.TotalMs = time.Since(job.StartedAt).Milliseconds()
+
+	result := models.ScanJobResult{}
+	for _, pg := range res.Pages {
+		result.Endpoints += pg.Endpoints
+		result.Scripts += pg.Scripts
+		result.Sinks += pg.Sinks
+		result.Resources += pg.Requests
+	}
+	set := bson.M{"import": res, "result": result, "timings": timings, "finished_at": time.Now(), "state": models.JobDone}
+	if len(res.Pages) > 0 {
+		set["site_id"], set["url_norm"] = res.Pages[0].SiteID, res.Pages[0].URLNorm
+	}
+	if err != nil {
+		set["state"], set["error"] = models.JobFailed, "import error: "+err.Error()
+	}
+	setScanJob(ctx, job.ID, set)
+}
+
+// groupHARPages: اگر pageref باشد از آن، وگرنه هر درخواست به آخرین سند HTML قبل از خودش نسبت داده می‌شود
+func groupHARPages(h *HARFile) ([]*harPage, int) {
+	entries := make([]*harFileEntry, 0, len(h.Log.Entries))
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		if !strings.HasPrefix(e.Request.URL, "http://") && !strings.HasPrefix(e.Request.URL, "https://") {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	skipped := len(h.Log.Entries) - len(entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedDateTime < entries[j].StartedDateTime })
+
+	var pages []*harPage
+	byRef := map[string]*harPage{}
+	var current *harPage
+	for _, e := range entries {
+		isDoc := harIsDocument(e)
+		if e.PageRef != "" {
+			pg := byRef[e.PageRef]
+			if pg == nil {
+				pg = &harPage{}
+				byRef[e.PageRef] = pg
+				pages = append(pages, pg)
+			}
+			if pg.doc == nil && isDoc {
+				pg.doc = e
+			}
+			pg.entries = append(pg.entries, e)
+			continue
+		}
+		if isDoc {
+			current = &harPage{doc: e}
+			pages = append(pages, current)
+		}
+		if current == nil {
+			skipped++
+			continue
+		}
+		current.entries = append(current.entries, e)
+	}
+
+	out := pages[:0]
+	for _, pg := range pages {
+		if pg.doc == nil {
+			skipped += len(pg.entries)
+			continue
+		}
+		out = append(out, pg)
+	}
+	return out, skipped
+}
+
+func importHARPage(ctx context.Context, importID string, pg *harPage, opts HARImportOptions) models.HARImportPage {
+	pageURL := pg.doc.Request.URL
+	siteID, urlNorm, _ := PageKeys(pageURL)
+	out := models.HARImportPage{URL: pageURL, URLNorm: urlNorm, SiteID: siteID, Requests: len(pg.entries)}
+
+	html, _ := harBodyText(pg.doc)
+	scripts := map[string]string{}
+	var resources, scriptURLs []string
+	requests := make([]models.NetworkRequest, 0, len(pg.entries))
+	var budget *bodyBudget
+	if opts.KeepBodies {
+		b := newBodyBudget()
+		budget = &b
+	}
+
+	for i, e := range pg.entries {
+		resources = append(resources, e.Request.URL)
+		if harIsScript(e) {
+			scriptURLs = append(scriptURLs, e.Request.URL)
+			if code, ok := harBodyText(e); ok {
+				scripts[e.Request.URL] = code
+			}
+		}
+		if i < maxNetworkRequests {
+			requests = append(requests, harToNetworkRequest(i, e, budget))
+		}
+	}
+	out.Scripts = len(scripts)
+
+	hints := endpointHints{}
+	for _, p := range extractPathsFromHTML(html) {
+		hints.add(p, ExtractorRegex, "", "")
+	}
+	for _, code := range scripts {
+		_ = extractEndpointsFromJS(code, hints)
+	}
+
+	resp := &models.ScanResponse{
+		URL:           pageURL,
+		Resources:     uniqueStrings(resources),
+		UniquePaths:   hints.paths(),
+		EndpointHints: hints,
+		AllScripts:    uniqueStrings(scriptURLs),
+		Requests:      requests,
+		ProcessedAt:   time.Now().Format(time.RFC3339),
+		PageDuration:  "0s",
+		Origin:        models.OriginHARImport,
+		ImportID:      importID,
+		GraphQL:       analyzeGraphQL(pageURL, scripts, requests),
+		Secrets:       ScanSecrets(html, scripts, urlNorm, siteID),
+		HTML:          html,
+		ScriptBodies:  scripts,
+	}
+	out.Endpoints = len(resp.UniquePaths)
+
+	saveCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	if err := SaveScanResponse(saveCtx, resp); err != nil {
+		out.Error = "save error: " + err.Error()
+		return out
+	}
+
+	sinks := ScanSinksGo(html, scripts, urlNorm, siteID)
+	for i := range sinks {
+		sinks[i].Origin = models.OriginHARImport
+		sinks[i].ImportID = importID
+	}
+	if _, err := PersistSinks(saveCtx, sinks); err != nil {
+		out.Error = "sinks error: " + err.Error()
+	} else if err := AttachSnapshotSinks(saveCtx, resp.SnapshotID, sinks); err != nil {
+		out.Error = "snapshot error: " + err.Error()
+	}
+	out.Sinks = len(sinks)
+	return out
+}
+
+// harToNetworkRequest: budget اگر nil نباشد بدنهٔ پاسخ با همان سقف‌های ضبط زنده نگه داشته می‌شود
+func harToNetworkRequest(i int, e *harFileEntry, budget *bodyBudget) models.NetworkRequest {
+	nr := models.NetworkRequest{
+		RequestID:       fmt.Sprintf("har-%d", i),
+		Method:          strings.ToUpper(e.Request.Method),
+		URL:             e.Request.URL,
+		Type:            harResourceType(e),
+		Status:          e.Response.Status,
+		StatusText:      e.Response.StatusText,
+		MIMEType:        harMime(e),
+		RedirectTo:      e.Response.RedirectURL,
+		RemoteIP:        e.ServerIPAddress,
+		Protocol:        strings.ToLower(e.Response.HTTPVersion),
+		DurationMs:      e.Time,
+		RequestHeaders:  e.Request.Headers,
+		ResponseHeaders: e.Response.Headers,
+		BodySize:        int64(e.Response.Content.Size),
+	}
+	if e.Response.BodySize > 0 {
+		nr.EncodedSize = int64(e.Response.BodySize)
+	}
+	if e.Response.Status == 0 {
+		nr.Failed = true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime); err == nil {
+		nr.StartedAt = t
+	}
+	if e.Request.PostData != nil {
+		nr.PostData = truncateBytes(e.Request.PostData.Text, maxPostDataBytes)
+	}
+	if t := e.Timings; t != nil {
+		nr.Timing = &models.NetworkTiming{
+			Blocked: t.Blocked, DNS: t.DNS, Connect: t.Connect, SSL: t.SSL,
+			Send: t.Send, Wait: t.Wait, Receive: t.Receive,
+		}
+	}
+	if budget != nil && e.Response.Content.Text != "" {
+		body := e.Response.Content.Text
+		nr.BodyBase64 = e.Response.Content.Encoding == "base64"
+		limit := budget.allow(len(body))
+		if nr.BodyBase64 {
+			limit -= limit % 4 // base64 بریده‌شده باید قابل decode بماند
+		}
+		if limit <= 0 {
+			nr.BodyBase64 = false
+			return nr
+		}
+		if len(body) > limit {
+			body = body[:limit]
+			nr.BodyTruncated = true
+		}
+		nr.Body = body
+		budget.spend(len(body))
+	}
+	return nr
+}
+
+// harBodyText: متن پاسخ (base64 در صورت نیاز decode می‌شود)؛ باینری‌ها رد می‌شوند
+func harBodyText(e *harFileEntry) (string, bool) {
+	c := e.Response.Content
+	if c.Text == "" {
+		return "", false
+	}
+	if c.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil || !utf8.Valid(b) {
+			return "", false
+		}
+		return string(b), true
+	}
+	return c.Text, true
+}
+
+func harMime(e *harFileEntry) string {
+	m := strings.ToLower(e.Response.Content.MimeType)
+	if i := strings.IndexByte(m, ';'); i >= 0 {
+		m = m[:i]
+	}
+	return strings.TrimSpace(m)
+}
+
+func harIsDocument(e *harFileEntry) bool {
+	if strings.EqualFold(e.ResourceType, "document") {
+		return true
+	}
+	if e.ResourceType != "" || !strings.EqualFold(e.Request.Method, "GET") {
+		return false
+	}
+	m := harMime(e)
+	return (m == "text/html" || m == "application/xhtml+xml") && e.Response.Status >= 200 && e.Response.Status < 300
+}
+
+func harIsScript(e *harFileEntry) bool {
+	if strings.EqualFold(e.ResourceType, "script") {
+		return true
+	}
+	m := harMime(e)
+	if strings.Contains(m, "javascript") || strings.Contains(m, "ecmascript") {
+		return true
+	}
+	u := e.Request.URL
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	ext := strings.ToLower(path.Ext(u))
+	return ext == ".js" || ext == ".mjs"
+}
+
+// harResourceType: نام نوع منبع به سبک CDP (Document، XHR، Script، ...)
+func harResourceType(e *harFileEntry) string {
+	switch strings.ToLower(e.ResourceType) {
+	case "document":
+		return "Document"
+	case "xhr":
+		return "XHR"
+	case "fetch":
+		return "Fetch"
+	case "script":
+		return "Script"
+	case "stylesheet":
+		return "Stylesheet"
+	case "image":
+		return "Image"
+	case "font":
+		return "Font"
+	case "websocket":
+		return "WebSocket"
+	case "":
+	default:
+		return "Other"
+	}
+	switch {
+	case harIsDocument(e):
+		return "Document"
+	case harIsScript(e):
+		return "Script"
+	case strings.Contains(harMime(e), "json") || headerValue(e.Request.Headers, "X-Requested-With") != "":
+		return "XHR"
+	}
+	return "Other"
+}
@@ -0,0 +1,455 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+)
+
+// نام extractorها روی endpointها
+const (
+	ExtractorRegex   = "regex"
+	ExtractorAST     = "ast"
+	ExtractorNetwork = "network"
+)
+
+// jsEndpoint: یک مسیر پیدا‌شده با AST؛ Context فقط برای فراخوانی‌های شناخته‌شده پر است
+type jsEndpoint struct {
+	Path    string
+	Method  string // GET/POST/... یا خالی
+	Context string // fetch | xhr | axios | jquery | client
+}
+
+var (
+	jsPathCharsRe = regexp.MustCompile(`^[\w\-./?&=#%:{}~+,;@!$*'()\[\]]+$`)
+	jsHasWordRe   = regexp.MustCompile(`[A-Za-z0-9]`)
+	httpMethods   = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true}
+)
+
+// hostهایی که در bundleها به‌عنوان namespace/مستندات می‌آیند، نه endpoint
+var jsNoiseHosts = map[string]bool{"www.w3.org": true, "w3.org": true, "schema.org": true, "fb.me": true, "reactjs.org": true}
+
+// extractEndpointsAST: رشته‌ها، template literalها و الحاق رشته‌ها را با پارسر JS (goja) دنبال می‌کند
+func extractEndpointsAST(code string) (out []jsEndpoint, err error) {
+	if len(code) > envInt("JS_AST_MAX_BYTES", 3<<20) {
+		return nil, fmt.Errorf("script too large for ast (%d bytes)", len(code))
+	}
+	defer func() {
+		// پارسر روی بعضی ورودی‌های عجیب panic می‌کند؛ اسکن نباید بخوابد
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("ast parse panic: %v", r)
+		}
+	}()
+	prog, err := parser.ParseFile(nil, "", code, parser.IgnoreRegExpErrors, parser.WithDisableSourceMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &jsEvaluator{consts: map[string]string{}, ambiguous: map[string]bool{}}
+	walkJS(reflect.ValueOf(prog), ev.collectConst)
+
+	seen := map[jsEndpoint]struct{}{}
+	add := func(p, method, ctx string) {
+		if !jsPathLike(p) {
+			return
+		}
+		e := jsEndpoint{Path: p, Method: method, Context: ctx}
+		if _, ok := seen[e]; ok {
+			return
+		}
+		seen[e] = struct{}{}
+		out = append(out, e)
+	}
+
+	walkJS(reflect.ValueOf(prog), func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.CallExpression:
+			if p, m, c, ok := ev.callEndpoint(x.Callee, x.ArgumentList); ok {
+				add(p, m, c)
+			}
+		case *ast.NewExpression:
+			// new Request(url, {method})
+			if name := jsCalleeName(x.Callee); name == "Request" && len(x.ArgumentList) > 0 {
+				p, _ := ev.eval(x.ArgumentList[0])
+				add(p, ev.optMethod(x.ArgumentList, 1, "method", "GET"), "fetch")
+			}
+		case *ast.BinaryExpression:
+			if x.Operator == token.PLUS {
+				if p, lit := ev.eval(x); lit {
+					add(p, "", "")
+				}
+				// تکه‌های الحاق جدا ثبت نشوند ("/api/" تنها endpoint نیست)
+				return false
+			}
+		case *ast.TemplateLiteral:
+			if x.Tag == nil {
+				if p, lit := ev.eval(x); lit {
+					add(p, "", "")
+				}
+			}
+		case *ast.StringLiteral:
+			add(x.Value.String(), "", "")
+		}
+		return true
+	})
+	return out, nil
+}
+
+// walkJS: پیمایش عمومی AST با reflect (goja Walker ندارد)
+func walkJS(v reflect.Value, fn func(ast.Node) bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			walkJS(v.Elem(), fn)
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if n, ok := v.Interface().(ast.Node); ok && !fn(n) {
+			return
+		}
+		walkJS(v.Elem(), fn)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			// DeclarationList کپی hoist‌شدهٔ همان bindingهاست
+			if !f.IsExported() || f.Name == "DeclarationList" {
+				continue
+			}
+			walkJS(v.Field(i), fn)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkJS(v.Index(i), fn)
+		}
+	}
+}
+
+type jsEvaluator struct {
+	consts    map[string]string // name یا name.key → مقدار رشته‌ای کامل
+	ambiguous map[string]bool   // نام‌هایی که چند بار با مقدار متفاوت bind شده‌اند (رایج در کد minify‌شده)
+}
+
+func (ev *jsEvaluator) setConst(name, val string, ok bool) {
+	if ev.ambiguous[name] {
+		return
+	}
+	if old, seen := ev.consts[name]; !ok || (seen && old != val) {
+		delete(ev.consts, name)
+		ev.ambiguous[name] = true
+		return
+	}
+	ev.consts[name] = val
+}
+
+func (ev *jsEvaluator) collectConst(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.Binding:
+		id, ok := x.Target.(*ast.Identifier)
+		if !ok || x.Initializer == nil {
+			return true
+		}
+		name := id.Name.String()
+		if obj, ok := x.Initializer.(*ast.ObjectLiteral); ok {
+			for _, prop := range obj.Value {
+				if kp, ok := prop.(*ast.PropertyKeyed); ok && !kp.Computed {
+					if key, ok := kp.Key.(*ast.StringLiteral); ok {
+						v, complete := ev.evalFull(kp.Value)
+						ev.setConst(name+"."+key.Value.String(), v, complete)
+					}
+				}
+			}
+			return true
+		}
+		v, complete := ev.evalFull(x.Initializer)
+		ev.setConst(name, v, complete)
+	case *ast.AssignExpression:
+		if id, ok := x.Left.(*ast.Identifier); ok {
+			ev.setConst(id.Name.String(), "", false)
+		}
+	}
+	return true
+}
+
+// evalFull: فقط وقتی همهٔ اجزا رشتهٔ معلوم‌اند (برای ثابت‌ها)
+func (ev *jsEvaluator) evalFull(e ast.Expression) (string, bool) {
+	s, lit := ev.eval(e)
+	return s, lit && !strings.Contains(s, "{")
+}
+
+// eval: مقدار رشته‌ای تقریبی؛ اجزای نامعلوم به {name} تبدیل می‌شوند. lit یعنی حداقل یک تکهٔ ثابت داشت
+func (ev *jsEvaluator) eval(e ast.Expression) (s string, lit bool) {
+	switch x := e.(type) {
+	case *ast.StringLiteral:
+		return x.Value.String(), true
+	case *ast.TemplateLiteral:
+		if x.Tag != nil {
+			return "{param}", false
+		}
+		var b strings.Builder
+		for i, el := range x.Elements {
+			if el.Parsed != "" {
+				b.WriteString(el.Parsed.String())
+				lit = true
+			}
+			if i < len(x.Expressions) {
+				part, _ := ev.eval(x.Expressions[i])
+				b.WriteString(part)
+			}
+		}
+		return b.String(), lit
+	case *ast.BinaryExpression:
+		if x.Operator != token.PLUS {
+			return "{param}", false
+		}
+		l, llit := ev.eval(x.Left)
+		r, rlit := ev.eval(x.Right)
+		return l + r, llit || rlit
+	case *ast.Identifier:
+		name := x.Name.String()
+		if v, ok := ev.consts[name]; ok {
+			return v, true
+		}
+		return "{" + name + "}", false
+	case *ast.DotExpression:
+		if full := jsCalleeName(x); full != "" {
+			if v, ok := ev.consts[full]; ok {
+				return v, true
+			}
+		}
+		return "{" + x.Identifier.Name.String() + "}", false
+	case *ast.NumberLiteral:
+		return x.Literal, true
+	}
+	return "{param}", false
+}
+
+// callEndpoint: fetch / xhr.open / axios / jQuery / کلاینت‌های HTTP با متدهای get/post/...
+func (ev *jsEvaluator) callEndpoint(callee ast.Expression, args []ast.Expression) (p, method, ctx string, ok bool) {
+	name := jsCalleeName(callee)
+	if name == "" || len(args) == 0 {
+		return "", "", "", false
+	}
+	recv, last := "", name
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		recv, last = name[:i], name[i+1:]
+	}
+
+	switch {
+	case name == "fetch" || (last == "fetch" && (recv == "window" || recv == "self" || recv == "globalThis")):
+		p, _ = ev.eval(args[0])
+		return p, ev.optMethod(args, 1, "method", "GET"), "fetch", true
+
+	case last == "open" && len(args) >= 2:
+		m, _ := ev.eval(args[0])
+		m = strings.ToUpper(m)
+		if !httpMethods[m] {
+			return "", "", "", false
+		}
+		p, _ = ev.eval(args[1])
+		return p, m, "xhr", true
+
+	case name == "axios" || (recv == "axios" && last == "request"):
+		return ev.configCall(args, "url", "method", "GET", "axios")
+
+	case recv == "$" || recv == "jQuery":
+		switch last {
+		case "ajax":
+			return ev.configCall(args, "url", "type", "GET", "jquery")
+		case "get", "getJSON":
+			p, _ = ev.eval(args[0])
+			return p, "GET", "jquery", true
+		case "post":
+			p, _ = ev.eval(args[0])
+			return p, "POST", "jquery", true
+		}
+		return "", "", "", false
+	}
+
+	verb := strings.ToUpper(last)
+	if last == "del" {
+		verb = "DELETE"
+	}
+	if recv != "" && httpMethods[verb] && verb != "OPTIONS" {
+		p, _ = ev.eval(args[0])
+		// Map.get("x") و مشابه: فقط وقتی آرگومان شبیه مسیر است
+		if !jsPathLike(p) {
+			return "", "", "", false
+		}
+		ctx = "client"
+		if recv == "axios" || strings.HasSuffix(recv, ".axios") {
+			ctx = "axios"
+		}
+		return p, verb, ctx, true
+	}
+	return "", "", "", false
+}
+
+// configCall: fn(url, {method}) یا fn({url, method})
+func (ev *jsEvaluator) configCall(args []ast.Expression, urlKey, methodKey, def, ctx string) (string, string, string, bool) {
+	if obj, ok := args[0].(*ast.ObjectLiteral); ok {
+		u := jsObjectProp(obj, urlKey)
+		if u == nil {
+			return "", "", "", false
+		}
+		p, _ := ev.eval(u)
+		return p, ev.optMethod(args, 0, methodKey, def), ctx, true
+	}
+	p, _ := ev.eval(args[0])
+	return p, ev.optMethod(args, 1, methodKey, def), ctx, true
+}
+
+// optMethod: متد از آبجکت تنظیمات args[i] (مثل {method:"POST"})؛ در نبود آن def
+func (ev *jsEvaluator) optMethod(args []ast.Expression, i int, key, def string) string {
+	if i >= len(args) {
+		return def
+	}
+	obj, ok := args[i].(*ast.ObjectLiteral)
+	if !ok {
+		return def
+	}
+	if key == "type" {
+		// jQuery هر دو را می‌پذیرد
+		if v := jsObjectProp(obj, "method"); v != nil {
+			if m, full := ev.evalFull(v); full && httpMethods[strings.ToUpper(m)] {
+				return strings.ToUpper(m)
+			}
+		}
+	}
+	if v := jsObjectProp(obj, key); v != nil {
+		if m, full := ev.evalFull(v); full && httpMethods[strings.ToUpper(m)] {
+			return strings.ToUpper(m)
+		}
+	}
+	return def
+}
+
+func jsObjectProp(obj *ast.ObjectLiteral, key string) ast.Expression {
+	for _, prop := range obj.Value {
+		switch p := prop.(type) {
+		case *ast.PropertyKeyed:
+			if k, ok := p.Key.(*ast.StringLiteral); ok && !p.Computed && k.Value.String() == key {
+				return p.Value
+			}
+		case *ast.PropertyShort:
+			if p.Name.Name.String() == key {
+				return &p.Name
+			}
+		}
+	}
+	return nil
+}
+
+// jsCalleeName: a.b.c برای Identifier/DotExpression (this → "this")؛ در غیر این صورت ""
+func jsCalleeName(e ast.Expression) string {
+	switch x := e.(type) {
+	case *ast.Identifier:
+		return x.Name.String()
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.DotExpression:
+		left := jsCalleeName(x.Left)
+		if left == "" {
+			return ""
+		}
+		return left + "." + x.Identifier.Name.String()
+	case *ast.OptionalChain:
+		return jsCalleeName(x.Expression)
+	case *ast.Optional:
+		return jsCalleeName(x.Expression)
+	}
+	return ""
+}
+
+// jsPathLike: مسیر نسبی (مثل isRelativeLike) یا URL مطلق http(s)
+func jsPathLike(s string) bool {
+	if !jsPathCharsRe.MatchString(s) || !jsHasWordRe.MatchString(s) || strings.HasPrefix(s, "{") {
+		return false
+	}
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		if len(s) > 2048 {
+			return false
+		}
+		u, err := url.Parse(s)
+		return err == nil && u.Host != "" && !jsNoiseHosts[strings.ToLower(u.Hostname())]
+	}
+	return !strings.HasPrefix(s, "//") && isRelativeLike(s)
+}
+
+// endpointHints: extractor و متد/زمینهٔ هر مسیر برای ذخیره کنار UniquePaths
+type endpointHints map[string]*models.EndpointHint
+
+func (h endpointHints) add(p, extractor, method, ctx string) {
+	e := h[p]
+	if e == nil {
+		e = &models.EndpointHint{}
+		h[p] = e
+	}
+	e.Extractors = appendUnique(e.Extractors, extractor)
+	if method != "" {
+		e.Methods = appendUnique(e.Methods, method)
+	}
+	if ctx != "" {
+		e.Contexts = appendUnique(e.Contexts, ctx)
+	}
+}
+
+func (h endpointHints) merge(o endpointHints) {
+	for p, e := range o {
+		dst := h[p]
+		if dst == nil {
+			dst = &models.EndpointHint{}
+			h[p] = dst
+		}
+		for _, x := range e.Extractors {
+			dst.Extractors = appendUnique(dst.Extractors, x)
+		}
+		for _, m := range e.Methods {
+			dst.Methods = appendUnique(dst.Methods, m)
+		}
+		for _, c := range e.Contexts {
+			dst.Contexts = appendUnique(dst.Contexts, c)
+		}
+	}
+}
+
+// paths: مسیرها به ترتیب ثابت
+func (h endpointHints) paths() []string {
+	out := make([]string, 0, len(h))
+	for p := range h {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// extractEndpointsFromJS: regex فعلی + AST روی یک کد JS؛ خطای parse فقط یعنی AST سهمی ندارد
+func extractEndpointsFromJS(code string, hints endpointHints) error {
+	for _, p := range extractPathsFromHTML(code) {
+		hints.add(p, ExtractorRegex, "", "")
+	}
+	eps, err := extractEndpointsAST(code)
+	for _, e := range eps {
+		hints.add(e.Path, ExtractorAST, e.Method, e.Context)
+	}
+	return err
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
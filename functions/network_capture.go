@@ -0,0 +1,548 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"go.mongodb.org/mongo-driver/bson"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxNetworkRequests = 2000
+	maxPostDataBytes   = 16 << 10
+)
+
+// networkRecorder همهٔ درخواست‌های تب را از رویدادهای Network جمع می‌کند
+type networkRecorder struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	byID    map[network.RequestID]*models.NetworkRequest
+	startTS map[network.RequestID]time.Time
+	order   []*models.NetworkRequest
+
+	// ExtraInfo ممکن است قبل از رویداد اصلی برسد
+	extraReq  map[network.RequestID]network.Headers
+	extraResp map[network.RequestID]network.Headers
+
+	// بدنهٔ پاسخ‌ها (اختیاری)
+	bodies        bool
+	budget        bodyBudget
+	pendingBodies sync.WaitGroup
+
+	docStart    time.Time
+	pageTimings models.PageTimings
+}
+
+// startNetworkCapture: باید قبل از network.Enable و Navigate صدا زده شود
+func startNetworkCapture(ctx context.Context, withBodies bool) *networkRecorder {
+	rec := &networkRecorder{
+		ctx:       ctx,
+		byID:      map[network.RequestID]*models.NetworkRequest{},
+		startTS:   map[network.RequestID]time.Time{},
+		extraReq:  map[network.RequestID]network.Headers{},
+		extraResp: map[network.RequestID]network.Headers{},
+		bodies:    withBodies,
+		budget:    newBodyBudget(),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			rec.onRequest(e)
+		case *network.EventRequestWillBeSentExtraInfo:
+			rec.onRequestExtra(e)
+		case *network.EventResponseReceived:
+			rec.onResponse(e.RequestID, e.Type, e.Response)
+		case *network.EventResponseReceivedExtraInfo:
+			rec.onResponseExtra(e)
+		case *network.EventLoadingFinished:
+			rec.onFinished(e)
+		case *network.EventLoadingFailed:
+			rec.onFailed(e)
+		case *page.EventDomContentEventFired:
+			rec.onPageEvent(e.Timestamp, &rec.pageTimings.OnContentLoadMs)
+		case *page.EventLoadEventFired:
+			rec.onPageEvent(e.Timestamp, &rec.pageTimings.OnLoadMs)
+		}
+	})
+	return rec
+}
+
+func (r *networkRecorder) onRequest(e *network.EventRequestWillBeSent) {
+	if e.Request == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// ریدایرکت: همان RequestID دوباره می‌آید؛ hop قبلی را با status 3xx می‌بندیم
+	if prev := r.byID[e.RequestID]; prev != nil && e.RedirectResponse != nil {
+		r.applyResponse(prev, "", e.RedirectResponse)
+		prev.RedirectTo = e.Request.URL
+		r.setDuration(e.RequestID, prev, e.Timestamp)
+		delete(r.byID, e.RequestID)
+	}
+
+	if len(r.order) >= maxNetworkRequests {
+		return
+	}
+
+	nr := &models.NetworkRequest{
+		RequestID:      string(e.RequestID),
+		Method:         e.Request.Method,
+		URL:            e.Request.URL + e.Request.URLFragment,
+		Type:           string(e.Type),
+		RequestHeaders: headerList(e.Request.Headers),
+	}
+	if h, ok := r.extraReq[e.RequestID]; ok {
+		nr.RequestHeaders = headerList(h)
+		delete(r.extraReq, e.RequestID)
+	}
+	if e.WallTime != nil {
+		nr.StartedAt = e.WallTime.Time()
+	}
+	if e.Timestamp != nil {
+		r.startTS[e.RequestID] = e.Timestamp.Time()
+		if r.docStart.IsZero() && e.Type == network.ResourceTypeDocument {
+			r.docStart = e.Timestamp.Time()
+		}
+	}
+	if e.Initiator != nil {
+		nr.Initiator = string(e.Initiator.Type)
+		nr.InitiatorURL = e.Initiator.URL
+		nr.InitiatorLn = int(e.Initiator.LineNumber) + 1
+		if nr.InitiatorURL == "" && e.Initiator.Stack != nil && len(e.Initiator.Stack.CallFrames) > 0 {
+			cf := e.Initiator.Stack.CallFrames[0]
+			nr.InitiatorURL = cf.URL
+			nr.InitiatorLn = int(cf.LineNumber) + 1
+		}
+	}
+
+	// بدنهٔ درخواست فقط برای XHR/fetch نگه داشته می‌شود
+	if isAPIRequestType(nr.Type) && e.Request.HasPostData {
+		var sb strings.Builder
+		for _, pe := range e.Request.PostDataEntries {
+			if b, err := base64.StdEncoding.DecodeString(pe.Bytes); err == nil {
+				sb.Write(b)
+			}
+		}
+		if sb.Len() > 0 {
+			nr.PostData = truncateBytes(sb.String(), maxPostDataBytes)
+		} else {
+			// بدنه‌های بزرگ در رویداد نمی‌آیند؛ جدا می‌گیریم (داخل listener نمی‌شود CDP را بلاک کرد)
+			go r.fetchPostData(e.RequestID, nr)
+		}
+	}
+
+	r.byID[e.RequestID] = nr
+	r.order = append(r.order, nr)
+}
+
+// onRequestExtra: هدرهای واقعی ارسالی (با Cookie) از شبکهٔ کروم
+func (r *networkRecorder) onRequestExtra(e *network.EventRequestWillBeSentExtraInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if nr := r.byID[e.RequestID]; nr != nil {
+		nr.RequestHeaders = headerList(e.Headers)
+		return
+	}
+	r.extraReq[e.RequestID] = e.Headers
+}
+
+// onResponseExtra: هدرهای خام پاسخ (با Set-Cookie) بر هدرهای فیلترشدهٔ Response اولویت دارند
+func (r *networkRecorder) onResponseExtra(e *network.EventResponseReceivedExtraInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if nr := r.byID[e.RequestID]; nr != nil && nr.Status > 0 {
+		nr.ResponseHeaders = headerList(e.Headers)
+		return
+	}
+	r.extraResp[e.RequestID] = e.Headers
+}
+
+func (r *networkRecorder) fetchPostData(id network.RequestID, nr *models.NetworkRequest) {
+	var body string
+	_ = chromedp.Run(r.ctx, chromedp.ActionFunc(func(c context.Context) error {
+		var err error
+		body, err = network.GetRequestPostData(id).Do(c)
+		return err
+	}))
+	if body == "" {
+		return
+	}
+	r.mu.Lock()
+	nr.PostData = truncateBytes(body, maxPostDataBytes)
+	r.mu.Unlock()
+}
+
+func (r *networkRecorder) onResponse(id network.RequestID, typ network.ResourceType, resp *network.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if nr := r.byID[id]; nr != nil {
+		r.applyResponse(nr, typ, resp)
+		if h, ok := r.extraResp[id]; ok {
+			nr.ResponseHeaders = headerList(h)
+			delete(r.extraResp, id)
+		}
+	}
+}
+
+// applyResponse: باید با r.mu گرفته‌شده صدا زده شود
+func (r *networkRecorder) applyResponse(nr *models.NetworkRequest, typ network.ResourceType, resp *network.Response) {
+	if resp == nil {
+		return
+	}
+	if typ != "" {
+		nr.Type = string(typ)
+	}
+	nr.Status = int(resp.Status)
+	nr.StatusText = resp.StatusText
+	nr.MIMEType = resp.MimeType
+	nr.RemoteIP = resp.RemoteIPAddress
+	nr.Protocol = resp.Protocol
+	nr.FromCache = resp.FromDiskCache || resp.FromPrefetchCache || resp.FromServiceWorker
+	nr.ResponseHeaders = headerList(resp.Headers)
+	if resp.Timing != nil {
+		nr.Timing = harTiming(resp.Timing)
+	}
+}
+
+func (r *networkRecorder) onFinished(e *network.EventLoadingFinished) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nr := r.byID[e.RequestID]
+	if nr == nil {
+		return
+	}
+	nr.EncodedSize = int64(e.EncodedDataLength)
+	r.setDuration(e.RequestID, nr, e.Timestamp)
+	if r.bodies && r.budget.left > 0 {
+		r.pendingBodies.Add(1)
+		go r.fetchBody(e.RequestID, nr)
+	}
+}
+
+// fetchBody: بدنهٔ پاسخ تا سقف HAR_BODY_MAX_BYTES؛ بدنه‌های باینری base64 ذخیره می‌شوند
+func (r *networkRecorder) fetchBody(id network.RequestID, nr *models.NetworkRequest) {
+	defer r.pendingBodies.Done()
+	var body []byte
+	err := chromedp.Run(r.ctx, chromedp.ActionFunc(func(c context.Context) error {
+		var err error
+		body, err = network.GetResponseBody(id).Do(c)
+		return err
+	}))
+	if err != nil || len(body) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nr.BodySize = int64(len(body))
+	limit := r.budget.allow(len(body))
+	if limit == 0 {
+		return
+	}
+	if len(body) > limit {
+		body = body[:limit]
+		nr.BodyTruncated = true
+	}
+	if utf8.Valid(body) {
+		nr.Body = string(body)
+	} else {
+		nr.Body = base64.StdEncoding.EncodeToString(body)
+		nr.BodyBase64 = true
+	}
+	r.budget.spend(len(nr.Body))
+}
+
+func (r *networkRecorder) onFailed(e *network.EventLoadingFailed) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nr := r.byID[e.RequestID]
+	if nr == nil {
+		return
+	}
+	nr.Failed = true
+	nr.ErrorText = e.ErrorText
+	if e.BlockedReason != "" {
+		nr.ErrorText += " (blocked: " + string(e.BlockedReason) + ")"
+	}
+	if e.Canceled && nr.ErrorText == "" {
+		nr.ErrorText = "canceled"
+	}
+	r.setDuration(e.RequestID, nr, e.Timestamp)
+}
+
+func (r *networkRecorder) onPageEvent(ts *cdp.MonotonicTime, dst *float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ts == nil || r.docStart.IsZero() || *dst != 0 {
+		return
+	}
+	*dst = float64(ts.Time().Sub(r.docStart).Microseconds()) / 1000
+}
+
+// setDuration: مدت کل درخواست؛ باقی‌ماندهٔ آن بعد از فازهای قبلی سهم receive است
+func (r *networkRecorder) setDuration(id network.RequestID, nr *models.NetworkRequest, ts *cdp.MonotonicTime) {
+	start, ok := r.startTS[id]
+	if !ok || ts == nil {
+		return
+	}
+	nr.DurationMs = float64(ts.Time().Sub(start).Microseconds()) / 1000
+	if t := nr.Timing; t != nil {
+		used := 0.0
+		for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait} {
+			if v > 0 {
+				used += v
+			}
+		}
+		if rest := nr.DurationMs - used; rest > 0 {
+			t.Receive = rest
+		}
+	}
+}
+
+// waitBodies: تا timeout منتظر گرفتن بدنه‌های در جریان می‌ماند
+func (r *networkRecorder) waitBodies(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.pendingBodies.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// entries: کپی از لاگ تا این لحظه
+func (r *networkRecorder) entries() []models.NetworkRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]models.NetworkRequest, 0, len(r.order))
+	for _, nr := range r.order {
+		c := *nr
+		if nr.Timing != nil {
+			t := *nr.Timing
+			c.Timing = &t
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (r *networkRecorder) timings() models.PageTimings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pageTimings
+}
+
+// harTiming: ResourceTiming کروم (آفست‌ها نسبت به requestTime) را به فازهای HAR تبدیل می‌کند
+func harTiming(t *network.ResourceTiming) *models.NetworkTiming {
+	phase := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+	out := &models.NetworkTiming{
+		DNS:     phase(t.DNSStart, t.DNSEnd),
+		Connect: phase(t.ConnectStart, t.ConnectEnd),
+		SSL:     phase(t.SslStart, t.SslEnd),
+		Send:    phase(t.SendStart, t.SendEnd),
+		Wait:    phase(t.SendEnd, t.ReceiveHeadersEnd),
+	}
+	out.Blocked = t.SendStart
+	for _, v := range []float64{t.DNSStart, t.ConnectStart} {
+		if v >= 0 {
+			out.Blocked = v
+			break
+		}
+	}
+	if out.Blocked < 0 {
+		out.Blocked = -1
+	}
+	if out.Send < 0 {
+		out.Send = 0
+	}
+	if out.Wait < 0 {
+		out.Wait = 0
+	}
+	return out
+}
+
+// headerList: هدرهای CDP (تکراری‌ها با \n به هم چسبیده‌اند) به فهرست مرتب
+func headerList(h network.Headers) []models.HTTPHeader {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make([]models.HTTPHeader, 0, len(h))
+	for k, v := range h {
+		for _, line := range strings.Split(fmt.Sprint(v), "\n") {
+			out = append(out, models.HTTPHeader{Name: k, Value: line})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+func isAPIRequestType(t string) bool {
+	return t == string(network.ResourceTypeXHR) || t == string(network.ResourceTypeFetch)
+}
+
+// bodyBudget: سقف هر بدنه (HAR_BODY_MAX_BYTES) و مجموع بدنه‌های یک صفحه (HAR_BODIES_TOTAL_BYTES)؛
+// هم ضبط زنده و هم import HAR از همین استفاده می‌کنند
+type bodyBudget struct {
+	max, left int
+}
+
+func newBodyBudget() bodyBudget {
+	return bodyBudget{max: envInt("HAR_BODY_MAX_BYTES", 256<<10), left: envInt("HAR_BODIES_TOTAL_BYTES", 8<<20)}
+}
+
+// allow: چند بایت از بدنهٔ n بایتی ذخیره شود (۰ یعنی هیچ)
+func (b *bodyBudget) allow(n int) int {
+	return max(0, min(n, b.max, b.left))
+}
+
+func (b *bodyBudget) spend(n int) { b.left -= n }
+
+func truncateBytes(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// saveNetworkLog: لاگ شبکهٔ صفحه را جایگزین می‌کند؛ اندپوینت‌های مشاهده‌شده را SaveScanResponse ثبت می‌کند
+func saveNetworkLog(ctx context.Context, siteID, urlNorm string, resp *models.ScanResponse) error {
+	reqs := resp.Requests
+	if len(reqs) == 0 {
+		return nil
+	}
+	doc := models.NetworkLogDoc{
+		SiteID:     siteID,
+		PageURL:    urlNorm,
+		Requests:   reqs,
+		Count:      len(reqs),
+		Truncated:  len(reqs) >= maxNetworkRequests,
+		Title:      resp.Title,
+		CapturedAt: time.Now(),
+	}
+	if resp.PageTimings != nil {
+		doc.Timings = *resp.PageTimings
+	}
+	if err := fitNetworkLog(&doc, envInt("NETWORK_LOG_MAX_BYTES", 12<<20)); err != nil {
+		return err
+	}
+	for _, nr := range doc.Requests {
+		if nr.Body != "" {
+			doc.HasBodies = true
+			break
+		}
+	}
+	_, err := models.NetworkColl().UpdateOne(ctx,
+		bson.M{"page_url": urlNorm},
+		bson.M{"$set": doc},
+		mopts.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// observedEndpoint: همهٔ XHR/fetchهای یک اسکن به یک endpoint
+type observedEndpoint struct {
+	host       string
+	hosts      []string
+	methods    []string
+	lastStatus int
+}
+
+// observedEndpoints: درخواست‌های API هم‌سایت با کلید endpoint (path?query)؛
+// endpointی که در یک اسکن چند بار صدا زده شود (polling) یک مشاهده است
+func observedEndpoints(reqs []models.NetworkRequest, pageHost string) map[string]*observedEndpoint {
+	out := map[string]*observedEndpoint{}
+	for _, nr := range reqs {
+		if !isAPIRequestType(nr.Type) {
+			continue
+		}
+		u, err := url.Parse(nr.URL)
+		if err != nil || !sameETLDPlusOne(u.Hostname(), pageHost) {
+			continue
+		}
+		ep := u.EscapedPath()
+		if ep == "" {
+			ep = "/"
+		}
+		if u.RawQuery != "" {
+			ep += "?" + u.RawQuery
+		}
+		host := strings.ToLower(u.Hostname())
+		o := out[ep]
+		if o == nil {
+			o = &observedEndpoint{host: host}
+			out[ep] = o
+		}
+		o.hosts = appendUnique(o.hosts, host)
+		if nr.Method != "" {
+			o.methods = appendUnique(o.methods, nr.Method)
+		}
+		if nr.Status > 0 {
+			o.lastStatus = nr.Status
+		}
+	}
+	return out
+}
+
+// fitNetworkLog: سند لاگ باید زیر سقف ۱۶MB Mongo بماند؛ اگر سریالایزشده از limit بزرگ‌تر باشد
+// اول بدنه‌ها (بزرگ‌ترین اول) و بعد درخواست‌های انتهای لیست حذف می‌شوند. resp.Requests دست نمی‌خورد
+func fitNetworkLog(doc *models.NetworkLogDoc, limit int) error {
+	size := func() (int, error) {
+		b, err := bson.Marshal(doc)
+		return len(b), err
+	}
+	n, err := size()
+	if err != nil || n <= limit {
+		return err
+	}
+	doc.Requests = append([]models.NetworkRequest(nil), doc.Requests...)
+
+	var withBody []int
+	for i := range doc.Requests {
+		if doc.Requests[i].Body != "" {
+			withBody = append(withBody, i)
+		}
+	}
+	sort.Slice(withBody, func(a, b int) bool { return len(doc.Requests[withBody[a]].Body) > len(doc.Requests[withBody[b]].Body) })
+	for _, i := range withBody {
+		if n <= limit {
+			break
+		}
+		n -= len(doc.Requests[i].Body)
+		doc.Requests[i].Body, doc.Requests[i].BodyBase64, doc.Requests[i].BodyTruncated = "", false, true
+	}
+	if n, err = size(); err != nil {
+		return err
+	}
+	for n > limit && len(doc.Requests) > 0 {
+		doc.Requests = doc.Requests[:len(doc.Requests)-max(1, len(doc.Requests)/10)]
+		doc.Truncated = true
+		if n, err = size(); err != nil {
+			return err
+		}
+	}
+	doc.Count = len(doc.Requests)
+	return nil
+}
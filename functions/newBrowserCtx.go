@@ -1,12 +1,15 @@
 package functions
 
 import (
+	"SiteChecker/models"
 	"context"
+	"log"
+
 	"github.com/chromedp/chromedp"
 )
 
-func newBrowserCtx(parent context.Context) (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+func browserAllocOptions() []chromedp.ExecAllocatorOption {
+	return append(chromedp.DefaultExecAllocatorOptions[:],
 
 		chromedp.ExecPath("/usr/bin/chromium"),
 
@@ -19,8 +22,23 @@ func newBrowserCtx(parent context.Context) (context.Context, context.CancelFunc)
 		chromedp.Flag("hide-scrollbars", true),
 		chromedp.Flag("window-size", "1366,768"),
 	)
+}
+
+// newBrowserCtx: اگر پول فعال باشد یک تب ایزوله از پول، وگرنه یک پروسهٔ Chromium جدا.
+// پروکسی (اگر باشد) روی BrowserContext همان تب اعمال می‌شود و بقیهٔ اسکن‌ها را تحت تأثیر قرار نمی‌دهد.
+func newBrowserCtx(parent context.Context, proxy *models.ProxyConfig) (context.Context, context.CancelFunc) {
+	if p := browserPool; p != nil {
+		ctx, cancel, err := p.acquire(parent, proxyContextOptions(proxy)...)
+		if err == nil {
+			return ctx, cancel
+		}
+		log.Printf("[browser-pool] acquire failed, falling back to dedicated browser: %v", err)
+		p.mu.Lock()
+		p.stats.Fallbacks++
+		p.mu.Unlock()
+	}
 
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parent, opts...)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parent, append(browserAllocOptions(), proxyAllocOptions(proxy)...)...)
 	ctx, cancelCtx := chromedp.NewContext(allocCtx)
 	return ctx, func() {
 		cancelCtx()
@@ -0,0 +1,283 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	openapiParamRe = regexp.MustCompile(`\{([^{}/]+)\}`)
+	openapiOpIDRe  = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// openapiObserved: پاسخ‌ها و content-type درخواست‌های دیده‌شده برای یک (path, method)
+type openapiObserved struct {
+	statuses map[int]map[string]struct{} // status → mime types
+	reqTypes map[string]struct{}
+}
+
+// BuildOpenAPI: سند OpenAPI 3.1 از endpointهای ذخیره‌شدهٔ سایت (+ لاگ شبکه برای پاسخ‌ها)
+func BuildOpenAPI(ctx context.Context, siteID string) (*models.OpenAPIDoc, error) {
+	var site models.SiteDoc
+	if err := models.SitesColl().FindOne(ctx, bson.M{"_id": siteID}).Decode(&site); err != nil {
+		return nil, err
+	}
+
+	cur, err := models.EndpointsColl().Find(ctx, bson.M{"site_id": siteID},
+		mopts.Find().SetSort(bson.D{{Key: "seen_count", Value: -1}}).SetLimit(int64(envInt("OPENAPI_MAX_ENDPOINTS", 5000))))
+	if err != nil {
+		return nil, err
+	}
+	var eps []models.EndpointDoc
+	if err := cur.All(ctx, &eps); err != nil {
+		return nil, err
+	}
+
+	servers := map[string]struct{}{}
+	for _, h := range site.Hosts {
+		servers["https://"+h] = struct{}{}
+	}
+	observed, err := openapiObservations(ctx, siteID, servers)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &models.OpenAPIDoc{
+		OpenAPI: "3.1.0",
+		Info: models.OpenAPIInfo{
+			Title:       siteID + " (discovered)",
+			Description: "Generated from endpoints discovered by SiteChecker scans. Methods and parameters are inferred and may be incomplete.",
+			Version:     time.Now().UTC().Format("2006-01-02"),
+		},
+		Paths: map[string]models.OpenAPIPathItem{},
+	}
+
+	pathKeys := map[string]string{} // شکل مسیر بدون نام پارامتر → مسیر انتخاب‌شده
+	opIDs := map[string]int{}
+	for _, ep := range eps {
+		tmpl := ep.Template
+		if tmpl == "" {
+			tmpl = TemplateEndpoint(ep.Endpoint).Template
+		}
+		p := tmpl
+		if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+			u, err := url.Parse(p)
+			if err != nil {
+				continue
+			}
+			servers[u.Scheme+"://"+u.Host] = struct{}{}
+			p = strings.TrimPrefix(p, u.Scheme+"://"+u.Host)
+		}
+		// مسیرهای ./ و ../ بدون صفحهٔ مبدأ قابل تبدیل به path مطلق نیستند
+		if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+			continue
+		}
+
+		// OpenAPI دو مسیر هم‌شکل با نام پارامتر متفاوت را مجاز نمی‌داند
+		shape := openapiParamRe.ReplaceAllString(p, "{}")
+		if first, ok := pathKeys[shape]; ok {
+			p = first
+		} else {
+			pathKeys[shape] = p
+		}
+		item := doc.Paths[p]
+		if item == nil {
+			item = models.OpenAPIPathItem{}
+			doc.Paths[p] = item
+		}
+
+		methods := uniqueStrings(append(append([]string{}, ep.Methods...), ep.InferredMethods...))
+		if len(methods) == 0 {
+			methods = []string{"GET"}
+		}
+		examples := paramExamples(ep.ParamExamples)
+		for _, m := range methods {
+			m = strings.ToLower(m)
+			op := item[m]
+			if op == nil {
+				op = &models.OpenAPIOperation{OperationID: openapiOperationID(m, p, opIDs)}
+				if ep.Category != "" {
+					op.Tags = []string{ep.Category}
+				}
+				for _, name := range openapiPathParams(p) {
+					op.Parameters = append(op.Parameters, openapiParam(name, "path", examples[name]))
+				}
+				item[m] = op
+			}
+			op.XObserved = op.XObserved || ep.Observed
+			op.XSeenCount += ep.SeenCount
+			for _, x := range ep.Extractors {
+				op.XExtractors = appendUnique(op.XExtractors, x)
+			}
+			if len(op.XExamples) < 5 {
+				op.XExamples = appendUnique(op.XExamples, ep.Endpoint)
+			}
+			for _, q := range ep.QueryParams {
+				if !hasOpenAPIParam(op.Parameters, q, "query") {
+					op.Parameters = append(op.Parameters, openapiParam(q, "query", examples[q]))
+				}
+			}
+		}
+	}
+
+	for p, item := range doc.Paths {
+		for m, op := range item {
+			sort.Strings(op.XExtractors)
+			obs := observed[p+" "+strings.ToUpper(m)]
+			op.Responses = openapiResponses(obs)
+			if obs != nil && len(obs.reqTypes) > 0 && (m == "post" || m == "put" || m == "patch") {
+				op.RequestBody = &models.OpenAPIRequestBody{Content: map[string]models.OpenAPIMediaType{}}
+				for t := range obs.reqTypes {
+					op.RequestBody.Content[t] = models.OpenAPIMediaType{}
+				}
+			}
+		}
+	}
+
+	for s := range servers {
+		doc.Servers = append(doc.Servers, models.OpenAPIServer{URL: s})
+	}
+	sort.Slice(doc.Servers, func(i, j int) bool { return doc.Servers[i].URL < doc.Servers[j].URL })
+	return doc, nil
+}
+
+// openapiObservations: درخواست‌های XHR/Fetch هم‌سایت از لاگ‌های شبکه، با کلید "template METHOD"
+func openapiObservations(ctx context.Context, siteID string, servers map[string]struct{}) (map[string]*openapiObserved, error) {
+	cur, err := models.NetworkColl().Find(ctx, bson.M{"site_id": siteID},
+		mopts.Find().SetProjection(bson.M{
+			"requests.url": 1, "requests.method": 1, "requests.type": 1, "requests.status": 1,
+			"requests.mime_type": 1, "requests.request_headers": 1,
+		}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := map[string]*openapiObserved{}
+	for cur.Next(ctx) {
+		var doc models.NetworkLogDoc
+		if err := cur.Decode(&doc); err != nil {
+			continue
+		}
+		for _, nr := range doc.Requests {
+			if !isAPIRequestType(nr.Type) || nr.Status <= 0 {
+				continue
+			}
+			u, err := url.Parse(nr.URL)
+			if err != nil || !sameETLDPlusOne(u.Hostname(), siteID) {
+				continue
+			}
+			servers[u.Scheme+"://"+u.Host] = struct{}{}
+			p := u.EscapedPath()
+			if p == "" {
+				p = "/"
+			}
+			key := TemplateEndpoint(p).Template + " " + strings.ToUpper(nr.Method)
+			o := out[key]
+			if o == nil {
+				o = &openapiObserved{statuses: map[int]map[string]struct{}{}, reqTypes: map[string]struct{}{}}
+				out[key] = o
+			}
+			if o.statuses[nr.Status] == nil {
+				o.statuses[nr.Status] = map[string]struct{}{}
+			}
+			if nr.MIMEType != "" {
+				o.statuses[nr.Status][nr.MIMEType] = struct{}{}
+			}
+			if ct := headerValue(nr.RequestHeaders, "Content-Type"); ct != "" {
+				if mt, _, err := mime.ParseMediaType(ct); err == nil {
+					o.reqTypes[mt] = struct{}{}
+				}
+			}
+		}
+	}
+	return out, cur.Err()
+}
+
+func openapiResponses(obs *openapiObserved) map[string]models.OpenAPIResponse {
+	if obs == nil || len(obs.statuses) == 0 {
+		return map[string]models.OpenAPIResponse{"default": {Description: "Response not observed"}}
+	}
+	out := map[string]models.OpenAPIResponse{}
+	for status, types := range obs.statuses {
+		r := models.OpenAPIResponse{Description: "Observed response"}
+		if len(types) > 0 {
+			r.Content = map[string]models.OpenAPIMediaType{}
+			for t := range types {
+				r.Content[t] = models.OpenAPIMediaType{}
+			}
+		}
+		out[strconv.Itoa(status)] = r
+	}
+	return out
+}
+
+func openapiPathParams(p string) []string {
+	var out []string
+	for _, m := range openapiParamRe.FindAllStringSubmatch(p, -1) {
+		out = appendUnique(out, m[1])
+	}
+	return out
+}
+
+func openapiParam(name, in, example string) models.OpenAPIParameter {
+	param := models.OpenAPIParameter{Name: name, In: in, Required: in == "path", Schema: models.OpenAPISchema{Type: "string"}}
+	if example != "" {
+		param.Example = example
+	}
+	switch {
+	case name == "uuid":
+		param.Schema.Format = "uuid"
+	case name == "date":
+		param.Schema.Format = "date"
+	case tmplNumericRe.MatchString(example):
+		if n, err := strconv.ParseInt(example, 10, 64); err == nil {
+			param.Schema.Type = "integer"
+			param.Example = n
+		}
+	}
+	return param
+}
+
+func hasOpenAPIParam(params []models.OpenAPIParameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// paramExamples: "name=value" های ذخیره‌شده → اولین مقدار هر پارامتر
+func paramExamples(pairs []string) map[string]string {
+	out := map[string]string{}
+	for _, kv := range pairs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if _, seen := out[k]; !seen {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func openapiOperationID(method, p string, used map[string]int) string {
+	id := method + "_" + strings.Trim(openapiOpIDRe.ReplaceAllString(p, "_"), "_")
+	used[id]++
+	if n := used[id]; n > 1 {
+		id += "_" + strconv.Itoa(n)
+	}
+	return id
+}
@@ -0,0 +1,23 @@
+package functions
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PageKeys: همان site_id و url_norm که SaveScanResults برای صفحه می‌سازد
+func PageKeys(rawURL string) (siteID, urlNorm string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	urlNorm = strings.ToLower(u.Scheme) + "://" + u.Host + p
+	if u.RawQuery != "" {
+		urlNorm += "?" + u.RawQuery
+	}
+	return eTLD1(u.Hostname()), urlNorm, nil
+}
@@ -0,0 +1,225 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// msgHandlerEntry: یک listener پیام از window.__scMsgHandlers
+type msgHandlerEntry struct {
+	Via      string `json:"via"` // addEventListener | onmessage
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Func     string `json:"func"`
+	Source   string `json:"source"`
+	Received int    `json:"received"`
+	Foreign  int    `json:"foreign"` // پیام‌های دریافتی با origin "null" (iframe sandbox)
+	Reached  []struct {
+		Kind   string `json:"kind"`
+		Origin string `json:"origin"`
+	} `json:"reached"` // sinkهایی که marker پیام در حین اجرای handler به آن‌ها رسید
+}
+
+// ارجاع به origin رویداد: e.origin یا e["origin"] (location.origin و مشابه حساب نمی‌شوند)
+var (
+	reOriginProp   = regexp.MustCompile(`([\w$]+)\s*(?:\.\s*origin\b|\[\s*["']origin["']\s*\])`)
+	reOriginBare   = regexp.MustCompile(`(?:^|[^\w$.])(origin)\b`)
+	reDestructured = regexp.MustCompile(`^\s*(?:async\s*)?(?:function\b[^(]*)?\(\s*\{([^}]*)\}`)
+	reOriginWord   = regexp.MustCompile(`\borigin\b`)
+	reSourceOnly   = regexp.MustCompile(`\.\s*source\s*[!=]==?`)
+
+	reAfterSubstr   = regexp.MustCompile(`^\s*\.\s*(indexOf|includes|startsWith|search|lastIndexOf)\s*\(`)
+	reAfterEndsWith = regexp.MustCompile("^\\s*\\.\\s*endsWith\\s*\\(\\s*[\"'`]([^\"'`]*)")
+	reAfterMatch    = regexp.MustCompile(`^\s*\.\s*match\s*\(\s*/((?:\\.|[^/\n])+)/`)
+	reAfterStar     = regexp.MustCompile("^\\s*[!=]==?\\s*[\"'`]\\*[\"'`]")
+	reAfterCompare  = regexp.MustCompile(`^\s*[!=]==?`)
+
+	reBeforeStrSubstr = regexp.MustCompile("[\"'`][^\"'`]*[\"'`]\\s*\\.\\s*(?:includes|indexOf)\\s*\\(\\s*$")
+	reBeforeList      = regexp.MustCompile(`\.\s*(?:includes|indexOf|has)\s*\(\s*$`)
+	reBeforeStar      = regexp.MustCompile("[\"'`]\\*[\"'`]\\s*[!=]==?\\s*$")
+	reBeforeCompare   = regexp.MustCompile(`[!=]==?\s*$`)
+	reBeforeRegexTest = regexp.MustCompile(`/((?:\\.|[^/\n])+)/[gimsuy]*\s*\.\s*test\s*\(\s*$`)
+	reBeforeTest      = regexp.MustCompile(`\.\s*test\s*\(\s*$`)
+
+	reUnescapedDot = regexp.MustCompile(`(?:^|[^\\])\.`)
+)
+
+// objectهایی که .origin آن‌ها origin رویداد نیست
+var nonEventOrigin = map[string]bool{
+	"location": true, "window": true, "self": true, "globalThis": true, "document": true,
+	"top": true, "parent": true, "opener": true, "url": true, "URL": true,
+}
+
+// AnalyzeOriginCheck: بررسی استاتیک سورس handler برای چک event.origin
+// خروجی: یکی از models.OriginCheck* و شواهد (تکه‌کد منطبق)
+func AnalyzeOriginCheck(src string) (string, []string) {
+	if strings.TrimSpace(src) == "" || strings.Contains(src, "[native code]") {
+		return models.OriginCheckUnknown, []string{"handler source unavailable (bound or native function)"}
+	}
+
+	var spans [][2]int
+	for _, m := range reOriginProp.FindAllStringSubmatchIndex(src, -1) {
+		if !nonEventOrigin[src[m[2]:m[3]]] {
+			spans = append(spans, [2]int{m[0], m[1]})
+		}
+	}
+	// function({origin, data}) {...}
+	if d := reDestructured.FindStringSubmatch(src); d != nil && reOriginWord.MatchString(d[1]) {
+		head := len(d[0])
+		for _, m := range reOriginBare.FindAllStringSubmatchIndex(src[head:], -1) {
+			spans = append(spans, [2]int{head + m[2], head + m[3]})
+		}
+	}
+	if len(spans) == 0 {
+		if reSourceOnly.MatchString(src) {
+			return models.OriginCheckMissing, []string{"only event.source is compared"}
+		}
+		return models.OriginCheckMissing, nil
+	}
+
+	found := map[string]bool{}
+	var evidence []string
+	add := func(verdict string, start, end int) {
+		found[verdict] = true
+		if len(evidence) < 5 {
+			evidence = append(evidence, strings.TrimSpace(src[max(0, start-40):min(len(src), end+60)]))
+		}
+	}
+	for _, sp := range spans {
+		before := src[max(0, sp[0]-120):sp[0]]
+		after := src[sp[1]:min(len(src), sp[1]+120)]
+		switch {
+		case reAfterSubstr.MatchString(after), reBeforeStrSubstr.MatchString(before):
+			add(models.OriginCheckBypassable, sp[0], sp[1])
+		case reAfterEndsWith.MatchString(after):
+			if lit := reAfterEndsWith.FindStringSubmatch(after)[1]; strings.HasPrefix(lit, ".") {
+				add(models.OriginCheckStrict, sp[0], sp[1])
+			} else {
+				add(models.OriginCheckBypassable, sp[0], sp[1])
+			}
+		case reAfterMatch.MatchString(after):
+			add(regexOriginVerdict(reAfterMatch.FindStringSubmatch(after)[1]), sp[0], sp[1])
+		case reBeforeRegexTest.MatchString(before):
+			add(regexOriginVerdict(reBeforeRegexTest.FindStringSubmatch(before)[1]), sp[0], sp[1])
+		case reAfterStar.MatchString(after), reBeforeStar.MatchString(before):
+			add(models.OriginCheckWildcard, sp[0], sp[1])
+		case reAfterCompare.MatchString(after), reBeforeCompare.MatchString(before), reBeforeList.MatchString(before):
+			add(models.OriginCheckStrict, sp[0], sp[1])
+		case reBeforeTest.MatchString(before):
+			add(models.OriginCheckUnknown, sp[0], sp[1])
+		}
+	}
+
+	// ضعیف‌ترین چک تعیین‌کننده است
+	for _, v := range []string{models.OriginCheckBypassable, models.OriginCheckWildcard, models.OriginCheckStrict} {
+		if found[v] {
+			return v, evidence
+		}
+	}
+	if len(evidence) == 0 {
+		evidence = append(evidence, strings.TrimSpace(src[max(0, spans[0][0]-40):min(len(src), spans[0][1]+60)]))
+	}
+	return models.OriginCheckUnknown, evidence
+}
+
+// regexOriginVerdict: regex بدون $ یا با نقطهٔ escape‌نشده قابل دور زدن است (a.com.evil.com، aXcom)
+func regexOriginVerdict(pattern string) string {
+	if !strings.HasSuffix(pattern, "$") || reUnescapedDot.MatchString(pattern) {
+		return models.OriginCheckBypassable
+	}
+	if !strings.HasPrefix(pattern, "^") && !strings.HasPrefix(pattern, "https") && !strings.HasPrefix(pattern, `\/\/`) {
+		return models.OriginCheckBypassable
+	}
+	return models.OriginCheckStrict
+}
+
+// CollectMessageHandlers: خواندن window.__scMsgHandlers و ساخت finding برای هر handler
+func CollectMessageHandlers(ctx context.Context, pageURL, siteID string) ([]models.FindingDoc, error) {
+	var entries []msgHandlerEntry
+	if err := chromedp.Run(ctx, chromedp.EvaluateAsDevTools(`window.__scMsgHandlers || []`, &entries)); err != nil {
+		return nil, err
+	}
+	out := make([]models.FindingDoc, 0, len(entries))
+	seen := make(map[string]int, len(entries))
+	for _, e := range entries {
+		f := messageHandlerFinding(siteID, pageURL, e)
+		if i, ok := seen[f.Sig]; ok {
+			// همان handler چند بار ثبت شده؛ یکی کافی است (بدترین نتیجه)
+			if models.SeverityRank(f.Severity) > models.SeverityRank(out[i].Severity) {
+				out[i] = f
+			}
+			continue
+		}
+		seen[f.Sig] = len(out)
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// messageHandlerFinding: نتیجهٔ چک origin (استاتیک) + رسیدن پیام iframe خارجی به sink (runtime)
+func messageHandlerFinding(siteID, pageURL string, e msgHandlerEntry) models.FindingDoc {
+	verdict, evidence := AnalyzeOriginCheck(e.Source)
+	srcURL, _ := normalizeSourceURL(pageURL, e.File)
+
+	var foreignSinks, sameSinks []string
+	for _, r := range e.Reached {
+		if r.Origin == "null" {
+			foreignSinks = appendUnique(foreignSinks, r.Kind)
+		} else {
+			sameSinks = appendUnique(sameSinks, r.Kind)
+		}
+	}
+
+	severity := models.SeverityInfo
+	title := fmt.Sprintf("message handler with %s origin check", verdict)
+	switch {
+	case len(foreignSinks) > 0:
+		severity = models.SeverityHigh
+		title = fmt.Sprintf("message from foreign origin reaches %s (%s origin check)", strings.Join(foreignSinks, ", "), verdict)
+	case verdict == models.OriginCheckMissing, verdict == models.OriginCheckWildcard, verdict == models.OriginCheckBypassable:
+		severity = models.SeverityMedium
+	case verdict == models.OriginCheckUnknown:
+		severity = models.SeverityLow
+	}
+
+	srcSum := sha256.Sum256([]byte(e.Source))
+	target := fmt.Sprintf("%s:%d:%d", srcURL, e.Line, e.Col)
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + models.FindingPostMessageHandler + "\x1f" + pageURL + "\x1f" +
+		target + "\x1f" + hex.EncodeToString(srcSum[:8])))
+
+	source := e.Source
+	if len(source) > 4000 {
+		source = source[:4000]
+	}
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   siteID,
+		PageURL:  pageURL,
+		Type:     models.FindingPostMessageHandler,
+		Severity: severity,
+		Title:    title,
+		URL:      srcURL,
+		Details: map[string]any{
+			"origin_check":      verdict,
+			"evidence":          evidence,
+			"via":               e.Via,
+			"line":              e.Line,
+			"col":               e.Col,
+			"func":              e.Func,
+			"source":            source,
+			"received":          e.Received,
+			"foreign_received":  e.Foreign,
+			"foreign_sinks":     foreignSinks,
+			"same_origin_sinks": sameSinks,
+			"confidence":        models.ConfidenceRuntime,
+		},
+	}
+}
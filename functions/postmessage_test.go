@@ -0,0 +1,48 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"testing"
+)
+
+func TestAnalyzeOriginCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		want         string
+		wantEvidence bool
+	}{
+		{"empty source", "", models.OriginCheckUnknown, true},
+		{"native function", "function () { [native code] }", models.OriginCheckUnknown, true},
+		{"origin never read", `function(e){ render(e.data) }`, models.OriginCheckMissing, false},
+		{"only source compared", `function(e){ if (e.source !== win) return; render(e.data) }`, models.OriginCheckMissing, true},
+		{"location.origin is not the event origin", `function(e){ send(location.origin, e.data) }`, models.OriginCheckMissing, false},
+		{"strict equality", `function(e){ if (e.origin !== "https://a.com") return; render(e.data) }`, models.OriginCheckStrict, true},
+		{"strict literal on the left", `function(e){ if ("https://a.com" === e.origin) render(e.data) }`, models.OriginCheckStrict, true},
+		{"bracket access", `function(e){ if (e["origin"] === "https://a.com") render(e.data) }`, models.OriginCheckStrict, true},
+		{"allow list includes", `function(e){ if (!ALLOWED.includes(e.origin)) return; render(e.data) }`, models.OriginCheckStrict, true},
+		{"indexOf substring", `function(e){ if (e.origin.indexOf("a.com") > -1) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"startsWith", `function(e){ if (e.origin.startsWith("https://a.com")) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"string includes origin", `function(e){ if ("https://a.com https://b.com".includes(e.origin)) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"endsWith dotted suffix", `function(e){ if (e.origin.endsWith(".a.com")) render(e.data) }`, models.OriginCheckStrict, true},
+		{"endsWith without dot", `function(e){ if (e.origin.endsWith("a.com")) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"anchored escaped regex test", `function(e){ if (/^https:\/\/a\.com$/.test(e.origin)) render(e.data) }`, models.OriginCheckStrict, true},
+		{"unescaped dot regex test", `function(e){ if (/^https:\/\/a.com$/.test(e.origin)) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"unanchored match", `function(e){ if (e.origin.match(/a\.com/)) render(e.data) }`, models.OriginCheckBypassable, true},
+		{"wildcard compare", `function(e){ if (e.origin === "*" || ok) render(e.data) }`, models.OriginCheckWildcard, true},
+		{"unknown regex variable", `function(e){ if (re.test(e.origin)) render(e.data) }`, models.OriginCheckUnknown, true},
+		{"destructured origin", `function({origin, data}){ if (origin !== "https://a.com") return; render(data) }`, models.OriginCheckStrict, true},
+		{"weakest check wins", `function(e){ if (e.origin === "https://a.com" || e.origin.indexOf("b.com") >= 0) render(e.data) }`, models.OriginCheckBypassable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, evidence := AnalyzeOriginCheck(tt.src)
+			if got != tt.want {
+				t.Errorf("AnalyzeOriginCheck() verdict = %q, want %q (evidence %q)", got, tt.want, evidence)
+			}
+			if (len(evidence) > 0) != tt.wantEvidence {
+				t.Errorf("AnalyzeOriginCheck() evidence = %q, want evidence: %v", evidence, tt.wantEvidence)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// الگوهای استاتیک کد مستعد prototype pollution (merge/extend/deparam بدون گارد کلید)
+var protoPatterns = []struct {
+	ID          string
+	Description string
+	Severity    string
+	Re          *regexp.Regexp
+}{
+	{"deparam", "query-string parser expanding key[sub] into nested objects", models.SeverityMedium,
+		regexp.MustCompile(`\.split\(\s*["']\]\[["']\s*\)`)},
+	{"jquery-deep-extend", "deep $.extend(true, ...) (vulnerable before jQuery 3.4.0)", models.SeverityLow,
+		regexp.MustCompile(`\.extend\s*\(\s*(?:true|!0)\s*,`)},
+	{"lodash-deep", "lodash deep merge / path setter (vulnerable in old lodash versions)", models.SeverityLow,
+		regexp.MustCompile(`\b_\.(?:merge|mergeWith|defaultsDeep|set|setWith|zipObjectDeep)\s*\(`)},
+	{"recursive-merge", "for..in copy that recurses into merge/extend", models.SeverityMedium,
+		regexp.MustCompile(`for\s*\(\s*(?:var|let|const)?\s*[\w$]+\s+in\s+[\w$.]+\s*\)[^{};]{0,40}\{?[^{}]{0,300}?\b[\w$]*(?:[mM]erge|[eE]xtend|[dD]eepAssign|[aA]ssignDeep|[mM]ixin)\s*\(`)},
+	{"path-assign", "dotted path split and assigned segment by segment", models.SeverityLow,
+		regexp.MustCompile(`\.split\(\s*["']\.["']\s*\)[\s\S]{0,200}?\[\s*[\w$.]+(?:\[\s*[\w$]+\s*\])?\s*\]\s*=[^=]`)},
+}
+
+// گارد رایج نزدیک الگو (کلیدهای خطرناک رد می‌شوند)؛ فقط severity را پایین می‌آورد
+var protoGuardRe = regexp.MustCompile(`__proto__|["']constructor["']|["']prototype["']|Object\.create\(\s*null\s*\)`)
+
+const protoMaxPerPattern = 5
+
+// scanProtoPollutionPatterns: الگوهای استاتیک در اسکریپت‌های CollectScripts
+func scanProtoPollutionPatterns(scripts map[string]string, pageURL, siteID string) []models.FindingDoc {
+	var out []models.FindingDoc
+	for key, code := range scripts {
+		srcURL, _ := normalizeSourceURL(pageURL, key)
+		for _, p := range protoPatterns {
+			locs := p.Re.FindAllStringIndex(code, protoMaxPerPattern)
+			for _, loc := range locs {
+				line, col := lineCol(code, loc[0])
+				around := code[max(0, loc[0]-400):min(len(code), loc[1]+400)]
+				guarded := protoGuardRe.MatchString(around)
+				severity := p.Severity
+				if guarded {
+					severity = models.SeverityInfo
+				}
+				sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x1f%s\x1f%s\x1f%s\x1f%s\x1f%d:%d",
+					siteID, models.FindingProtoPollutionPattern, pageURL, srcURL, p.ID, line, col)))
+				out = append(out, models.FindingDoc{
+					Sig:      hex.EncodeToString(sum[:]),
+					SiteID:   siteID,
+					PageURL:  pageURL,
+					Type:     models.FindingProtoPollutionPattern,
+					Severity: severity,
+					Title:    "possible prototype pollution: " + p.Description,
+					URL:      srcURL,
+					Details: map[string]any{
+						"pattern":    p.ID,
+						"line":       line,
+						"col":        col,
+						"snippet":    snippetAround(code, loc[0]),
+						"guarded":    guarded,
+						"cwe":        "CWE-1321",
+						"confidence": models.ConfidenceStatic,
+					},
+				})
+			}
+		}
+	}
+	return out
+}
+
+// --- تأیید runtime ---
+
+// protoVariants: شکل‌های مختلف کلید که parserها به Object.prototype می‌رسانند
+var protoVariants = []struct {
+	ID     string
+	Format string // %[1]s = property، %[2]s = مقدار
+}{
+	{"proto-bracket", "__proto__[%[1]s]=%[2]s"},
+	{"proto-dot", "__proto__.%[1]s=%[2]s"},
+	{"constructor-bracket", "constructor[prototype][%[1]s]=%[2]s"},
+	{"constructor-dot", "constructor.prototype.%[1]s=%[2]s"},
+}
+
+// gadgetهای شناخته‌شدهٔ کتابخانه‌ها (BlackFan/client-side-prototype-pollution)؛ فقط راهنما
+var protoGadgets = []struct {
+	Library    string
+	Detect     string // عبارت JS؛ نسخه یا true
+	Properties []string
+	Note       string
+}{
+	{"jQuery", `window.jQuery && jQuery.fn && jQuery.fn.jquery`, []string{"url", "dataType"}, "$.get / $.getScript read options from the prototype"},
+	{"lodash", `window._ && _.template && _.VERSION`, []string{"sourceURL"}, "_.template compiles sourceURL into the generated function"},
+	{"Google reCAPTCHA", `!!window.grecaptcha`, []string{"srcdoc"}, "widget iframe picks up srcdoc"},
+	{"Vue.js", `window.Vue && Vue.version`, []string{"template", "v-if"}, "component options are read from the prototype"},
+	{"DOMPurify", `window.DOMPurify && (DOMPurify.version || true)`, []string{"ALLOWED_ATTR"}, "polluted config allows event handler attributes"},
+	{"Google Closure", `!!window.goog`, []string{"CLOSURE_BASE_PATH"}, "script loader path"},
+	{"Adobe DTM", `!!window._satellite`, []string{"src"}, "script injection via src"},
+}
+
+type protoGadgetHint struct {
+	Library    string   `json:"library" bson:"library"`
+	Version    string   `json:"version,omitempty" bson:"version,omitempty"`
+	Properties []string `json:"properties" bson:"properties"`
+	Note       string   `json:"note" bson:"note"`
+}
+
+const protoSettleDelay = 3 * time.Second
+
+// CheckPrototypePollution: با payloadهای __proto__/constructor.prototype در query و hash ناوبری می‌کند
+// و با Runtime.evaluate بررسی می‌کند Object.prototype marker گرفته یا نه؛ هر آلودگی تأییدشده یک finding.
+// مثل verify XSS فقط روی hostهای لیست مجاز سایت و با fail شدن درخواست به hostهای دیگر
+func CheckPrototypePollution(ctx context.Context, siteID, urlNorm, rawURL string) ([]models.FindingDoc, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := activeProbeAllowHosts(ctx, siteID, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// در اسکن پروکسی و پروفایل از job/واچ روی ctx هستند؛ از API مستقیم فقط تنظیم پروکسی سایت اعمال می‌شود
+	if ctx, err = resolveScanContext(ctx, models.ScanRequest{URL: rawURL}); err != nil {
+		return nil, err
+	}
+
+	var out []models.FindingDoc
+	for _, vector := range []string{"query", "hash"} {
+		// همهٔ شکل‌ها در یک ناوبری، هر کدام با property جدا تا معلوم شود کدام کار کرد
+		props := make([]string, len(protoVariants))
+		parts := make([]string, len(protoVariants))
+		for i, v := range protoVariants {
+			props[i] = randomMarker("scpp")
+			parts[i] = fmt.Sprintf(v.Format, props[i], props[i])
+		}
+		polluted, _, err := protoProbe(ctx, protoURL(u, vector, parts), props, false, allow)
+		if err != nil {
+			return out, err
+		}
+		for i, v := range protoVariants {
+			if !polluted[props[i]] {
+				continue
+			}
+			// تکرار با فقط همین payload: URL قابل بازتولید + gadgetهای صفحه
+			prop := randomMarker("scpp")
+			payload := fmt.Sprintf(v.Format, prop, prop)
+			reproURL := protoURL(u, vector, []string{payload})
+			again, gadgets, err := protoProbe(ctx, reproURL, []string{prop}, true, allow)
+			reproduced := err == nil && again[prop]
+			if !reproduced {
+				reproURL = protoURL(u, vector, parts)
+				payload = parts[i]
+				prop = props[i]
+			}
+			out = append(out, protoPollutionFinding(siteID, urlNorm, vector, v.ID, prop, payload, reproURL, reproduced, gadgets))
+		}
+	}
+	return out, nil
+}
+
+// protoURL: payloadها به query موجود اضافه می‌شوند (بدون encode کروشه‌ها) یا جای hash را می‌گیرند
+func protoURL(u *url.URL, vector string, parts []string) string {
+	c := *u
+	joined := strings.Join(parts, "&")
+	if vector == "hash" {
+		c.Fragment, c.RawFragment = "", ""
+		return c.String() + "#" + joined
+	}
+	if c.RawQuery != "" {
+		c.RawQuery += "&" + joined
+	} else {
+		c.RawQuery = joined
+	}
+	return c.String()
+}
+
+// protoProbe: یک تب تازه؛ propertyهایی که روی {} خوانده می‌شوند (یعنی Object.prototype آلوده شده)
+func protoProbe(ctx context.Context, target string, props []string, withGadgets bool, allow []string) (map[string]bool, []protoGadgetHint, error) {
+	bctx, cancel := newBrowserCtx(ctx, scanProxyFrom(ctx))
+	defer cancel()
+	tctx, cancelT := context.WithTimeout(bctx, 30*time.Second)
+	defer cancelT()
+
+	propsJSON, _ := json.Marshal(props)
+	var hit []string
+	if err := chromedp.Run(tctx, scanTabPrep(tctx, target, allow)...); err != nil {
+		return nil, nil, err
+	}
+	if err := chromedp.Run(tctx,
+		chromedp.Navigate(target),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(protoSettleDelay),
+		chromedp.Evaluate(`(function(ps){ var o = {}; return ps.filter(function(p){ try { return o[p] !== undefined; } catch(_) { return false; } }); })(`+string(propsJSON)+`)`, &hit),
+	); err != nil {
+		return nil, nil, err
+	}
+	out := make(map[string]bool, len(hit))
+	for _, p := range hit {
+		out[p] = true
+	}
+	if !withGadgets || len(hit) == 0 {
+		return out, nil, nil
+	}
+
+	var gadgets []protoGadgetHint
+	for _, g := range protoGadgets {
+		var v any
+		if err := chromedp.Run(tctx, chromedp.Evaluate(`(function(){ try { return `+g.Detect+`; } catch(_) { return null; } })()`, &v)); err != nil {
+			continue
+		}
+		switch x := v.(type) {
+		case string:
+			gadgets = append(gadgets, protoGadgetHint{Library: g.Library, Version: x, Properties: g.Properties, Note: g.Note})
+		case bool:
+			if x {
+				gadgets = append(gadgets, protoGadgetHint{Library: g.Library, Properties: g.Properties, Note: g.Note})
+			}
+		}
+	}
+	return out, gadgets, nil
+}
+
+func protoPollutionFinding(siteID, urlNorm, vector, variant, prop, payload, reproURL string, reproduced bool, gadgets []protoGadgetHint) models.FindingDoc {
+	severity := models.SeverityMedium
+	if len(gadgets) > 0 {
+		severity = models.SeverityHigh
+	}
+	libs := make([]string, 0, len(gadgets))
+	for _, g := range gadgets {
+		libs = append(libs, g.Library)
+	}
+	sort.Strings(libs)
+	title := fmt.Sprintf("prototype pollution via %s (%s)", vector, variant)
+	if len(libs) > 0 {
+		title += "; gadget candidates: " + strings.Join(libs, ", ")
+	}
+	// sig بدون property تصادفی تا اسکن بعدی همان finding را به‌روز کند
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + models.FindingProtoPollution + "\x1f" + urlNorm + "\x1f" + vector + "\x1f" + variant))
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   siteID,
+		PageURL:  urlNorm,
+		Type:     models.FindingProtoPollution,
+		Severity: severity,
+		Title:    title,
+		URL:      reproURL,
+		Details: map[string]any{
+			"vector":     vector,
+			"variant":    variant,
+			"property":   prop,
+			"payload":    payload,
+			"repro_url":  reproURL,
+			"reproduced": reproduced, // false: فقط با URL ترکیبی (همهٔ شکل‌ها) دیده شد
+			"gadgets":    gadgets,
+			"cwe":        "CWE-1321",
+			"confidence": models.ConfidenceRuntime,
+		},
+	}
+}
+
+// CheckAndPersistPrototypePollution: مرحلهٔ prototype_pollution در اسکن/crawl؛ تعداد آلودگی‌های تأییدشده
+func CheckAndPersistPrototypePollution(ctx context.Context, rawURL, siteID, urlNorm string) int {
+	findings, err := CheckPrototypePollution(ctx, siteID, urlNorm, rawURL)
+	if err != nil && !errors.Is(err, ErrXSSVerifyNoAllowList) && !errors.Is(err, ErrXSSVerifyOutOfScope) {
+		log.Printf("[prototype pollution] url=%s err=%v", rawURL, err)
+	}
+	if err := PersistFindings(ctx, findings); err != nil {
+		log.Printf("[prototype pollution] persist error: %v", err)
+		return 0
+	}
+	return len(findings)
+}
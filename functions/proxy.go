@@ -0,0 +1,184 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+type scanProxyKey struct{}
+
+// ValidateProxy: فقط http/https/socks5 با host:port
+func ValidateProxy(cfg *models.ProxyConfig) error {
+	u, err := url.Parse(strings.TrimSpace(cfg.URL))
+	if err != nil || u.Host == "" || u.Port() == "" {
+		return fmt.Errorf("invalid proxy url %q (expected scheme://host:port)", cfg.URL)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "socks5":
+		// Chromium برای SOCKS احراز هویت پشتیبانی نمی‌کند
+		if cfg.Username != "" || u.User != nil {
+			return errors.New("socks5 proxy with credentials is not supported by Chromium")
+		}
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (http|https|socks5)", u.Scheme)
+	}
+	// اعتبارنامه داخل URL را به فیلدهای جدا منتقل می‌کنیم تا همه‌جا یک‌جور مصرف شود
+	if u.User != nil {
+		if cfg.Username == "" {
+			cfg.Username = u.User.Username()
+		}
+		if p, ok := u.User.Password(); ok && cfg.Password == "" {
+			cfg.Password = p
+		}
+		u.User = nil
+	}
+	cfg.URL = u.Scheme + "://" + u.Host
+	return nil
+}
+
+// ResolveProxy: پروکسی خود درخواست، وگرنه تنظیم فعال سایت، وگرنه nil
+func ResolveProxy(ctx context.Context, req models.ScanRequest) (*models.ProxyConfig, error) {
+	if req.Proxy != nil && strings.TrimSpace(req.Proxy.URL) != "" {
+		cfg := *req.Proxy
+		if err := ValidateProxy(&cfg); err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	}
+	siteID, _, err := PageKeys(req.URL)
+	if err != nil {
+		return nil, nil
+	}
+	s, err := models.GetProxySetting(ctx, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("proxy setting: %w", err)
+	}
+	if s == nil || !s.Enabled || s.URL == "" {
+		return nil, nil
+	}
+	cfg := s.ProxyConfig
+	if err := ValidateProxy(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// RedactProxy: نمایش پروکسی برای ذخیره روی صفحه (بدون رمز)
+func RedactProxy(cfg *models.ProxyConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return ""
+	}
+	if cfg.Username != "" {
+		u.User = url.User(cfg.Username)
+	}
+	return u.String()
+}
+
+func withScanProxy(ctx context.Context, cfg *models.ProxyConfig) context.Context {
+	if cfg == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, scanProxyKey{}, cfg)
+}
+
+func scanProxyFrom(ctx context.Context) *models.ProxyConfig {
+	cfg, _ := ctx.Value(scanProxyKey{}).(*models.ProxyConfig)
+	return cfg
+}
+
+// ScanHTTPClient: کلاینت HTTP سمت Go که از پروکسی اسکن جاری (در ctx) عبور می‌کند
+func ScanHTTPClient(ctx context.Context, timeout time.Duration) *http.Client {
+	cfg := scanProxyFrom(ctx)
+	if cfg == nil {
+		return &http.Client{Timeout: timeout}
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if u, err := url.Parse(cfg.URL); err == nil {
+		if cfg.Username != "" {
+			u.User = url.UserPassword(cfg.Username, cfg.Password)
+		}
+		bypass := cfg.Bypass
+		tr.Proxy = func(r *http.Request) (*url.URL, error) {
+			if proxyBypassed(r.URL.Hostname(), bypass) {
+				return nil, nil
+			}
+			return u, nil
+		}
+	}
+	if cfg.IgnoreTLS {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Timeout: timeout, Transport: tr}
+}
+
+// proxyBypassed: قواعد ساده مثل Chromium ("host"، "*.suffix"، ".suffix")
+func proxyBypassed(host string, rules []string) bool {
+	host = strings.ToLower(host)
+	for _, r := range rules {
+		r = strings.ToLower(strings.TrimSpace(r))
+		switch {
+		case r == "":
+		case r == "<local>":
+			if !strings.Contains(host, ".") {
+				return true
+			}
+		case strings.HasPrefix(r, "*."):
+			if strings.HasSuffix(host, r[1:]) || host == r[2:] {
+				return true
+			}
+		case strings.HasPrefix(r, "."):
+			if strings.HasSuffix(host, r) {
+				return true
+			}
+		case host == r:
+			return true
+		}
+	}
+	return false
+}
+
+// proxyContextOptions: پروکسی روی BrowserContext تب (برای مرورگرهای مشترک پول)
+func proxyContextOptions(cfg *models.ProxyConfig) []chromedp.CreateBrowserContextOption {
+	if cfg == nil {
+		return nil
+	}
+	return []chromedp.CreateBrowserContextOption{
+		func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			p = p.WithProxyServer(cfg.URL)
+			if len(cfg.Bypass) > 0 {
+				p = p.WithProxyBypassList(strings.Join(cfg.Bypass, ","))
+			}
+			return p
+		},
+	}
+}
+
+// proxyAllocOptions: همان تنظیم برای مرورگر اختصاصی (وقتی پول در دسترس نیست)
+func proxyAllocOptions(cfg *models.ProxyConfig) []chromedp.ExecAllocatorOption {
+	if cfg == nil {
+		return nil
+	}
+	opts := []chromedp.ExecAllocatorOption{chromedp.ProxyServer(cfg.URL)}
+	if len(cfg.Bypass) > 0 {
+		opts = append(opts, chromedp.Flag("proxy-bypass-list", strings.Join(cfg.Bypass, ",")))
+	}
+	if cfg.IgnoreTLS {
+		opts = append(opts, chromedp.IgnoreCertErrors)
+	}
+	return opts
+}
@@ -6,8 +6,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,6 +20,19 @@ import (
 )
 
 func SaveScanResults(ctx context.Context, rawURL string, resources, endpoints, scriptURLs []string) error {
+	return SaveScanResponse(ctx, &models.ScanResponse{
+		URL:         rawURL,
+		Resources:   resources,
+		UniquePaths: endpoints,
+		AllScripts:  scriptURLs,
+	})
+}
+
+// SaveScanResponse: ذخیرهٔ کامل خروجی RunScan (صفحه، اندپوینت‌ها و متادیتای اسکن مثل crawl_id)
+func SaveScanResponse(ctx context.Context, resp *models.ScanResponse) error {
+	rawURL := resp.URL
+	resources, endpoints, scriptURLs := resp.Resources, resp.UniquePaths, resp.AllScripts
+
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return err
@@ -41,6 +56,10 @@ func SaveScanResults(ctx context.Context, rawURL string, resources, endpoints, s
 	}
 	siteID := base
 	now := time.Now()
+	origin := resp.Origin
+	if origin == "" {
+		origin = models.OriginScan
+	}
 
 	// 0) گروه‌بندی داخلی/خارجی
 	inEP, extEP := splitInternalExternal(endpoints, host)
@@ -58,7 +77,7 @@ func SaveScanResults(ctx context.Context, rawURL string, resources, endpoints, s
 				"last_scan_at": now,
 				"display_url":  scheme + "://" + base,
 			},
-			"$addToSet":    bson.M{"hosts": host},
+			"$addToSet":    bson.M{"hosts": host, "origins": origin},
 			"$setOnInsert": bson.M{"created_at": now},
 		},
 		mopts.Update().SetUpsert(true),
@@ -70,24 +89,32 @@ func SaveScanResults(ctx context.Context, rawURL string, resources, endpoints, s
 	groupsEP := groupPaths(inEP, host)
 	groupsRES := groupPaths(inRES, host)
 
+	pageSet := bson.M{
+		"site_id":         siteID,
+		"scheme":          scheme,
+		"host":            host,
+		"path":            p,
+		"url":             rawURL,
+		"url_norm":        urlNorm,
+		"resources":       inRES,
+		"script_urls":     inSCR,
+		"endpoints":       inEP,
+		"groups":          groupsEP,
+		"resource_groups": groupsRES,
+		"externals":       externals,
+		"scanned_at":      now,
+		"origin":          origin,
+		"import_id":       resp.ImportID,
+		"proxy":           resp.Proxy,
+	}
+	if resp.CrawlID != "" {
+		pageSet["crawl_id"] = resp.CrawlID
+	}
+
 	_, err = models.PagesColl().UpdateOne(ctx,
 		bson.M{"url_norm": urlNorm},
 		bson.M{
-			"$set": bson.M{
-				"site_id":         siteID,
-				"scheme":          scheme,
-				"host":            host,
-				"path":            p,
-				"url":             rawURL,
-				"url_norm":        urlNorm,
-				"resources":       inRES,
-				"script_urls":     inSCR,
-				"endpoints":       inEP,
-				"groups":          groupsEP,
-				"resource_groups": groupsRES,
-				"externals":       externals,
-				"scanned_at":      now,
-			},
+			"$set":         pageSet,
 			"$setOnInsert": bson.M{"created_at": now},
 		},
 		mopts.Update().SetUpsert(true),
@@ -96,58 +123,138 @@ func SaveScanResults(ctx context.Context, rawURL string, resources, endpoints, s
 		return err
 	}
 
-	for _, ep := range inEP {
-		cat := categorize(host, ep)
-		filter := bson.M{"site_id": siteID, "endpoint": ep}
-		update := mongo.Pipeline{
-			{{"$set", bson.M{
-				"first_seen": bson.M{"$ifNull": bson.A{"$first_seen", now}},
-				"last_seen":  now,
-				"seen_count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$seen_count", 0}}, 1}},
-				"hosts":      bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$hosts", bson.A{}}}, bson.A{host}}},
-				"source_urls": bson.M{
-					"$slice": bson.A{
-						bson.M{
-							"$setUnion": bson.A{
-								bson.A{urlNorm},
-								bson.M{"$ifNull": bson.A{"$source_urls", bson.A{}}},
-							},
-						},
-						5,
-					},
-				},
-				"category": cat,
-			}}},
+	if err := saveNetworkLog(ctx, siteID, urlNorm, resp); err != nil {
+		return err
+	}
+	if resp.SnapshotID, err = savePageSnapshot(ctx, resp, siteID, urlNorm, inEP, externals, now); err != nil {
+		return err
+	}
+
+	for i := range resp.Findings {
+		resp.Findings[i].SiteID = siteID
+		resp.Findings[i].PageURL = urlNorm
+		if resp.Findings[i].Origin == "" {
+			resp.Findings[i].Origin = origin
 		}
-		if _, err := models.EndpointsColl().UpdateOne(ctx, filter, update, mopts.Update().SetUpsert(true)); err != nil {
-			return err
+	}
+	if err := PersistFindings(ctx, resp.Findings); err != nil {
+		return err
+	}
+	if err := persistGraphQL(ctx, siteID, resp.GraphQL); err != nil {
+		return err
+	}
+	for i := range resp.Secrets {
+		if resp.Secrets[i].Origin == "" {
+			resp.Secrets[i].Origin = origin
 		}
 	}
+	if err := PersistSecrets(ctx, resp.Secrets); err != nil {
+		return err
+	}
 
-	sinks, err := ScanSinks(ctx, urlNorm, siteID)
-	if err == nil && len(sinks) > 0 {
+	if err := saveScanEndpoints(ctx, siteID, host, urlNorm, origin, inEP, resp, now); err != nil {
+		return err
+	}
 
-		for _, s := range sinks {
-			_, _ = models.SinksColl().UpdateOne(ctx,
-				bson.M{
-					"site_id": s.SiteID, "page_url": s.PageURL,
-					"source_url": s.SourceURL, "kind": s.Kind,
-					"line": s.Line, "col": s.Col, "snippet": s.Snippet,
-				},
-				bson.M{
-					"$setOnInsert": s,
-					"$set":         bson.M{"detected_at": time.Now()},
-				},
-				mopts.Update().SetUpsert(true),
-			)
-		}
+	// بدنهٔ اسکریپت‌ها آخر و با timeout خودش (مستقل از deadline ذخیرهٔ صفحه)؛ خطایش ذخیره را خراب نمی‌کند
+	scriptCtx, cancelScripts := context.WithTimeout(context.WithoutCancel(ctx), scriptStoreTimeout())
+	defer cancelScripts()
+	if err := saveScriptBodies(scriptCtx, siteID, urlNorm, resp.ScriptBodies, now); err != nil {
+		log.Printf("[scripts] store error url=%s err=%v", urlNorm, err)
 	}
 
 	return nil
 }
 
+// saveScanEndpoints: اندپوینت‌های استخراج‌شده و مشاهده‌شده در شبکه با هم؛ هر endpoint یک upsert
+// در هر اسکن دارد تا seen_count تعداد اسکن‌هایی باشد که آن را دیده‌اند
+func saveScanEndpoints(ctx context.Context, siteID, host, urlNorm, origin string, inEP []string, resp *models.ScanResponse, now time.Time) error {
+	extracted := make(map[string]bool, len(inEP))
+	for _, ep := range inEP {
+		extracted[ep] = true
+	}
+	observed := observedEndpoints(resp.Requests, host)
+	keys := append([]string{}, inEP...)
+	for ep := range observed {
+		if !extracted[ep] {
+			keys = append(keys, ep)
+		}
+	}
+	sort.Strings(keys)
+
+	writes := make([]mongo.WriteModel, 0, len(keys))
+	for _, ep := range keys {
+		hosts, extractors := []string{}, []string{}
+		set := bson.M{
+			"first_seen": bson.M{"$ifNull": bson.A{"$first_seen", now}},
+			"last_seen":  now,
+			"seen_count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$seen_count", 0}}, 1}},
+			"origins":    bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$origins", bson.A{}}}, bson.A{origin}}},
+			"source_urls": bson.M{
+				"$slice": bson.A{
+					bson.M{
+						"$setUnion": bson.A{
+							bson.A{urlNorm},
+							bson.M{"$ifNull": bson.A{"$source_urls", bson.A{}}},
+						},
+					},
+					5,
+				},
+			},
+		}
+		if extracted[ep] {
+			// بدون hint یعنی از مسیر قدیمی (regex) آمده
+			hint := resp.EndpointHints[ep]
+			if hint == nil {
+				hint = &models.EndpointHint{Extractors: []string{ExtractorRegex}}
+			}
+			hosts = append(hosts, host)
+			extractors = append(extractors, hint.Extractors...)
+			set["category"] = categorize(host, ep)
+			if len(hint.Methods) > 0 {
+				set["inferred_methods"] = setUnionField("inferred_methods", hint.Methods)
+			}
+			if len(hint.Contexts) > 0 {
+				set["contexts"] = setUnionField("contexts", hint.Contexts)
+			}
+		}
+		if o := observed[ep]; o != nil {
+			hosts = append(hosts, o.hosts...)
+			extractors = append(extractors, ExtractorNetwork)
+			set["observed"] = true
+			if len(o.methods) > 0 {
+				set["methods"] = setUnionField("methods", o.methods)
+			}
+			if o.lastStatus > 0 {
+				set["last_status"] = o.lastStatus
+			}
+			if _, ok := set["category"]; !ok {
+				set["category"] = categorize(o.host, ep)
+			}
+		}
+		set["hosts"] = setUnionField("hosts", uniqueStrings(hosts))
+		set["extractors"] = setUnionField("extractors", uniqueStrings(extractors))
+		setTemplateFields(set, ep)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"site_id": siteID, "endpoint": ep}).
+			SetUpdate(mongo.Pipeline{{{Key: "$set", Value: set}}}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err := models.EndpointsColl().BulkWrite(ctx, writes, mopts.BulkWrite().SetOrdered(false))
+	return err
+}
+
 // --- helpers ---
 
+// setUnionField: عبارت pipeline برای اضافه کردن vals به آرایهٔ field (بدون تکرار)
+// $literal لازم است: مقداری که با "$" شروع شود (مثل ?$filter=) وگرنه مسیر فیلد حساب می‌شود
+func setUnionField(field string, vals []string) bson.M {
+	return bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}}, bson.M{"$literal": vals}}}
+}
+
 func splitInternalExternal(items []string, pageHost string) (internal []string, extern map[string][]string) {
 	extern = make(map[string][]string)
 	for _, it := range uniqueStrings(items) {
@@ -270,6 +377,9 @@ func categorize(host, pth string) string {
 	if ext != "" {
 		return "others"
 	}
+	if isGraphQLPath(base) {
+		return "graphql"
+	}
 	if strings.HasPrefix(host, "api.") || strings.Contains(s, "/api") {
 		return "api"
 	}
@@ -298,6 +408,7 @@ func PersistSinks(ctx context.Context, sinks []models.SinkDoc) (*mongo.BulkWrite
 		if len(s.Snippet) > 1000 {
 			s.Snippet = s.Snippet[:1000]
 		}
+		classifySink(&s)
 		sig := sinkSig(s.SiteID, s.PageURL, s.SourceURL, s.Kind, s.Line, s.Col, s.Snippet)
 		sn := s
 		// می‌تونی sig رو هم تو سند نگه داری
@@ -313,22 +424,42 @@ func PersistSinks(ctx context.Context, sinks []models.SinkDoc) (*mongo.BulkWrite
 
 	for sig, s := range uniq {
 		filter := bson.M{"sig": sig}
+		origin := s.Origin
+		if origin == "" {
+			origin = models.OriginScan
+		}
+		onInsert := bson.M{
+			"sig":               sig,
+			"site_id":           s.SiteID,
+			"page_url":          s.PageURL,
+			"source_url":        s.SourceURL,
+			"kind":              s.Kind,
+			"line":              s.Line,
+			"col":               s.Col,
+			"snippet":           s.Snippet,
+			"first_detected_at": s.DetectedAt,
+			"import_id":         s.ImportID,
+			"bundle_url":        s.BundleURL,
+		}
+		set := bson.M{
+			"last_detected_at": now,
+			"source_type":      s.SourceType, // فقط اینجا
+			"origin":           origin,
+			// با تغییر قوانین به‌روز می‌شوند
+			"severity":      s.Severity,
+			"severity_rank": models.SeverityRank(s.Severity),
+			"cwe":           s.CWE,
+			"owasp":         s.OWASP,
+		}
+		// static/runtime با sig ثابت است؛ taint فقط ارتقا می‌دهد و با اسکن بعدی پایین نمی‌آید
+		if s.Confidence == models.ConfidenceTaint {
+			set["confidence"] = s.Confidence
+		} else {
+			onInsert["confidence"] = s.Confidence
+		}
 		update := bson.M{
-			"$setOnInsert": bson.M{
-				"sig":               sig,
-				"site_id":           s.SiteID,
-				"page_url":          s.PageURL,
-				"source_url":        s.SourceURL,
-				"kind":              s.Kind,
-				"line":              s.Line,
-				"col":               s.Col,
-				"snippet":           s.Snippet,
-				"first_detected_at": s.DetectedAt,
-			},
-			"$set": bson.M{
-				"last_detected_at": now,
-				"source_type":      s.SourceType, // فقط اینجا
-			},
+			"$setOnInsert": onInsert,
+			"$set":         set,
 			"$inc": bson.M{
 				"hits": 1, // فقط اینجا
 			},
@@ -342,3 +473,47 @@ func PersistSinks(ctx context.Context, sinks []models.SinkDoc) (*mongo.BulkWrite
 	opts := mopts.BulkWrite().SetOrdered(false)
 	return models.SinksColl().BulkWrite(ctx, modelsBW, opts)
 }
+
+// PersistFindings: upsert بر اساس sig؛ first_seen فقط بار اول، hits هر بار
+func PersistFindings(ctx context.Context, findings []models.FindingDoc) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	now := time.Now()
+	bw := make([]mongo.WriteModel, 0, len(findings))
+	for _, f := range findings {
+		if f.Sig == "" || f.SiteID == "" {
+			continue
+		}
+		origin := f.Origin
+		if origin == "" {
+			origin = models.OriginScan
+		}
+		bw = append(bw, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sig": f.Sig}).
+			SetUpdate(bson.M{
+				"$setOnInsert": bson.M{
+					"sig":        f.Sig,
+					"site_id":    f.SiteID,
+					"type":       f.Type,
+					"first_seen": now,
+				},
+				"$set": bson.M{
+					"page_url":  f.PageURL,
+					"severity":  f.Severity,
+					"title":     f.Title,
+					"url":       f.URL,
+					"details":   f.Details,
+					"origin":    origin,
+					"last_seen": now,
+				},
+				"$inc": bson.M{"hits": 1},
+			}).
+			SetUpsert(true))
+	}
+	if len(bw) == 0 {
+		return nil
+	}
+	_, err := models.FindingsColl().BulkWrite(ctx, bw, mopts.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -3,6 +3,8 @@ package functions
 import (
 	"SiteChecker/models"
 	"context"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/chromedp/chromedp"
@@ -17,6 +19,7 @@ type sinkFinding struct {
 	Snippet    string `json:"snippet"`
 }
 
+// ScanSinks: اسکن regex درون صفحه (DOM نهایی + متن اسکریپت‌ها) با همان قوانین SinkRules؛ ctx باید تب chromedp باشد
 func ScanSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, error) {
 	js := `
 (async function(){
@@ -32,52 +35,29 @@ func ScanSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, e
     const end   = Math.min(text.length, idx + 120);
     return text.slice(start, end);
   }
-  const patterns = [
-    {kind:"innerHTML",               re:/\.innerHTML\s*=/g},
-    {kind:"dangerouslySetInnerHTML", re:/dangerouslySetInnerHTML\s*:/g},
-    {kind:"eval",                    re:/\beval\s*\(/g},
-    {kind:"newFunction",             re:/\bnew\s+Function\s*\(/g},
-    {kind:"setTimeoutStr",           re:/\bsetTimeout\s*\(\s*(['"]).*?\1\s*(?:,|\))/g},
-    {kind:"setIntervalStr",          re:/\bsetInterval\s*\(\s*(['"]).*?\1\s*(?:,|\))/g},
-    {kind:"documentWrite",           re:/\bdocument\.write\s*\(/g},
-    {kind:"prompt",                  re:/\bprompt\s*\(/g},
-    {kind:"alert",                   re:/\balert\s*\(/g},
-    {kind:"confirm",                 re:/\bconfirm\s*\(/g},
-    {kind:"fetch",                   re:/\bfetch\s*\(/g},
-    {kind:"XMLHttpRequest",          re:/\bXMLHttpRequest\b/g},
-    {kind:"syncXHR",                 re:/\.open\s*\([^,]+,[^,]+,\s*false\s*\)/g},
-    {kind:"localStorage",            re:/\blocalStorage\b/g},
-    {kind:"sessionStorage",          re:/\bsessionStorage\b/g},
-    {kind:"JSON.parse",              re:/\bJSON\.parse\s*\(/g},
-    {kind:"JSON.stringify",          re:/\bJSON\.stringify\s*\(/g},
-    {kind:"postMessageSend",         re:/\bpostMessage\s*\(/g},
-    {kind:"postMessageRecv",         re:/addEventListener\s*\(\s*['"]message['"]/g},
-    {kind:"onmessageHandler",        re:/\bonmessage\s*=/g},
-    {kind:"inlineEventHandler",      re:/on[a-z]+\s*=\s*(['"]).*?\1/ig},
-    {kind:"directDOM",               re:/document\.(getElementById|getElementsByClassName|querySelector(All)?)\s*\(/g},
-    {kind:"heavyLoop",               re:/for\s*\(\s*var\s+i\s*=\s*0\s*;\s*i\s*<\s*\d{6,}\s*;|while\s*\(\s*true\s*\)/g}
-  ];
+  const patterns = (__RULES__ || []).map(p => ({kind:p.kind, applies:p.applies||[], re:new RegExp(p.src, 'g'+(p.flags||''))}));
+  const applies = (p, scope) => p.applies.indexOf(scope) !== -1;
 
   const results = [];
 
   // HTML
   const html = document.documentElement.outerHTML;
   for (const p of patterns) {
+    if (!applies(p, "html")) continue;
     for (const mm of html.matchAll(p.re)) {
       const idx = mm.index || 0;
       const lc = lineColFromIndex(html, idx);
       results.push({
-        kind:p.kind, source_type:"html", source_url:location.href,
+        kind:p.kind, source_type:"html", source_url:"",
         line:lc.line, col:lc.col, snippet:snippet(html, idx)
       });
     }
   }
 
-  // Scripts  ← این قسمت عوض شد
-  const list = Array.from(document.scripts).map((s, i) => {
-    const abs   = s.src ? new URL(s.src, location.href).href : null;
-    const label = abs || (location.href + '#inline-' + (i+1));
-    return { src: abs, label, text: s.src ? null : (s.text || "") };
+  // Scripts (inline → source_url خالی، در Go به page#inline نگاشت می‌شود)
+  const list = Array.from(document.scripts).map(s => {
+    const abs = s.src ? new URL(s.src, location.href).href : null;
+    return { src: abs, text: s.src ? null : (s.text || "") };
   });
 
   for (const it of list) {
@@ -91,15 +71,17 @@ func ScanSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, e
 
   for (const it of list) {
     if (!it.text) continue;
+    const scope = it.src ? "script" : "inline";
     for (const p of patterns) {
+      if (!applies(p, scope)) continue;
       const re = new RegExp(p.re.source, p.re.flags);
       for (const mm of it.text.matchAll(re)) {
         const idx = mm.index || 0;
         const lc = lineColFromIndex(it.text, idx);
         results.push({
           kind:p.kind,
-          source_type: it.src ? "script" : "inline",
-          source_url: it.label,   // ✅ همیشه label داریم
+          source_type: scope,
+          source_url: it.src || "",
           line:lc.line, col:lc.col, snippet:snippet(it.text, idx)
         });
       }
@@ -108,6 +90,9 @@ func ScanSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, e
   return results;
 })()
 `
+	patterns, _ := json.Marshal(SinkRules().jsPatterns())
+	js = strings.Replace(js, "__RULES__", string(patterns), 1)
+
 	var found []sinkFinding
 	if err := chromedp.Run(ctx, chromedp.EvaluateAsDevTools(js, &found)); err != nil {
 		return nil, err
@@ -116,16 +101,10 @@ func ScanSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, e
 	out := make([]models.SinkDoc, 0, len(found))
 	now := time.Now()
 	for _, f := range found {
-		srcURL, srcType := normalizeSourceURL(pageURL, f.SourceURL)
-		if f.SourceType == "" {
-			f.SourceType = srcType
-		}
-		if srcURL != "" {
-			f.SourceURL = srcURL
-		}
-		f.SourceURL = srcURL
-		if f.SourceType == "" {
-			f.SourceType = srcType
+		if f.SourceType == "html" {
+			f.SourceURL = pageURL
+		} else {
+			f.SourceURL, f.SourceType = normalizeSourceURL(pageURL, f.SourceURL)
 		}
 		out = append(out, models.SinkDoc{
 			SiteID:     siteID,
@@ -0,0 +1,155 @@
+package functions
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// انواع رویدادهای پیشرفت اسکن
+const (
+	EvStageStart  = "stage_start"
+	EvStageEnd    = "stage_end"
+	EvScriptError = "script_error"
+	EvState       = "state"
+	EvDone        = "done"
+)
+
+type ScanEvent struct {
+	Seq        int       `json:"seq"`
+	JobID      string    `json:"job_id"`
+	Type       string    `json:"type"`
+	Stage      string    `json:"stage,omitempty"`
+	State      string    `json:"state,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// سقف تاریخچهٔ نگه‌داشته‌شده برای هر job (برای subscriberهایی که دیر وصل می‌شوند)
+const scanEventsHistory = 1000
+
+type scanStream struct {
+	seq    int // شمارندهٔ مستقل از تاریخچه؛ بعد از پر شدن تاریخچه هم بالا می‌رود
+	events []ScanEvent
+	subs   map[chan ScanEvent]struct{}
+	closed bool
+}
+
+var scanStreams = struct {
+	sync.Mutex
+	m map[string]*scanStream
+}{m: map[string]*scanStream{}}
+
+type scanJobKey struct{}
+
+// withScanJob: شناسهٔ job را روی ctx می‌گذارد تا مراحل RunScan رویداد منتشر کنند
+func withScanJob(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, scanJobKey{}, jobID)
+}
+
+func scanJobFrom(ctx context.Context) string {
+	id, _ := ctx.Value(scanJobKey{}).(string)
+	return id
+}
+
+// openScanStream: stream را از لحظهٔ Enqueue می‌سازد تا subscriberها قبل از شروع اسکن هم وصل شوند
+func openScanStream(jobID string) {
+	scanStreams.Lock()
+	defer scanStreams.Unlock()
+	if scanStreams.m[jobID] == nil {
+		scanStreams.m[jobID] = &scanStream{subs: map[chan ScanEvent]struct{}{}}
+	}
+}
+
+// emitScanEvent: اگر ctx متعلق به یک job باشد رویداد را منتشر می‌کند
+func emitScanEvent(ctx context.Context, ev ScanEvent) {
+	jobID := scanJobFrom(ctx)
+	if jobID == "" {
+		return
+	}
+	ev.JobID = jobID
+	ev.At = time.Now()
+
+	scanStreams.Lock()
+	defer scanStreams.Unlock()
+	st := scanStreams.m[jobID]
+	if st == nil {
+		st = &scanStream{subs: map[chan ScanEvent]struct{}{}}
+		scanStreams.m[jobID] = st
+	}
+	if st.closed {
+		return
+	}
+	st.seq++
+	ev.Seq = st.seq
+	// تاریخچه پنجرهٔ آخرین رویدادهاست تا done همیشه به کلاینت دیررس برسد
+	if len(st.events) >= scanEventsHistory {
+		st.events = append(st.events[:0], st.events[1:]...)
+	}
+	st.events = append(st.events, ev)
+	for ch := range st.subs {
+		select {
+		case ch <- ev:
+		default: // subscriber کند؛ رویداد را از دست می‌دهد ولی اسکن بلاک نمی‌شود
+		}
+	}
+	if ev.Type == EvDone {
+		st.closed = true
+		for ch := range st.subs {
+			close(ch)
+		}
+		st.subs = nil
+		// تاریخچه را کمی نگه دار تا کلاینت‌های دیررس هم ببینند
+		time.AfterFunc(10*time.Minute, func() {
+			scanStreams.Lock()
+			delete(scanStreams.m, jobID)
+			scanStreams.Unlock()
+		})
+	}
+}
+
+// scanStage: رویداد شروع/پایان یک مرحله را همراه با مدت‌زمان و خطا منتشر می‌کند
+func scanStage(ctx context.Context, stage string, fn func() error) error {
+	return scanStageURL(ctx, stage, "", fn)
+}
+
+// scanStageURL: مثل scanStage ولی URL مرحله (مثلاً صفحهٔ crawl) هم در رویدادها می‌آید
+func scanStageURL(ctx context.Context, stage, u string, fn func() error) error {
+	emitScanEvent(ctx, ScanEvent{Type: EvStageStart, Stage: stage, URL: u})
+	start := time.Now()
+	err := fn()
+	ev := ScanEvent{Type: EvStageEnd, Stage: stage, URL: u, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	emitScanEvent(ctx, ev)
+	return err
+}
+
+// SubscribeScanEvents: تاریخچهٔ رویدادها + کانال رویدادهای بعدی.
+// اگر stream بسته شده باشد ch برابر nil است. ok=false یعنی این instance رویدادی از این job ندارد.
+func SubscribeScanEvents(jobID string) (history []ScanEvent, ch chan ScanEvent, unsubscribe func(), ok bool) {
+	scanStreams.Lock()
+	defer scanStreams.Unlock()
+	st := scanStreams.m[jobID]
+	if st == nil {
+		return nil, nil, func() {}, false
+	}
+	history = append([]ScanEvent(nil), st.events...)
+	if st.closed {
+		return history, nil, func() {}, true
+	}
+	ch = make(chan ScanEvent, 64)
+	st.subs[ch] = struct{}{}
+	return history, ch, func() {
+		scanStreams.Lock()
+		defer scanStreams.Unlock()
+		if _, ok := st.subs[ch]; ok {
+			delete(st.subs, ch)
+			close(ch)
+		}
+	}, true
+}
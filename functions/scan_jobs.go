@@ -0,0 +1,378 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrScanQueueFull وقتی برگردانده می‌شود که تعداد jobهای در صف از سقف بیشتر باشد
+var ErrScanQueueFull = errors.New("scan queue is full")
+
+// scanWake: بیدار کردن workerها بعد از Enqueue (بدون منتظر ماندن برای poll)
+var scanWake = make(chan struct{}, 1)
+
+// StartScanWorkers: jobهای نیمه‌کاره قبل از ری‌استارت را به صف برمی‌گرداند و n worker راه می‌اندازد
+func StartScanWorkers(ctx context.Context, n int) {
+	if n <= 0 {
+		n = envInt("SCAN_WORKERS", 2)
+	}
+	failExhaustedScanJobs(ctx)
+	res, err := models.ScanJobsColl().UpdateMany(ctx,
+		bson.M{"state": bson.M{"$in": bson.A{models.JobRunning, models.JobSaving}}},
+		bson.M{"$set": bson.M{"state": models.JobQueued, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("[jobs] requeue error: %v", err)
+	} else if res.ModifiedCount > 0 {
+		log.Printf("[jobs] requeued %d interrupted jobs", res.ModifiedCount)
+	}
+
+	for i := 0; i < n; i++ {
+		go scanWorker(ctx)
+	}
+	wakeScanWorkers()
+}
+
+// failExhaustedScanJobs: jobی که SCAN_JOB_MAX_ATTEMPTS بار شروع شده و باز نیمه‌کاره مانده (مثلاً Chrome را
+// کرش یا هنگ می‌کند) دوباره صف نمی‌شود و failed می‌شود
+func failExhaustedScanJobs(ctx context.Context) {
+	maxAttempts := envInt("SCAN_JOB_MAX_ATTEMPTS", 3)
+	filter := bson.M{
+		"state":    bson.M{"$in": bson.A{models.JobRunning, models.JobSaving}},
+		"attempts": bson.M{"$gte": maxAttempts},
+	}
+	var ids []string
+	cur, err := models.ScanJobsColl().Find(ctx, filter, mopts.Find().SetProjection(bson.M{"_id": 1}))
+	if err == nil {
+		var rows []struct {
+			ID string `bson:"_id"`
+		}
+		err = cur.All(ctx, &rows)
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+		}
+	}
+	if err != nil {
+		log.Printf("[jobs] max attempts check error: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	now := time.Now()
+	msg := fmt.Sprintf("interrupted %d times (SCAN_JOB_MAX_ATTEMPTS=%d); not requeued", maxAttempts, maxAttempts)
+	if _, err := models.ScanJobsColl().UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{
+		"state": models.JobFailed, "error": msg, "finished_at": now, "updated_at": now,
+	}}); err != nil {
+		log.Printf("[jobs] max attempts update error: %v", err)
+		return
+	}
+	_, _ = models.CrawlsColl().UpdateMany(ctx, bson.M{"job_id": bson.M{"$in": ids}, "state": models.JobRunning}, bson.M{"$set": bson.M{
+		"state": models.JobFailed, "error": msg, "finished_at": now,
+	}})
+	log.Printf("[jobs] failed %d jobs over max attempts", len(ids))
+}
+
+func wakeScanWorkers() {
+	select {
+	case scanWake <- struct{}{}:
+	default:
+	}
+}
+
+// EnqueueScanJob: ساخت job جدید در Mongo و بیدار کردن workerها
+func EnqueueScanJob(ctx context.Context, req models.ScanRequest) (*models.ScanJobDoc, error) {
+	siteID, urlNorm, err := PageKeys(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &models.ScanJobDoc{
+		ID:        primitive.NewObjectID().Hex(),
+		Request:   req,
+		SiteID:    siteID,
+		URLNorm:   urlNorm,
+		State:     models.JobQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := insertScanJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// insertScanJob: سقف صف (SCAN_QUEUE_MAX)، درج job و بیدار کردن workerها
+func insertScanJob(ctx context.Context, job *models.ScanJobDoc) error {
+	maxQueued := int64(envInt("SCAN_QUEUE_MAX", 500))
+	queued, err := models.ScanJobsColl().CountDocuments(ctx, bson.M{"state": models.JobQueued})
+	if err != nil {
+		return err
+	}
+	if queued >= maxQueued {
+		return ErrScanQueueFull
+	}
+	if _, err := models.ScanJobsColl().InsertOne(ctx, job); err != nil {
+		return err
+	}
+	openScanStream(job.ID)
+	wakeScanWorkers()
+	return nil
+}
+
+func GetScanJob(ctx context.Context, id string) (*models.ScanJobDoc, error) {
+	var job models.ScanJobDoc
+	if err := models.ScanJobsColl().FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func scanWorker(ctx context.Context) {
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+	for {
+		job, err := claimScanJob(ctx)
+		if err == nil {
+			runScanJob(ctx, job)
+			continue
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("[jobs] claim error: %v", err)
+		}
+		select {
+		case <-scanWake:
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// claimScanJob: قدیمی‌ترین job در صف را به‌صورت اتمیک برمی‌دارد
+func claimScanJob(ctx context.Context) (*models.ScanJobDoc, error) {
+	now := time.Now()
+	var job models.ScanJobDoc
+	err := models.ScanJobsColl().FindOneAndUpdate(ctx,
+		bson.M{"state": models.JobQueued},
+		bson.M{
+			"$set": bson.M{"state": models.JobRunning, "started_at": now, "updated_at": now},
+			"$inc": bson.M{"attempts": 1},
+		},
+		mopts.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(mopts.After),
+	).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// setScanJob: آپدیت سند job؛ اگر state عوض شده باشد رویداد state (و در پایان done) هم منتشر می‌شود
+func setScanJob(ctx context.Context, id string, set bson.M) {
+	set["updated_at"] = time.Now()
+	if _, err := models.ScanJobsColl().UpdateByID(ctx, id, bson.M{"$set": set}); err != nil {
+		log.Printf("[jobs] update job=%s err=%v", id, err)
+	}
+	state, _ := set["state"].(string)
+	if state == "" {
+		return
+	}
+	errMsg, _ := set["error"].(string)
+	emitScanEvent(ctx, ScanEvent{Type: EvState, State: state, Error: errMsg})
+	if state == models.JobDone || state == models.JobFailed {
+		emitScanEvent(ctx, ScanEvent{Type: EvDone, State: state, Error: errMsg})
+	}
+}
+
+func runScanJob(ctx context.Context, job *models.ScanJobDoc) {
+	openScanStream(job.ID)
+	ctx = withScanJob(ctx, job.ID)
+	emitScanEvent(ctx, ScanEvent{Type: EvState, State: models.JobRunning, URL: job.Request.URL})
+
+	if job.Request.HARImport != nil {
+		runHARImportJob(ctx, job)
+		return
+	}
+
+	// پروکسی و پروفایل احراز هویت یک‌بار برای همهٔ مراحل (و همهٔ صفحات crawl)
+	ctx, err := resolveScanContext(ctx, job.Request)
+	if err != nil {
+		setScanJob(ctx, job.ID, bson.M{"state": models.JobFailed, "error": err.Error(), "finished_at": time.Now()})
+		return
+	}
+
+	if job.Request.Crawl != nil {
+		runCrawlJob(ctx, job)
+		return
+	}
+
+	req := job.Request
+	timings := models.ScanJobTimings{QueuedMs: job.StartedAt.Sub(job.CreatedAt).Milliseconds()}
+
+	scanStart := time.Now()
+	resp, err := RunScanContext(ctx, req)
+	timings.ScanMs = time.Since(scanStart).Milliseconds()
+	if ctx.Err() != nil {
+		// خاموش شدن سرور: job در running می‌ماند و بعد از ری‌استارت دوباره صف می‌شود
+		return
+	}
+	if err != nil {
+		timings.TotalMs = time.Since(job.StartedAt).Milliseconds()
+		setScanJob(ctx, job.ID, bson.M{
+			"state":       models.JobFailed,
+			"error":       "scan error: " + err.Error(),
+			"timings":     timings,
+			"finished_at": time.Now(),
+		})
+		return
+	}
+	resp.ProcessedAt = time.Now().Format(time.RFC3339)
+	resp.PageDuration = time.Since(scanStart).String()
+
+	setScanJob(ctx, job.ID, bson.M{"state": models.JobSaving, "timings": timings})
+
+	saveStart := time.Now()
+	saveErr := scanStage(ctx, "save", func() error {
+		saveCtx, cancelSave := context.WithTimeout(ctx, 10*time.Second)
+		defer cancelSave()
+		return SaveScanResponse(saveCtx, resp)
+	})
+	if saveErr != nil {
+		log.Printf("[mongo save] url=%s err=%v", req.URL, saveErr)
+	}
+
+	var sinksCount int
+	_ = scanStage(ctx, "sinks", func() error {
+		sinksCtx, cancelSinks := context.WithTimeout(ctx, 60*time.Second)
+		defer cancelSinks()
+		sinksCount = ScanAndPersistSinks(sinksCtx, req.URL, job.SiteID, job.URLNorm, resp)
+		return nil
+	})
+	var verified int
+	if req.VerifyXSS {
+		_ = scanStage(ctx, "verify", func() error {
+			verifyCtx, cancelVerify := context.WithTimeout(ctx, 3*time.Minute)
+			defer cancelVerify()
+			verified = VerifyAndPersistXSS(verifyCtx, req.URL, job.SiteID, job.URLNorm)
+			return nil
+		})
+	}
+	var polluted int
+	if req.PrototypePollution {
+		_ = scanStage(ctx, "prototype_pollution", func() error {
+			ppCtx, cancelPP := context.WithTimeout(ctx, 2*time.Minute)
+			defer cancelPP()
+			polluted = CheckAndPersistPrototypePollution(ppCtx, req.URL, job.SiteID, job.URLNorm)
+			return nil
+		})
+	}
+	timings.SaveMs = time.Since(saveStart).Milliseconds()
+	timings.TotalMs = time.Since(job.StartedAt).Milliseconds()
+
+	set := bson.M{
+		"timings":     timings,
+		"scan_errors": resp.Errors,
+		"finished_at": time.Now(),
+		"result": models.ScanJobResult{
+			Resources: len(resp.Resources),
+			Endpoints: len(resp.UniquePaths),
+			Scripts:   len(resp.AllScripts),
+			Sinks:     sinksCount,
+			Verified:  verified,
+			Polluted:  polluted,
+			Duration:  resp.PageDuration,
+		},
+	}
+	if saveErr != nil {
+		set["state"] = models.JobFailed
+		set["error"] = "save error: " + saveErr.Error()
+	} else {
+		set["state"] = models.JobDone
+		set["page_link"] = "/api/pages/by-url?url=" + url.QueryEscape(job.URLNorm)
+	}
+	setScanJob(ctx, job.ID, set)
+}
+
+// ScanAndPersistSinks: اسکن سینک‌ها (استاتیک + runtime) و ذخیره؛ تعداد سینک‌های یافته‌شده را برمی‌گرداند
+// resp.MappedSinks (سینک‌های کد اصلی از source map) جای سینک‌های همان bundle را می‌گیرند؛
+// نتیجه روی snapshot همین اسکن (resp.SnapshotID) هم ثبت می‌شود
+func ScanAndPersistSinks(ctx context.Context, rawURL, siteID, urlNorm string, resp *models.ScanResponse) int {
+	var sinks []models.SinkDoc
+	mapped := resp.MappedSinks
+
+	// هر دو اسکنر درون‌صفحه‌ای در یک تب اینسترومنت‌شده اجرا می‌شوند
+	res, err := scanSinksInTab(ctx, rawURL, urlNorm, siteID, true)
+	if err != nil {
+		log.Printf("[sinks] tab scan error: %v", err)
+	}
+	sinks = append(sinks, res.Static...)
+	sinks = append(sinks, res.Runtime...)
+
+	if len(mapped) > 0 {
+		bundles := make(map[string]struct{})
+		for _, s := range mapped {
+			bundles[s.BundleURL] = struct{}{}
+		}
+		kept := sinks[:0]
+		for _, s := range sinks {
+			// فقط سینک‌های استاتیک bundle جایگزین می‌شوند؛ runtime (و taint) می‌مانند
+			if _, ok := bundles[s.SourceURL]; !ok || s.SourceType == "runtime" {
+				kept = append(kept, s)
+			}
+		}
+		sinks = append(kept, mapped...)
+	}
+
+	// ست کردن شناسه‌ها اگر خالی باشند
+	for i := range sinks {
+		if sinks[i].SiteID == "" {
+			sinks[i].SiteID = siteID
+		}
+		if sinks[i].PageURL == "" {
+			sinks[i].PageURL = urlNorm
+		}
+	}
+
+	if len(sinks) > 0 {
+		if bwRes, bwErr := PersistSinks(ctx, sinks); bwErr != nil {
+			log.Printf("[sinks] persist error: %v", bwErr)
+		} else {
+			log.Printf("[sinks] bulk write matched=%d modified=%d upserted=%d",
+				bwRes.MatchedCount, bwRes.ModifiedCount, bwRes.UpsertedCount)
+		}
+	}
+	// اسکن تب ناموفق یعنی لیست sinkها ناقص است؛ snapshot بدون sink بماند تا diff گمراه نشود
+	if err == nil {
+		if err := AttachSnapshotSinks(ctx, resp.SnapshotID, sinks); err != nil {
+			log.Printf("[sinks] snapshot error: %v", err)
+		}
+	}
+	if len(res.Flows) > 0 {
+		carryXSSVerification(ctx, res.Flows)
+		if err := PersistFindings(ctx, res.Flows); err != nil {
+			log.Printf("[sinks] taint flows persist error: %v", err)
+		} else {
+			log.Printf("[sinks] taint flows=%d", len(res.Flows))
+		}
+	}
+	if len(res.Handlers) > 0 {
+		if err := PersistFindings(ctx, res.Handlers); err != nil {
+			log.Printf("[sinks] message handlers persist error: %v", err)
+		}
+	}
+	return len(sinks)
+}
@@ -3,38 +3,9 @@ package functions
 import (
 	"SiteChecker/models"
 	"regexp"
-	"strings"
 	"time"
 )
 
-var (
-	reInnerHTML      = regexp.MustCompile(`\.innerHTML\s*=`)
-	reDangerousSet   = regexp.MustCompile(`dangerouslySetInnerHTML\s*:`)
-	reEval           = regexp.MustCompile(`\beval\s*\(`)
-	reNewFunction    = regexp.MustCompile(`\bnew\s+Function\s*\(`)
-	reStrArg         = `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')` // رشتهٔ JS
-	reSetTimeoutStr  = regexp.MustCompile(`\bsetTimeout\s*\(\s*` + reStrArg)
-	reSetIntervalStr = regexp.MustCompile(`\bsetInterval\s*\(\s*` + reStrArg)
-	reDocWrite       = regexp.MustCompile(`\bdocument\.write\s*\(`)
-	rePrompt         = regexp.MustCompile(`\bprompt\s*\(`)
-	reAlert          = regexp.MustCompile(`\balert\s*\(`)
-	reConfirm        = regexp.MustCompile(`\bconfirm\s*\(`)
-	reFetch          = regexp.MustCompile(`\bfetch\s*\(`)
-	reXHR            = regexp.MustCompile(`\bXMLHttpRequest\b`)
-	reSyncXHR        = regexp.MustCompile(`\.open\s*\([^,]+,[^,]+,\s*false\s*\)`)
-	reLocalStorage   = regexp.MustCompile(`\blocalStorage\b`)
-	reSessionStorage = regexp.MustCompile(`\bsessionStorage\b`)
-	reJSONParse      = regexp.MustCompile(`\bJSON\.parse\s*\(`)
-	reJSONStr        = regexp.MustCompile(`\bJSON\.stringify\s*\(`)
-	reDirectDOM      = regexp.MustCompile(`document\.(getElementById|getElementsByClassName|querySelector(All)?)\s*\(`)
-	reHeavyLoop      = regexp.MustCompile(`for\s*\([^;]*;[^;]*<\s*\d{6,}\s*;|while\s*\(\s*true\s*\)`)
-	// postMessage
-	rePostMessageSend = regexp.MustCompile(`\.postMessage\s*\(`)
-	rePostMessageRecv = regexp.MustCompile(`addEventListener\s*\(\s*['"]message['"]|\.onmessage\s*=`)
-	// HTML inline handlers
-	reInlineHandler = regexp.MustCompile(`on[a-zA-Z]+\s*=\s*` + reStrArg)
-)
-
 func lineCol(s string, idx int) (int, int) {
 	line, col := 1, 1
 	for i := 0; i < idx && i < len(s); i++ {
@@ -79,51 +50,25 @@ func scanOne(kind, sourceType, sourceURL, text string, re *regexp.Regexp, out *[
 	}
 }
 
+// ScanSinksGo: اسکن استاتیک HTML و اسکریپت‌ها با قوانین regex (SinkRules)
 func ScanSinksGo(html string, scripts map[string]string, pageURL, siteID string) []models.SinkDoc {
 	var out []models.SinkDoc
+	rules := SinkRules()
 
-	// HTML
-	srcURL := pageURL
-	srcType := "html"
-	htmlL := strings.ToLower(html)
-	_ = htmlL
-
-	scanOne("inlineEventHandler", srcType, srcURL, html, reInlineHandler, &out, siteID, pageURL)
-
-	scanOne("postMessageRecv", srcType, srcURL, html, rePostMessageRecv, &out, siteID, pageURL)
-
-	scanOne("directDOM", srcType, srcURL, html, reDirectDOM, &out, siteID, pageURL)
+	for _, r := range rules.scanRules("html") {
+		scanOne(r.Kind, "html", pageURL, html, r.re, &out, siteID, pageURL)
+	}
 
 	for u, code := range scripts {
-		srcType = "script"
-		srcURL = u
-		if normU, normT := normalizeSourceURL(pageURL, srcURL); normU != "" {
-			srcURL = normU
-			if srcType == "" || srcType == "script" { // فقط اگر لازم بود
-				srcType = normT
-			}
-		}
-		scanOne("innerHTML", srcType, srcURL, code, reInnerHTML, &out, siteID, pageURL)
-		scanOne("dangerouslySetInnerHTML", srcType, srcURL, code, reDangerousSet, &out, siteID, pageURL)
-		scanOne("eval", srcType, srcURL, code, reEval, &out, siteID, pageURL)
-		scanOne("newFunction", srcType, srcURL, code, reNewFunction, &out, siteID, pageURL)
-		scanOne("setTimeoutStr", srcType, srcURL, code, reSetTimeoutStr, &out, siteID, pageURL)
-		scanOne("setIntervalStr", srcType, srcURL, code, reSetIntervalStr, &out, siteID, pageURL)
-		scanOne("documentWrite", srcType, srcURL, code, reDocWrite, &out, siteID, pageURL)
-		scanOne("prompt", srcType, srcURL, code, rePrompt, &out, siteID, pageURL)
-		scanOne("alert", srcType, srcURL, code, reAlert, &out, siteID, pageURL)
-		scanOne("confirm", srcType, srcURL, code, reConfirm, &out, siteID, pageURL)
-		scanOne("fetch", srcType, srcURL, code, reFetch, &out, siteID, pageURL)
-		scanOne("XMLHttpRequest", srcType, srcURL, code, reXHR, &out, siteID, pageURL)
-		scanOne("syncXHR", srcType, srcURL, code, reSyncXHR, &out, siteID, pageURL)
-		scanOne("localStorage", srcType, srcURL, code, reLocalStorage, &out, siteID, pageURL)
-		scanOne("sessionStorage", srcType, srcURL, code, reSessionStorage, &out, siteID, pageURL)
-		scanOne("JSON.parse", srcType, srcURL, code, reJSONParse, &out, siteID, pageURL)
-		scanOne("JSON.stringify", srcType, srcURL, code, reJSONStr, &out, siteID, pageURL)
-		scanOne("postMessageSend", srcType, srcURL, code, rePostMessageSend, &out, siteID, pageURL)
-		scanOne("postMessageRecv", srcType, srcURL, code, rePostMessageRecv, &out, siteID, pageURL)
-		scanOne("directDOM", srcType, srcURL, code, reDirectDOM, &out, siteID, pageURL)
-		scanOne("heavyLoop", srcType, srcURL, code, reHeavyLoop, &out, siteID, pageURL)
+		srcURL, srcType := normalizeSourceURL(pageURL, u)
+		scanScriptSinks(srcType, srcURL, code, &out, siteID, pageURL)
 	}
 	return out
 }
+
+// scanScriptSinks: قوانین مخصوص کد JS (برای bundle و سورس اصلی source map)؛ inline فقط قوانین inline
+func scanScriptSinks(srcType, srcURL, code string, out *[]models.SinkDoc, siteID, pageURL string) {
+	for _, r := range SinkRules().scanRules(ruleScope(srcType)) {
+		scanOne(r.Kind, srcType, srcURL, code, r.re, out, siteID, pageURL)
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -44,55 +44,31 @@ func runDueWatches(ctx context.Context) error {
 		if err := cur.Decode(&w); err != nil {
 			continue
 		}
-
-		// 1) اسکن
-		req := models.ScanRequest{URL: w.URL, WaitSec: 7, JSFetchTimeout: 8}
-		resp, err := RunScan(req)
-		if err != nil {
-			log.Printf("[watch] scan error url=%s err=%v", w.URL, err)
-		}
-
-		if resp != nil {
-			// ذخیره نتایج صفحه/اندپوینت‌ها
-			_ = SaveScanResults(ctx, w.URL, resp.Resources, resp.UniquePaths, resp.AllScripts)
-		}
-
-		// 2) محاسبه تغییرات
-		changed, summary := computeChangeSummary(ctx, w.SiteID, w.URLNorm, w.LastSummary)
-
-		// 3) آپدیت زمان‌بندی
-		upd := bson.M{
-			"$set": bson.M{
-				"last_run_at":  now,
-				"next_run_at":  now.Add(time.Duration(max(5, w.FreqMin)) * time.Minute),
-				"last_summary": summary,
-				"updated_at":   time.Now(),
-			},
-		}
-		if changed {
-			upd["$set"].(bson.M)["last_change_at"] = time.Now()
-			// 4) Notify Discord
-			_ = notifyDiscord(ctx, w.SiteID, w.URL, summary)
-		}
-		_, _ = models.WatchesColl().UpdateOne(ctx, bson.M{"site_id": w.SiteID, "url_norm": w.URLNorm}, upd)
+		RunWatch(ctx, w, models.WatchTriggerSchedule)
 	}
 	return nil
 }
 
-func computeChangeSummary(ctx context.Context, siteID, urlNorm string, prev models.WatchSummary) (bool, models.WatchSummary) {
+// computeWatchSummary: شمارش‌های فعلی صفحه برای نمایش و پیام (تشخیص تغییر با computeWatchDelta است)
+func computeWatchSummary(ctx context.Context, siteID, urlNorm, minSeverity string) models.WatchSummary {
 	epCount, epLast := endpointsStatsForPage(ctx, siteID, urlNorm)
 	skCount, skLast := sinksStatsForPage(ctx, siteID, urlNorm)
-	sum := models.WatchSummary{Endpoints: epCount, Sinks: skCount, LastEP: epLast, LastSink: skLast}
+	secCount, secLast := secretsStatsForPage(ctx, siteID, urlNorm)
+	sum := models.WatchSummary{Endpoints: epCount, Sinks: skCount, LastEP: epLast, LastSink: skLast, Secrets: secCount, LastSecret: secLast}
+	sum.SinkSeverity = sinkSeverityForPage(ctx, siteID, urlNorm)
+	if minSeverity != "" {
+		sum.Severe, sum.LastSevere = severeStatsForPage(ctx, siteID, urlNorm, minSeverity)
+	}
 	// دلخواه: Digest
 	h := sha256.New()
 	h.Write([]byte(siteID))
 	h.Write([]byte(urlNorm))
 	h.Write([]byte(epLast.Format(time.RFC3339)))
 	h.Write([]byte(skLast.Format(time.RFC3339)))
-	h.Write([]byte{byte(epCount), byte(skCount)})
+	h.Write([]byte(secLast.Format(time.RFC3339)))
+	h.Write([]byte{byte(epCount), byte(skCount), byte(secCount), byte(sum.Severe)})
 	sum.Digest = hex.EncodeToString(h.Sum(nil))
-	changed := (sum.Endpoints != prev.Endpoints) || (sum.Sinks != prev.Sinks) || epLast.After(prev.LastEP) || skLast.After(prev.LastSink)
-	return changed, sum
+	return sum
 }
 
 func endpointsStatsForPage(ctx context.Context, siteID, urlNorm string) (int, time.Time) {
@@ -102,7 +78,7 @@ func endpointsStatsForPage(ctx context.Context, siteID, urlNorm string) (int, ti
 		LastSeen time.Time `bson:"last"`
 	}
 	_ = models.EndpointsColl().FindOne(ctx, bson.M{"site_id": siteID, "source_urls": urlNorm},
-		options.FindOne().SetSort(bson.D{{"last_seen", -1}}).SetProjection(bson.M{"last": "$last_seen"})).Decode(&last)
+		options.FindOne().SetSort(bson.D{{Key: "last_seen", Value: -1}}).SetProjection(bson.M{"last": "$last_seen"})).Decode(&last)
 	return int(epCount), last.LastSeen
 }
 
@@ -112,39 +88,106 @@ func sinksStatsForPage(ctx context.Context, siteID, urlNorm string) (int, time.T
 		Last time.Time `bson:"last"`
 	}
 	_ = models.SinksColl().FindOne(ctx, bson.M{"site_id": siteID, "page_url": urlNorm},
-		options.FindOne().SetSort(bson.D{{"last_detected_at", -1}}).SetProjection(bson.M{"last": "$last_detected_at"})).Decode(&last)
+		options.FindOne().SetSort(bson.D{{Key: "last_detected_at", Value: -1}}).SetProjection(bson.M{"last": "$last_detected_at"})).Decode(&last)
 	return int(skCount), last.Last
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+// secretsStatsForPage: تعداد secretهای صفحه و first_seen جدیدترین
+func secretsStatsForPage(ctx context.Context, siteID, urlNorm string) (int, time.Time) {
+	count, _ := models.SecretsColl().CountDocuments(ctx, bson.M{"site_id": siteID, "page_url": urlNorm})
+	var last struct {
+		Last time.Time `bson:"first_seen"`
 	}
-	return b
+	_ = models.SecretsColl().FindOne(ctx, bson.M{"site_id": siteID, "page_url": urlNorm},
+		options.FindOne().SetSort(bson.D{{Key: "first_seen", Value: -1}}).SetProjection(bson.M{"first_seen": 1})).Decode(&last)
+	return int(count), last.Last
 }
 
-// notifyDiscord: اگر دیسکورد فعال و وبهوک ست باشد، پیام تغییرات را ارسال می‌کند.
-func notifyDiscord(ctx context.Context, siteID, pageURL string, sum models.WatchSummary) error {
-	// خواندن تنظیمات
-	doc, err := models.GetDiscordSettings(ctx)
+// sinkSeverityForPage: تعداد sinkهای صفحه به تفکیک severity
+func sinkSeverityForPage(ctx context.Context, siteID, urlNorm string) map[string]int {
+	cur, err := models.SinksColl().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"site_id": siteID, "page_url": urlNorm}}},
+		{{Key: "$group", Value: bson.M{"_id": "$severity", "count": bson.M{"$sum": 1}}}},
+	})
 	if err != nil {
-		return err
+		return nil
 	}
-	if !doc.Enabled || strings.TrimSpace(doc.WebhookURL) == "" {
-		// پیکربندی نشده یا غیرفعال → نوتیفای نکن
+	defer cur.Close(ctx)
+	var rows []struct {
+		Severity string `bson:"_id"`
+		Count    int    `bson:"count"`
+	}
+	if err := cur.All(ctx, &rows); err != nil || len(rows) == 0 {
 		return nil
 	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		sev := r.Severity
+		if sev == "" {
+			sev = models.SeverityInfo
+		}
+		out[sev] += r.Count
+	}
+	return out
+}
 
-	// ساخت پیام خلاصه
-	msg := fmt.Sprintf(
-		"🔔 *SiteChecker*\nSite: `%s`\nPage: %s\nEndpoints: %d (last: %s)\nSinks: %d (last: %s)\nTime: %s",
-		siteID,
-		pageURL,
-		sum.Endpoints, sum.LastEP.Format(time.RFC3339),
-		sum.Sinks, sum.LastSink.Format(time.RFC3339),
-		time.Now().Format(time.RFC3339),
-	)
+// severeStatsForPage: تعداد sink + secret با severity >= minSeverity و جدیدترین first seen آن‌ها
+func severeStatsForPage(ctx context.Context, siteID, urlNorm, minSeverity string) (int, time.Time) {
+	skFilter := bson.M{"site_id": siteID, "page_url": urlNorm, "severity_rank": bson.M{"$gte": models.SeverityRank(minSeverity)}}
+	secFilter := bson.M{"site_id": siteID, "page_url": urlNorm, "severity": bson.M{"$in": models.SeveritiesAtLeast(minSeverity)}}
 
-	// ارسال با وبهوک (همین پکیج: functions.SendDiscordWebhook)
-	return SendDiscordWebhook(ctx, doc.WebhookURL, msg)
+	skCount, _ := models.SinksColl().CountDocuments(ctx, skFilter)
+	secCount, _ := models.SecretsColl().CountDocuments(ctx, secFilter)
+
+	var sk struct {
+		First time.Time `bson:"first_detected_at"`
+	}
+	_ = models.SinksColl().FindOne(ctx, skFilter,
+		options.FindOne().SetSort(bson.D{{Key: "first_detected_at", Value: -1}}).SetProjection(bson.M{"first_detected_at": 1})).Decode(&sk)
+	var sec struct {
+		First time.Time `bson:"first_seen"`
+	}
+	_ = models.SecretsColl().FindOne(ctx, secFilter,
+		options.FindOne().SetSort(bson.D{{Key: "first_seen", Value: -1}}).SetProjection(bson.M{"first_seen": 1})).Decode(&sec)
+
+	last := sk.First
+	if sec.First.After(last) {
+		last = sec.First
+	}
+	return int(skCount + secCount), last
+}
+
+// formatSeverityCounts: "high 2, medium 1" به ترتیب شدت
+func formatSeverityCounts(m map[string]int) string {
+	var parts []string
+	for _, sev := range []string{models.SeverityCritical, models.SeverityHigh, models.SeverityMedium, models.SeverityLow, models.SeverityInfo} {
+		if m[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", sev, m[sev]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// notifyDiscord: پیام تغییرات این اجرا (delta) و شمارش‌های فعلی صفحه
+func notifyDiscord(ctx context.Context, siteID, pageURL, minSeverity string, sum models.WatchSummary, delta *models.WatchDelta) models.WatchNotification {
+	// ساخت پیام: اول تغییرات، بعد وضعیت فعلی
+	sinks := fmt.Sprintf("%d", sum.Sinks)
+	if bySev := formatSeverityCounts(sum.SinkSeverity); bySev != "" {
+		sinks += " [" + bySev + "]"
+	}
+	msg := fmt.Sprintf("🔔 *SiteChecker*\nSite: `%s`\nPage: %s\n", siteID, pageURL)
+	msg += formatWatchDelta(delta)
+	msg += fmt.Sprintf("Totals: endpoints %d, sinks %s, secrets %d\n", sum.Endpoints, sinks, sum.Secrets)
+	if minSeverity != "" {
+		msg += fmt.Sprintf("Findings >= %s: %d (newest: %s)\n", minSeverity, sum.Severe, sum.LastSevere.Format(time.RFC3339))
+	}
+	msg += "Time: " + time.Now().Format(time.RFC3339)
+	return sendWatchNotification(ctx, "changes", msg)
 }
@@ -4,6 +4,8 @@ package functions
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/chromedp/chromedp"
 
@@ -11,36 +13,58 @@ import (
 	rt "github.com/chromedp/cdproto/runtime"
 )
 
+// ScriptCollector: سورس اسکریپت‌هایی که تب اجرا می‌کند + sourceMappingURL گزارش‌شدهٔ هر کدام
+type ScriptCollector struct {
+	mu      sync.Mutex
+	pending atomic.Int32      // GetScriptSourceهای در جریان؛ رویداد بعد از snapshot هم می‌رسد، پس WaitGroup نه
+	scripts map[string]string // key = URL یا inline:<id>
+	maps    map[string]string // key → sourceMapURL (همان‌طور که Chrome گزارش داده)
+}
+
 // CollectScripts همهٔ اسکریپت‌هایی که تب واقعاً اجرا می‌کند را جمع می‌کند
-// map[key] = source   (key = URL یا inline:<id>)
-func CollectScripts(ctx context.Context) (map[string]string, error) {
-	var mu sync.Mutex
-	scripts := make(map[string]string)
+func CollectScripts(ctx context.Context) (*ScriptCollector, error) {
+	c := &ScriptCollector{
+		scripts: make(map[string]string),
+		maps:    make(map[string]string),
+	}
 
 	// رویداد ScriptParsed را گوش بده
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
-		if e, ok := ev.(*debugger.EventScriptParsed); ok {
-			sid := e.ScriptID // در نسخه‌های جدید از نوع runtime.ScriptID است
-			u := e.URL
-			if u == "" {
-				u = "inline:" + string(sid)
-			}
-
-			// سورس را بگیر (امضای جدید 3 خروجی می‌دهد)
-			go func(uid string, id rt.ScriptID) {
-				src, _, err := debugger.GetScriptSource(id).Do(ctx)
-				if err != nil || src == "" {
-					return
-				}
-				// سقف 2MB برای هر فایل
-				if len(src) > 2<<20 {
-					src = src[:2<<20]
-				}
-				mu.Lock()
-				scripts[uid] = src
-				mu.Unlock()
-			}(u, sid)
+		e, ok := ev.(*debugger.EventScriptParsed)
+		if !ok {
+			return
+		}
+		u := e.URL
+		if u == "" {
+			u = "inline:" + string(e.ScriptID)
 		}
+		if e.SourceMapURL != "" {
+			c.mu.Lock()
+			c.maps[u] = e.SourceMapURL
+			c.mu.Unlock()
+		}
+
+		// listener نباید بلاک شود؛ CDP از goroutine جدا و از طریق executor تب
+		c.pending.Add(1)
+		go func(uid string, id rt.ScriptID) {
+			defer c.pending.Add(-1)
+			var src string
+			err := chromedp.Run(ctx, chromedp.ActionFunc(func(cx context.Context) error {
+				var err error
+				src, _, err = debugger.GetScriptSource(id).Do(cx)
+				return err
+			}))
+			if err != nil || src == "" {
+				return
+			}
+			// سقف 2MB برای هر فایل
+			if len(src) > 2<<20 {
+				src = src[:2<<20]
+			}
+			c.mu.Lock()
+			c.scripts[uid] = src
+			c.mu.Unlock()
+		}(u, e.ScriptID)
 	})
 
 	// Enable به‌صورت ActionFunc چون Do الان دو خروجی می‌دهد
@@ -51,5 +75,25 @@ func CollectScripts(ctx context.Context) (map[string]string, error) {
 		return nil, err
 	}
 
-	return scripts, nil
+	return c, nil
+}
+
+// snapshot: تا timeout منتظر GetScriptSourceهای در جریان می‌ماند و کپی نقشه‌ها را برمی‌گرداند
+func (c *ScriptCollector) snapshot(timeout time.Duration) (scripts, sourceMaps map[string]string) {
+	deadline := time.Now().Add(timeout)
+	for c.pending.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scripts = make(map[string]string, len(c.scripts))
+	for k, v := range c.scripts {
+		scripts[k] = v
+	}
+	sourceMaps = make(map[string]string, len(c.maps))
+	for k, v := range c.maps {
+		sourceMaps[k] = v
+	}
+	return scripts, sourceMaps
 }
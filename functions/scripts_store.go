@@ -0,0 +1,210 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrScriptNotStored = errors.New("script body not stored")
+
+// scriptStoreMax: اسکریپت‌های بزرگ‌تر ذخیره نمی‌شوند (سقف سند Mongo ۱۶MB است)
+func scriptStoreMax() int { return envInt("SCRIPT_STORE_MAX_KB", 8192) * 1024 }
+
+// scriptStoreTimeout: سقف زمان ذخیره و تحلیل بدنهٔ اسکریپت‌های یک صفحه
+func scriptStoreTimeout() time.Duration {
+	return time.Duration(envInt("SCRIPT_STORE_TIMEOUT_SEC", 60)) * time.Second
+}
+
+// saveScriptBodies: محتوای اسکریپت‌ها (dedup با sha256) و نسخه‌های URLهای خارجی
+func saveScriptBodies(ctx context.Context, siteID, pageURL string, bodies map[string]string, now time.Time) error {
+	for key, code := range bodies {
+		if code == "" || len(code) > scriptStoreMax() {
+			continue
+		}
+		srcURL, srcType := normalizeSourceURL(pageURL, key)
+		sha := sha256Hex(code)
+		if err := saveScriptBlob(ctx, sha, code, now); err != nil {
+			return err
+		}
+		// inline/dynamic URL ثابتی ندارند؛ فقط با hash از snapshot قابل دسترسی‌اند
+		if srcType != "script" {
+			continue
+		}
+		if err := saveScriptVersion(ctx, siteID, pageURL, srcURL, sha, code, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveScriptBlob(ctx context.Context, sha, code string, now time.Time) error {
+	n, err := models.ScriptsColl().CountDocuments(ctx, bson.M{"_id": sha}, mopts.Count().SetLimit(1))
+	if err != nil || n > 0 {
+		return err
+	}
+	gz, err := gzipString(code)
+	if err != nil {
+		return err
+	}
+	_, err = models.ScriptsColl().UpdateByID(ctx, sha, bson.M{"$setOnInsert": models.ScriptBlobDoc{
+		SHA256: sha, Size: len(code), GzipSize: len(gz), Body: gz, FirstSeen: now,
+	}}, mopts.Update().SetUpsert(true))
+	return err
+}
+
+// saveScriptVersion: نسخهٔ جدید یک URL یک‌بار تحلیل می‌شود (endpointها و sinkها) و بعد فقط last_seen جلو می‌رود
+func saveScriptVersion(ctx context.Context, siteID, pageURL, srcURL, sha, code string, now time.Time) error {
+	id := sha256Hex(siteID + "|" + srcURL + "|" + sha)
+	res, err := models.ScriptVersionsColl().UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_seen": now}})
+	if err != nil || res.MatchedCount > 0 {
+		return err
+	}
+	endpoints, sinks := analyzeScriptVersion(siteID, pageURL, srcURL, code)
+	_, err = models.ScriptVersionsColl().UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"last_seen": now},
+		"$setOnInsert": bson.M{
+			"site_id": siteID, "url": srcURL, "sha256": sha, "size": len(code), "first_seen": now,
+			"page_url": pageURL, "endpoints": endpoints, "sinks": sinks,
+		},
+	}, mopts.Update().SetUpsert(true))
+	return err
+}
+
+// analyzeScriptVersion: endpointها و sinkهای استاتیک خود این فایل (برای خلاصهٔ تغییر نسخه)
+func analyzeScriptVersion(siteID, pageURL, srcURL, code string) ([]string, []models.ScriptSinkRef) {
+	hints := endpointHints{}
+	_ = extractEndpointsFromJS(code, hints)
+	endpoints := hints.paths()
+	if endpoints == nil {
+		endpoints = []string{}
+	}
+
+	var found []models.SinkDoc
+	scanScriptSinks("script", srcURL, code, &found, siteID, pageURL)
+	sinks := make([]models.ScriptSinkRef, 0, len(found))
+	seen := make(map[string]bool, len(found))
+	for _, s := range found {
+		classifySink(&s)
+		key := snapshotSinkKey(s)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		snip := s.Snippet
+		if len(snip) > 200 {
+			snip = snip[:200]
+		}
+		sinks = append(sinks, models.ScriptSinkRef{Key: key, Kind: s.Kind, Line: s.Line, Severity: s.Severity, Snippet: snip})
+	}
+	return endpoints, sinks
+}
+
+// LoadScriptBody: محتوای اسکریپت با sha256
+func LoadScriptBody(ctx context.Context, sha string) (string, error) {
+	var blob models.ScriptBlobDoc
+	err := models.ScriptsColl().FindOne(ctx, bson.M{"_id": sha}).Decode(&blob)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", ErrScriptNotStored
+	}
+	if err != nil {
+		return "", err
+	}
+	return gunzipString(blob.Body)
+}
+
+// FindScriptVersion: نسخهٔ sha از url (sha خالی = آخرین نسخه؛ before غیرصفر = آخرین نسخهٔ قبل از آن)
+func FindScriptVersion(ctx context.Context, siteID, srcURL, sha string, before time.Time) (*models.ScriptVersionDoc, error) {
+	filter := bson.M{"url": srcURL}
+	if siteID != "" {
+		filter["site_id"] = siteID
+	}
+	switch {
+	case sha != "":
+		filter["sha256"] = sha
+	case !before.IsZero():
+		filter["first_seen"] = bson.M{"$lt": before}
+	}
+	var v models.ScriptVersionDoc
+	opts := mopts.FindOne().SetSort(bson.D{{Key: "first_seen", Value: -1}})
+	if err := models.ScriptVersionsColl().FindOne(ctx, filter, opts).Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CompareScriptVersions: endpointها و sinkهای اضافه/حذف‌شده بین دو نسخه
+func CompareScriptVersions(from, to *models.ScriptVersionDoc) models.ScriptVersionChange {
+	c := models.ScriptVersionChange{
+		URL:        to.URL,
+		FromSHA256: from.SHA256,
+		ToSHA256:   to.SHA256,
+		FromSize:   from.Size,
+		ToSize:     to.Size,
+	}
+	c.AddedEndpoints, c.RemovedEndpoints = diffStrings(from.Endpoints, to.Endpoints)
+	c.AddedSinks, c.RemovedSinks = diffScriptSinks(from.Sinks, to.Sinks)
+	return c
+}
+
+func diffScriptSinks(a, b []models.ScriptSinkRef) (added, removed []models.ScriptSinkRef) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s.Key] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s.Key] = true
+	}
+	added, removed = []models.ScriptSinkRef{}, []models.ScriptSinkRef{}
+	for _, s := range b {
+		if !inA[s.Key] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s.Key] {
+			removed = append(removed, s)
+		}
+	}
+	// شدیدترها اول
+	bySeverity := func(list []models.ScriptSinkRef) {
+		sort.SliceStable(list, func(i, j int) bool {
+			return models.SeverityRank(list[i].Severity) > models.SeverityRank(list[j].Severity)
+		})
+	}
+	bySeverity(added)
+	bySeverity(removed)
+	return added, removed
+}
+
+func gzipString(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := io.WriteString(zw, s); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipString(b []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	return string(out), err
+}
@@ -0,0 +1,287 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// secretRule: الگوی یک نوع secret؛ group گروهی از regex است که خود مقدار را دارد (0 = کل match)
+type secretRule struct {
+	ID          string
+	Description string
+	Severity    string
+	re          *regexp.Regexp
+	group       int
+	minEntropy  float64 // برای الگوهای عمومی؛ 0 = بدون بررسی
+	plain       bool    // مقدار محرمانه نیست (hostname/URL) و ماسک نمی‌شود
+}
+
+var secretRules = []secretRule{
+	{ID: "aws_access_key_id", Description: "AWS access key ID", Severity: models.SeverityHigh,
+		re: regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`), group: 1},
+	{ID: "aws_secret_access_key", Description: "AWS secret access key", Severity: models.SeverityCritical,
+		re: regexp.MustCompile(`(?i)aws[\w.-]{0,20}(?:secret|private)[\w.-]{0,20}["']?\s*[:=]\s*["']([0-9a-zA-Z/+]{40})["']`), group: 1, minEntropy: 3.5},
+	{ID: "gcp_api_key", Description: "Google / Firebase API key", Severity: models.SeverityMedium,
+		re: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`), group: 1},
+	{ID: "gcp_service_account", Description: "Google service account private key ID", Severity: models.SeverityCritical,
+		re: regexp.MustCompile(`["']private_key_id["']\s*:\s*["']([0-9a-f]{40})["']`), group: 1},
+	{ID: "firebase_database", Description: "Firebase Realtime Database URL", Severity: models.SeverityLow,
+		re: regexp.MustCompile(`\b(https://[a-z0-9-]+\.(?:firebaseio\.com|firebasedatabase\.app))\b`), group: 1, plain: true},
+	{ID: "stripe_secret_key", Description: "Stripe live secret/restricted key", Severity: models.SeverityCritical,
+		re: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9a-zA-Z]{24,99})\b`), group: 1},
+	{ID: "stripe_publishable_key", Description: "Stripe live publishable key", Severity: models.SeverityInfo,
+		re: regexp.MustCompile(`\b(pk_live_[0-9a-zA-Z]{24,99})\b`), group: 1},
+	{ID: "slack_token", Description: "Slack token", Severity: models.SeverityHigh,
+		re: regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,72})\b`), group: 1},
+	{ID: "slack_webhook", Description: "Slack incoming webhook", Severity: models.SeverityHigh,
+		re: regexp.MustCompile(`(https://hooks\.slack\.com/services/T[0-9A-Z]+/B[0-9A-Z]+/[0-9A-Za-z]+)`), group: 1},
+	{ID: "github_token", Description: "GitHub token", Severity: models.SeverityHigh,
+		re: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[0-9A-Za-z]{36,255}|github_pat_[0-9A-Za-z_]{82})\b`), group: 1},
+	{ID: "private_key", Description: "Private key block", Severity: models.SeverityCritical,
+		re: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----[A-Za-z0-9+/=\s\\n]{16,}`), group: 0},
+	{ID: "jwt", Description: "JSON Web Token", Severity: models.SeverityMedium,
+		re: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`), group: 1},
+	{ID: "generic_secret", Description: "High-entropy value assigned to a secret-like name", Severity: models.SeverityMedium,
+		re:    regexp.MustCompile(`(?i)\b[\w.-]{0,30}(?:api[_-]?key|apikey|secret|token|passwd|password|auth[_-]?key|access[_-]?key|client[_-]?secret)[\w.-]{0,20}["']?\s*[:=]\s*["']([A-Za-z0-9_\-+/=.]{16,128})["']`),
+		group: 1, minEntropy: 3.5},
+	{ID: "internal_host", Description: "Internal hostname or private IP", Severity: models.SeverityLow,
+		re:    regexp.MustCompile(`(?i)(?://|["'@])((?:[a-z0-9][a-z0-9-]*\.)+(?:internal|corp|intranet|lan|local)|10\.\d{1,3}\.\d{1,3}\.\d{1,3}|192\.168\.\d{1,3}\.\d{1,3}|172\.(?:1[6-9]|2\d|3[01])\.\d{1,3}\.\d{1,3})(?:[:/"']|$)`),
+		group: 1, plain: true},
+}
+
+// مقدارهای نمونه/جایگزین که در کد زیاد دیده می‌شوند
+var secretPlaceholderRe = regexp.MustCompile(`(?i)example|placeholder|your[_-]|xxxx|\*\*\*\*|changeme|dummy|sample|test[_-]?key|<[^>]*>|\$\{`)
+
+// ScanSecrets: HTML صفحه و همهٔ اسکریپت‌های گرفته‌شده؛ خروجی بدون مقدار خام
+func ScanSecrets(html string, scripts map[string]string, pageURL, siteID string) []models.SecretDoc {
+	var out []models.SecretDoc
+	seen := map[string]struct{}{}
+	scanSecretsIn("html", pageURL, html, siteID, pageURL, seen, &out)
+
+	keys := make([]string, 0, len(scripts))
+	for k := range scripts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		srcURL, srcType := normalizeSourceURL(pageURL, k)
+		scanSecretsIn(srcType, srcURL, scripts[k], siteID, pageURL, seen, &out)
+	}
+	return out
+}
+
+// secretHit: یک match قبل از ساخت سند (برای ماسک کردن همهٔ مقدارهای داخل context)
+type secretHit struct {
+	rule       *secretRule
+	start, end int
+	val        string
+	masked     string
+	entropy    float64
+}
+
+func scanSecretsIn(srcType, srcURL, text, siteID, pageURL string, seen map[string]struct{}, out *[]models.SecretDoc) {
+	if text == "" {
+		return
+	}
+	var hits []secretHit
+	for i := range secretRules {
+		rule := &secretRules[i]
+		for _, loc := range rule.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*rule.group], loc[2*rule.group+1]
+			if start < 0 {
+				continue
+			}
+			val := text[start:end]
+			entropy := shannonEntropy(val)
+			if rule.minEntropy > 0 && (entropy < rule.minEntropy || secretPlaceholderRe.MatchString(val)) {
+				continue
+			}
+			// الگوی عمومی روی مقداری که قاعدهٔ مشخص‌تری پیدا کرده تکرار نشود
+			if rule.ID == "generic_secret" && overlapsHit(hits, start, end) {
+				continue
+			}
+			masked := val
+			if !rule.plain {
+				masked = maskSecretValue(val)
+			}
+			hits = append(hits, secretHit{rule: rule, start: start, end: end, val: val, masked: masked, entropy: entropy})
+		}
+	}
+
+	for _, h := range hits {
+		sum := sha256.Sum256([]byte(h.val))
+		valueHash := hex.EncodeToString(sum[:])
+		line, col := lineCol(text, h.start)
+		sig := secretSig(siteID, pageURL, srcURL, h.rule.ID, line, col, valueHash)
+		if _, dup := seen[sig]; dup {
+			continue
+		}
+		seen[sig] = struct{}{}
+
+		*out = append(*out, models.SecretDoc{
+			Sig:         sig,
+			SiteID:      siteID,
+			PageURL:     pageURL,
+			SourceType:  srcType,
+			SourceURL:   srcURL,
+			RuleID:      h.rule.ID,
+			Description: h.rule.Description,
+			Severity:    h.rule.Severity,
+			Masked:      h.masked,
+			ValueHash:   valueHash,
+			Entropy:     math.Round(h.entropy*100) / 100,
+			Line:        line,
+			Col:         col,
+			Context:     secretContext(text, h.start, h.end, hits),
+		})
+	}
+}
+
+func overlapsHit(hits []secretHit, start, end int) bool {
+	for _, h := range hits {
+		if start < h.end && h.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// secretSig: مثل sinkSig ولی به‌جای snippet، hash مقدار
+func secretSig(siteID, pageURL, sourceURL, ruleID string, line, col int, valueHash string) string {
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + pageURL + "\x1f" + sourceURL + "\x1f" + ruleID +
+		"\x1f" + fmt.Sprintf("%d:%d", line, col) + "\x1f" + valueHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// maskSecretValue: چهار نویسهٔ اول و آخر فقط برای مقدار ۲۴+ نویسه‌ای؛ کوتاه‌تر حداکثر ۴ نویسه (۱۲ و کمتر فقط دو نویسهٔ اول)
+func maskSecretValue(v string) string {
+	v = strings.ReplaceAll(v, "\n", " ")
+	if len(v) <= 12 {
+		if len(v) <= 2 {
+			return strings.Repeat("*", len(v))
+		}
+		return v[:2] + strings.Repeat("*", len(v)-2)
+	}
+	if len(v) < 24 {
+		return v[:2] + strings.Repeat("*", len(v)-4) + v[len(v)-2:]
+	}
+	stars := min(len(v)-8, 16)
+	return v[:4] + strings.Repeat("*", stars) + v[len(v)-4:]
+}
+
+// secretContext: حدود 60 نویسه دو طرف مقدار؛ هر مقدار دیگری که در این بازه افتاده هم ماسک می‌شود
+func secretContext(text string, start, end int, hits []secretHit) string {
+	from := max(0, start-60)
+	to := end + 60
+	if to > len(text) {
+		to = len(text)
+	}
+	inWin := make([]secretHit, 0, 4)
+	for _, h := range hits {
+		if h.start < to && from < h.end {
+			inWin = append(inWin, h)
+		}
+	}
+	sort.Slice(inWin, func(i, j int) bool { return inWin[i].start < inWin[j].start })
+
+	var b strings.Builder
+	pos := from
+	for _, h := range inWin {
+		if h.end <= pos {
+			continue // داخل مقدار قبلی
+		}
+		if h.start > pos {
+			b.WriteString(text[pos:h.start])
+		}
+		if h.start >= pos && h.end <= to {
+			b.WriteString(h.masked)
+		} else {
+			b.WriteString("****") // بریده‌شده در لبهٔ بازه یا هم‌پوشان
+		}
+		pos = min(h.end, to)
+	}
+	if pos < to {
+		b.WriteString(text[pos:to])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	freq := map[rune]float64{}
+	n := 0.0
+	for _, r := range s {
+		freq[r]++
+		n++
+	}
+	var h float64
+	for _, c := range freq {
+		p := c / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// PersistSecrets: upsert بر اساس sig؛ first_seen فقط بار اول
+func PersistSecrets(ctx context.Context, secrets []models.SecretDoc) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	now := time.Now()
+	bw := make([]mongo.WriteModel, 0, len(secrets))
+	for _, s := range secrets {
+		if s.Sig == "" || s.SiteID == "" {
+			continue
+		}
+		origin := s.Origin
+		if origin == "" {
+			origin = models.OriginScan
+		}
+		bw = append(bw, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sig": s.Sig}).
+			SetUpdate(bson.M{
+				"$setOnInsert": bson.M{
+					"sig":         s.Sig,
+					"site_id":     s.SiteID,
+					"page_url":    s.PageURL,
+					"source_type": s.SourceType,
+					"source_url":  s.SourceURL,
+					"rule_id":     s.RuleID,
+					"value_hash":  s.ValueHash,
+					"line":        s.Line,
+					"col":         s.Col,
+					"first_seen":  now,
+				},
+				"$set": bson.M{
+					"description": s.Description,
+					"severity":    s.Severity,
+					"masked":      s.Masked,
+					"entropy":     s.Entropy,
+					"context":     s.Context,
+					"origin":      origin,
+					"last_seen":   now,
+				},
+				"$inc": bson.M{"hits": 1},
+			}).
+			SetUpsert(true))
+	}
+	if len(bw) == 0 {
+		return nil
+	}
+	_, err := models.SecretsColl().BulkWrite(ctx, bw, mopts.BulkWrite().SetOrdered(false))
+	return err
+}
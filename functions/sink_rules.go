@@ -0,0 +1,312 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules/sinks.yaml
+var builtinSinkRulesYAML []byte
+
+var (
+	ruleIDRe       = regexp.MustCompile(`^[A-Za-z][\w.-]{0,63}$`)
+	ruleCWERe      = regexp.MustCompile(`^CWE-\d{1,5}$`)
+	ruleOWASPRe    = regexp.MustCompile(`^A\d{2}:\d{4}$`)
+	ruleInlineFlag = regexp.MustCompile(`\(\?[a-zA-Z]`) // (?i) و ... در RegExp مرورگر نیست
+	ruleHookTarget = regexp.MustCompile(`^[A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)*$`)
+)
+
+var (
+	ruleSeverities = map[string]bool{models.SeverityInfo: true, models.SeverityLow: true, models.SeverityMedium: true, models.SeverityHigh: true, models.SeverityCritical: true}
+	ruleScopes     = map[string]bool{"html": true, "inline": true, "script": true}
+	ruleHooks      = map[string]bool{"call": true, "setter": true, "attribute": true, "listener": true}
+)
+
+// sinkRule: قانون آماده‌شده (regex کامپایل‌شده + دامنه)
+type sinkRule struct {
+	models.SinkRule
+	re      *regexp.Regexp
+	applies map[string]bool
+}
+
+// SinkRuleSet: قوانین فعال به ترتیب تعریف؛ Errors خطاهای فایل کاربر (که نادیده گرفته شد)
+type SinkRuleSet struct {
+	Rules    []models.SinkRule `json:"rules"`
+	UserFile string            `json:"user_file,omitempty"`
+	Errors   []string          `json:"errors,omitempty"`
+
+	compiled []sinkRule
+	byKind   map[string]*models.SinkRule
+}
+
+var (
+	sinkRulesMu  sync.Mutex
+	sinkRulesSet *SinkRuleSet
+)
+
+// SinkRules: قوانین فعال (بار اول از built-in + SINK_RULES_FILE ساخته و cache می‌شود)
+func SinkRules() *SinkRuleSet {
+	sinkRulesMu.Lock()
+	defer sinkRulesMu.Unlock()
+	if sinkRulesSet == nil {
+		sinkRulesSet = loadSinkRules(os.Getenv("SINK_RULES_FILE"))
+	}
+	return sinkRulesSet
+}
+
+// ReloadSinkRules: خواندن دوبارهٔ فایل کاربر بدون restart
+func ReloadSinkRules() *SinkRuleSet {
+	set := loadSinkRules(os.Getenv("SINK_RULES_FILE"))
+	sinkRulesMu.Lock()
+	sinkRulesSet = set
+	sinkRulesMu.Unlock()
+	return set
+}
+
+func loadSinkRules(userFile string) *SinkRuleSet {
+	builtin, errs := ParseSinkRules(builtinSinkRulesYAML, "builtin")
+	if len(errs) > 0 {
+		// قوانین داخلی همراه باینری‌اند؛ خطا یعنی باگ
+		panic("builtin sink rules: " + strings.Join(errs, "; "))
+	}
+	set := &SinkRuleSet{UserFile: userFile}
+	merged := builtin
+	if userFile != "" {
+		data, err := os.ReadFile(userFile)
+		if err != nil {
+			set.Errors = append(set.Errors, err.Error())
+		} else if user, errs := ParseSinkRules(data, "user"); len(errs) > 0 {
+			set.Errors = append(set.Errors, errs...)
+		} else {
+			merged = mergeSinkRules(builtin, user)
+		}
+		for _, e := range set.Errors {
+			log.Printf("[rules] %s: %s (using built-in rules)", userFile, e)
+		}
+	}
+
+	for _, r := range merged {
+		if r.Disabled {
+			continue
+		}
+		set.Rules = append(set.Rules, r)
+		set.compiled = append(set.compiled, compileSinkRule(r))
+	}
+	set.byKind = make(map[string]*models.SinkRule, len(set.Rules))
+	for i := range set.Rules {
+		if _, ok := set.byKind[set.Rules[i].Kind]; !ok {
+			set.byKind[set.Rules[i].Kind] = &set.Rules[i]
+		}
+	}
+	return set
+}
+
+// ParseSinkRules: خواندن و اعتبارسنجی یک فایل قوانین ({rules: [...]})؛ همهٔ خطاها برگردانده می‌شوند
+func ParseSinkRules(data []byte, source string) ([]models.SinkRule, []string) {
+	var doc struct {
+		Rules []models.SinkRule `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, []string{"yaml: " + err.Error()}
+	}
+
+	var errs []string
+	seen := map[string]bool{}
+	for i := range doc.Rules {
+		r := &doc.Rules[i]
+		r.Source = source
+		if r.Kind == "" {
+			r.Kind = r.ID
+		}
+		if r.Severity == "" {
+			r.Severity = models.SeverityInfo
+		}
+		for _, e := range validateSinkRule(r) {
+			errs = append(errs, fmt.Sprintf("rule #%d (%s): %s", i+1, r.ID, e))
+		}
+		if seen[r.ID] {
+			errs = append(errs, fmt.Sprintf("rule #%d: duplicate id %q", i+1, r.ID))
+		}
+		seen[r.ID] = true
+	}
+	return doc.Rules, errs
+}
+
+func validateSinkRule(r *models.SinkRule) []string {
+	var errs []string
+	if !ruleIDRe.MatchString(r.ID) {
+		errs = append(errs, "id must start with a letter and contain only letters, digits, _ . -")
+	}
+	if r.Disabled {
+		return errs // فقط برای خاموش کردن قانون داخلی
+	}
+	if !ruleSeverities[r.Severity] {
+		errs = append(errs, "severity must be one of info, low, medium, high, critical")
+	}
+	if r.CWE != "" && !ruleCWERe.MatchString(r.CWE) {
+		errs = append(errs, `cwe must look like "CWE-79"`)
+	}
+	if r.OWASP != "" && !ruleOWASPRe.MatchString(r.OWASP) {
+		errs = append(errs, `owasp must look like "A03:2021"`)
+	}
+	if r.Regex == "" && len(r.Runtime) == 0 {
+		errs = append(errs, "regex or runtime is required")
+	}
+	if r.Taint && len(r.Runtime) == 0 {
+		errs = append(errs, "taint requires runtime hooks")
+	}
+	if r.Regex != "" {
+		if ruleInlineFlag.MatchString(r.Regex) {
+			errs = append(errs, "inline flags like (?i) are not supported by the in-page scanner; use flags")
+		}
+		if r.Flags != "" && r.Flags != "i" {
+			errs = append(errs, `flags may only be "i"`)
+		}
+		if _, err := regexp.Compile(goRuleRegex(r)); err != nil {
+			errs = append(errs, "regex: "+err.Error())
+		}
+		if len(r.AppliesTo) == 0 {
+			errs = append(errs, "applies_to is required with regex")
+		}
+	}
+	for _, s := range r.AppliesTo {
+		if !ruleScopes[s] {
+			errs = append(errs, fmt.Sprintf("applies_to %q must be html, inline or script", s))
+		}
+	}
+	for j, h := range r.Runtime {
+		if !ruleHooks[h.Hook] {
+			errs = append(errs, fmt.Sprintf("runtime[%d]: hook must be call, setter, attribute or listener", j))
+		}
+		switch h.Hook {
+		case "call", "setter":
+			if !ruleHookTarget.MatchString(h.Target) {
+				errs = append(errs, fmt.Sprintf("runtime[%d]: target must be a property path like window.eval", j))
+			}
+		case "attribute":
+			if _, err := regexp.Compile("(?i)" + h.Target); err != nil || h.Target == "" {
+				errs = append(errs, fmt.Sprintf("runtime[%d]: target must be an attribute-name regex", j))
+			}
+		case "listener":
+			if h.Target == "" {
+				errs = append(errs, fmt.Sprintf("runtime[%d]: target must be an event type", j))
+			}
+		}
+	}
+	return errs
+}
+
+// mergeSinkRules: قانون کاربر با id موجود جایگزین می‌شود، بقیه به انتها اضافه می‌شوند
+func mergeSinkRules(base, user []models.SinkRule) []models.SinkRule {
+	out := append([]models.SinkRule(nil), base...)
+	idx := make(map[string]int, len(out))
+	for i, r := range out {
+		idx[r.ID] = i
+	}
+	for _, r := range user {
+		if i, ok := idx[r.ID]; ok {
+			out[i] = r
+			continue
+		}
+		idx[r.ID] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+func goRuleRegex(r *models.SinkRule) string {
+	if r.Flags == "i" {
+		return "(?i)" + r.Regex
+	}
+	return r.Regex
+}
+
+func compileSinkRule(r models.SinkRule) sinkRule {
+	c := sinkRule{SinkRule: r, applies: map[string]bool{}}
+	if r.Regex != "" {
+		c.re = regexp.MustCompile(goRuleRegex(&r))
+	}
+	for _, s := range r.AppliesTo {
+		c.applies[s] = true
+	}
+	return c
+}
+
+// scanRules: قوانین regex دار برای یک نوع منبع (html | inline | script)
+func (s *SinkRuleSet) scanRules(scope string) []sinkRule {
+	var out []sinkRule
+	for _, r := range s.compiled {
+		if r.re != nil && r.applies[scope] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ruleScope: نوع منبع SinkDoc → دامنهٔ قانون (blob/data/dynamic هم کد اسکریپت‌اند)
+func ruleScope(sourceType string) string {
+	switch sourceType {
+	case "html", "inline":
+		return sourceType
+	}
+	return "script"
+}
+
+// jsPatterns: قوانین regex برای اسکنر درون صفحه
+func (s *SinkRuleSet) jsPatterns() []map[string]any {
+	var out []map[string]any
+	for _, r := range s.compiled {
+		if r.re == nil {
+			continue
+		}
+		out = append(out, map[string]any{"kind": r.Kind, "src": r.Regex, "flags": r.Flags, "applies": r.AppliesTo})
+	}
+	return out
+}
+
+// runtimeHooks: hookهای همهٔ قوانین برای اسکریپت اینسترومنتیشن
+func (s *SinkRuleSet) runtimeHooks() []map[string]any {
+	var out []map[string]any
+	for _, r := range s.compiled {
+		for _, h := range r.Runtime {
+			m := map[string]any{"kind": r.Kind, "hook": h.Hook, "target": h.Target, "snippet_arg": h.SnippetArg, "string_only": h.StringOnly, "taint": r.Taint}
+			if h.ArgEquals != nil {
+				m["arg_equals"] = map[string]any{"index": h.ArgEquals.Index, "value": h.ArgEquals.Value}
+			}
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// taintKind: آیا قانون این kind در taint tracking شرکت می‌کند
+func (s *SinkRuleSet) taintKind(kind string) bool {
+	r := s.byKind[kind]
+	return r != nil && r.Taint
+}
+
+// classifySink: severity/CWE/OWASP از قانون هم‌kind، confidence از نوع اسکنر (taint دست نمی‌خورد)
+func classifySink(s *models.SinkDoc) {
+	if r := SinkRules().byKind[s.Kind]; r != nil {
+		s.Severity, s.CWE, s.OWASP = r.Severity, r.CWE, r.OWASP
+	}
+	if s.Severity == "" {
+		s.Severity = models.SeverityInfo // kind قدیمی یا قانون حذف‌شده
+	}
+	if s.Confidence == "" {
+		s.Confidence = models.ConfidenceStatic
+		if s.SourceType == "runtime" {
+			s.Confidence = models.ConfidenceRuntime
+		}
+	}
+}
@@ -7,6 +7,9 @@ func normalizeSourceURL(pageURL, u string) (string, string) {
 	if u == "" || u == "<anonymous>" {
 		return pageURL + "#inline", "inline"
 	}
+	if strings.HasPrefix(u, "inline:") { // کلید اسکریپت inline در CollectScripts
+		return pageURL + "#inline", "inline"
+	}
 	if strings.HasPrefix(u, "blob:") {
 		return u, "blob"
 	}
@@ -3,6 +3,9 @@ package functions
 import (
 	"SiteChecker/models"
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/chromedp/cdproto/page"
@@ -10,50 +13,80 @@ import (
 )
 
 type sinkEntry struct {
-	Kind    string `json:"kind"`
-	File    string `json:"file"`
-	Line    int    `json:"line"`
-	Col     int    `json:"col"`
-	Func    string `json:"func"`
-	Snippet string `json:"snippet"`
-	Extra   any    `json:"extra"`
-	WhenMs  int64  `json:"when"`
+	Kind    string     `json:"kind"`
+	File    string     `json:"file"`
+	Line    int        `json:"line"`
+	Col     int        `json:"col"`
+	Func    string     `json:"func"`
+	Snippet string     `json:"snippet"`
+	WhenMs  int64      `json:"when"`
+	Taint   []taintHit `json:"taint"` // فقط وقتی marker یک source در آرگومان‌ها بود
+	Stack   string     `json:"stack"`
 }
 
-// ScanSinksRuntime: با اینسترومنتیشن قبل از لود، سینک‌ها را با فایل/خط/ستون ثبت می‌کند
-func ScanSinksRuntime(ctx context.Context, pageURL string) ([]models.SinkDoc, error) {
-	bctx, cancel := newBrowserCtx(ctx)
-	defer cancel()
-
-	// اسکریپتِ اینسترومنتیشن
-	instrument := `
-(function(){
+// اسکریپتِ اینسترومنتیشن؛ __HOOKS__ با hookهای SinkRules و __TAINT__ با markerهای source پر می‌شود
+const sinkInstrumentJS = `
+(function(hooks, markers){
   try {
     if (window.__sinkLog) return;
     window.__sinkLog = [];
+    const docURL = location.href.split("#")[0];
+    const _pm = window.postMessage;
 
     function push(e){ try{ window.__sinkLog.push(e); }catch(_){} }
 
-    // استخراج callsite از stack
-    function callsite(skip){
+    // --- taint: کاشت marker در sourceها (URL و referrer از سمت Go) ---
+    const taint = Object.keys(markers).map(src => [src, markers[src]]);
+    try{ if (markers["window.name"] && !window.name) window.name = markers["window.name"]; }catch(_){}
+    try{ if (markers["document.cookie"]) document.cookie = "sc_taint=" + markers["document.cookie"] + "; path=/"; }catch(_){}
+    try{ if (markers["localStorage"]) localStorage.setItem("sc_taint", markers["localStorage"]); }catch(_){}
+    try{ if (markers["sessionStorage"]) sessionStorage.setItem("sc_taint", markers["sessionStorage"]); }catch(_){}
+    // بعد از لود از Go صدا زده می‌شود؛ postMessage اصلی تا خودش sink حساب نشود
+    window.__scSeedMessage = function(){
+      try{
+        if (!markers.postMessage) return;
+        _pm.call(window, markers.postMessage, "*");
+        _pm.call(window, {data: markers.postMessage}, "*");
+      }catch(_){}
+    };
+    function findTaint(args){
+      if (!taint.length || !args) return null;
+      let out = null;
+      for (let i = 0; i < args.length; i++){
+        const x = args[i];
+        let s = null;
+        if (typeof x === "string") s = x;
+        else if (x && (x instanceof URL || (window.TrustedHTML && x instanceof TrustedHTML) || (window.TrustedScript && x instanceof TrustedScript))) s = String(x);
+        if (!s) continue;
+        for (const [src, mk] of taint){
+          const at = s.indexOf(mk);
+          if (at !== -1) (out = out || []).push({source:src, value:s.slice(Math.max(0, at-100), at+mk.length+100)});
+        }
+      }
+      return out;
+    }
+    function stackText(){
+      try{
+        return (new Error().stack||"").split("\n").slice(1)
+          .filter(l => l.indexOf("__sc_") === -1).slice(0, 10).map(l => l.trim()).join("\n");
+      }catch(_){ return ""; }
+    }
+
+    // استخراج callsite از stack (فریم‌های اسکریپت‌های خودمان __sc_* رد می‌شوند)
+    function callsite(){
       try{
-        const err = new Error();
-        const st = (err.stack||"").split("\n").slice(1+(skip||0));
+        const st = (new Error().stack||"").split("\n").slice(1);
         for (const ln of st){
-          // شکل‌های رایج: "at func (https://.../app.js:123:45)" یا "at https://.../app.js:123:45"
+          // "at func (https://.../app.js:123:45)" یا "at https://.../app.js:123:45"
           const m = ln.match(/at\s+(?:(.*?)\s+\()?(.*?):(\d+):(\d+)\)?/);
-          if (m){
-            const func = (m[1]||"").trim();
-            const file = (m[2]||"").trim();
-            const line = parseInt(m[3]||"0",10);
-            const col  = parseInt(m[4]||"0",10);
-            // فریم‌های خودمان را رد کن
-            if (file.includes("extensions::") || file.startsWith("chrome-extension:")) continue;
-            return {func, file, line, col};
-          }
+          if (!m) continue;
+          const file = (m[2]||"").trim();
+          if (file.indexOf("__sc_") !== -1 || file.includes("extensions::") || file.startsWith("chrome-extension:")) continue;
+          // اسکریپت inline خود سند (URL سند marker دارد)
+          return {func:(m[1]||"").trim(), file:file===docURL ? "" : file, line:parseInt(m[3]||"0",10), col:parseInt(m[4]||"0",10)};
         }
-      }catch(e){}
-      return {func:"", file:(location&&location.href)||"", line:0, col:0};
+      }catch(_){}
+      return {func:"", file:"", line:0, col:0};
     }
     function takeSnippet(x){
       try{
@@ -61,220 +94,305 @@ func ScanSinksRuntime(ctx context.Context, pageURL string) ([]models.SinkDoc, er
         return s.length>200 ? s.slice(0,200) : s;
       }catch(_){ return ""; }
     }
-    function log(kind, info, skip){
-      const cs = callsite((skip||0)+1);
-      push({
-        kind: kind,
-        file: cs.file,
-        line: cs.line,
-        col:  cs.col,
-        func: cs.func,
-        snippet: info && info.snippet ? takeSnippet(info.snippet) : (info==null?"":takeSnippet(info)),
-        extra: info && info.extra!==undefined ? info.extra : info,
-        when: Date.now()
-      });
-    }
-
-    // --- سینک‌ها ---
-
-    // eval
-    try{
-      const _eval = window.eval;
-      window.eval = function(s){ log("eval",{snippet:s},1); return _eval.apply(this, arguments); };
-    }catch(_){}
-
-    // new Function
-    try{
-      const _F = window.Function;
-      window.Function = function(){ log("newFunction",{snippet:Array.prototype.join.call(arguments,",")},1); return _F.apply(this, arguments); };
-      window.Function.prototype = _F.prototype;
-    }catch(_){}
-
-    // setTimeout / setInterval (رشته)
-    try{
-      const _st = window.setTimeout;
-      window.setTimeout = function(cb, t){ if (typeof cb==="string") log("setTimeoutStr",{snippet:cb},1); return _st.apply(this, arguments); };
-      const _si = window.setInterval;
-      window.setInterval = function(cb, t){ if (typeof cb==="string") log("setIntervalStr",{snippet:cb},1); return _si.apply(this, arguments); };
-    }catch(_){}
-
-    // document.write
-    try{
-      const _dw = document.write;
-      document.write = function(){ log("documentWrite", {extra:{argsLen:arguments.length}},1); return _dw.apply(this, arguments); };
-    }catch(_){}
-
-    // innerHTML setter
-    try{
-      const desc = Object.getOwnPropertyDescriptor(Element.prototype, "innerHTML");
-      if (desc && desc.set){
-        Object.defineProperty(Element.prototype, "innerHTML", {
-          configurable:true,
-          get: desc.get,
-          set: function(v){ log("innerHTML", {snippet:v},1); return desc.set.call(this, v); }
-        });
+    let internal = false; // کارهای خود اسکنر (iframe پیام خارجی) sink حساب نشوند
+    let active = null;    // handler پیامی که در حال اجراست
+    // args فقط برای hookهای قوانین taint: true پاس داده می‌شود
+    function log(kind, snippet, cs, args){
+      if (internal) return;
+      const e = {kind, file:cs.file, line:cs.line, col:cs.col, func:cs.func, snippet:takeSnippet(snippet), when:Date.now()};
+      const t = findTaint(args);
+      if (t){
+        e.taint = t; e.stack = stackText();
+        if (active && active.rec.reached.length < 20) active.rec.reached.push({kind, origin:active.origin});
       }
-    }catch(_){}
+      push(e);
+    }
 
-    // fetch
-    try{
-      const _fetch = window.fetch;
-      window.fetch = function(input, init){
-        const to = typeof input==="string" ? input : (input && input.url) || "";
-        log("fetch", {extra:{to: String(to)}}, 1);
-        return _fetch.apply(this, arguments);
+    // --- لیسنرهای message روی window: سورس handler و اینکه پیام origin خارجی به sink رسید ---
+    // قبل از hookهای قوانین نصب می‌شود تا آن‌ها listener اصلی (نه wrapper) را ببینند
+    const msgHandlers = window.__scMsgHandlers = [];
+    const msgWrapped = new WeakMap();
+    function fnSource(fn){
+      try{
+        const f = typeof fn === "function" ? fn : fn.handleEvent;
+        const s = Function.prototype.toString.call(f);
+        return s.length > 20000 ? s.slice(0, 20000) : s;
+      }catch(_){ return ""; }
+    }
+    function wrapMessage(listener, via, cs){
+      if (!listener || (typeof listener !== "function" && typeof listener.handleEvent !== "function")) return listener;
+      if (msgWrapped.has(listener)) return msgWrapped.get(listener);
+      const rec = {via, file:cs.file, line:cs.line, col:cs.col, func:cs.func, source:fnSource(listener), received:0, foreign:0, reached:[]};
+      msgHandlers.push(rec);
+      const w = function(ev){
+        rec.received++;
+        if (ev && ev.origin === "null") rec.foreign++;
+        const prev = active;
+        active = {rec, origin: ev ? String(ev.origin) : ""};
+        try{
+          return typeof listener === "function" ? listener.apply(this, arguments) : listener.handleEvent(ev);
+        } finally { active = prev; }
       };
-    }catch(_){}
-
-    // XHR
+      msgWrapped.set(listener, w);
+      return w;
+    }
     try{
-      const _open = XMLHttpRequest.prototype.open;
-      XMLHttpRequest.prototype.open = function(method, url, async){
-        const a = (async!==false);
-        log("XMLHttpRequest", {extra:{to:String(url), async:a}}, 1);
-        if (!a) log("syncXHR", {extra:{to:String(url)}}, 1);
-        return _open.apply(this, arguments);
+      const _addMsg = EventTarget.prototype.addEventListener, _rmMsg = EventTarget.prototype.removeEventListener;
+      EventTarget.prototype.addEventListener = function(type, listener){
+        if (String(type).toLowerCase() === "message" && (this === window || this == null)){
+          const args = Array.prototype.slice.call(arguments);
+          args[1] = wrapMessage(listener, "addEventListener", callsite());
+          return _addMsg.apply(this, args);
+        }
+        return _addMsg.apply(this, arguments);
       };
-    }catch(_){}
-
-    // Inline handlers
-    try{
-      const _setAttr = Element.prototype.setAttribute;
-      Element.prototype.setAttribute = function(name, value){
-        if (name && /^on[a-z]+$/i.test(name)){
-          log("inlineEventHandler",{snippet:value},1);
+      EventTarget.prototype.removeEventListener = function(type, listener){
+        if (String(type).toLowerCase() === "message" && listener && msgWrapped.has(listener)){
+          const args = Array.prototype.slice.call(arguments);
+          args[1] = msgWrapped.get(listener);
+          return _rmMsg.apply(this, args);
         }
-        return _setAttr.apply(this, arguments);
+        return _rmMsg.apply(this, arguments);
       };
     }catch(_){}
-
-    // direct DOM
     try{
-      const api = ["getElementById","getElementsByClassName","querySelector","querySelectorAll"];
-      for (const k of api){
-        const _fn = Document.prototype[k];
-        if (typeof _fn === "function"){
-          Document.prototype[k] = function(){ log("directDOM",{extra:{api:k}},1); return _fn.apply(this, arguments); };
-        }
+      let desc = null;
+      for (let o = window; o && !desc; o = Object.getPrototypeOf(o)) desc = Object.getOwnPropertyDescriptor(o, "onmessage");
+      if (desc && desc.set){
+        let current = null;
+        Object.defineProperty(window, "onmessage", {
+          configurable:true,
+          enumerable:desc.enumerable,
+          get: function(){ return current; },
+          set: function(v){
+            current = v;
+            desc.set.call(window, typeof v === "function" ? wrapMessage(v, "onmessage", callsite()) : v);
+          }
+        });
       }
     }catch(_){}
+    // پیام از iframe sandbox (origin = "null")؛ handlerی که origin را درست چک کند نباید به sink برسد
+    window.__scForeignMessage = function(){
+      if (!markers.postMessage) return;
+      internal = true;
+      try{
+        const m = JSON.stringify(markers.postMessage);
+        const f = document.createElement("iframe");
+        f.setAttribute("sandbox", "allow-scripts");
+        f.style.display = "none";
+        f.srcdoc = "<script>var m=" + m + ";parent.postMessage(m,'*');parent.postMessage({data:m},'*');" +
+          "parent.postMessage(JSON.stringify({data:m}),'*');<\/script>";
+        (document.body || document.documentElement).appendChild(f);
+      }catch(_){}
+      internal = false;
+    };
+    function resolve(path){
+      const parts = path.split(".");
+      let obj = window;
+      for (let i = parts[0]==="window" ? 1 : 0; i < parts.length-1; i++){
+        obj = obj[parts[i]];
+        if (obj == null) return null;
+      }
+      return {obj, prop:parts[parts.length-1]};
+    }
+    function pick(args, h){
+      const i = h.snippet_arg < 0 ? args.length + h.snippet_arg : (h.snippet_arg||0);
+      return args[i];
+    }
+    function matches(args, h){
+      if (h.string_only && typeof pick(args, h) !== "string") return false;
+      if (h.arg_equals && args[h.arg_equals.index] !== h.arg_equals.value) return false;
+      return true;
+    }
+    function group(kind){
+      const m = {};
+      for (const h of hooks) if (h.hook===kind) (m[h.target] = m[h.target] || []).push(h);
+      return m;
+    }
 
-    // --- postMessage: send + listen + recv ---
+    // call: wrap تابع
+    const calls = group("call");
+    for (const target in calls){
+      try{
+        const r = resolve(target); if (!r) continue;
+        const orig = r.obj[r.prop]; if (typeof orig !== "function") continue;
+        const list = calls[target];
+        const wrapped = function(){
+          const cs = callsite();
+          for (const h of list) if (matches(arguments, h)) log(h.kind, pick(arguments, h), cs, h.taint ? arguments : null);
+          return new.target ? Reflect.construct(orig, arguments, new.target) : orig.apply(this, arguments);
+        };
+        try{ wrapped.prototype = orig.prototype; }catch(_){}
+        r.obj[r.prop] = wrapped;
+      }catch(_){}
+    }
 
-    // send
-    try{
-      const _pm = window.postMessage;
-      window.postMessage = function(message, targetOrigin, transfer){
-        log("postMessageSend",{extra:{targetOrigin:String(targetOrigin||"")}},1);
-        return _pm.apply(this, arguments);
-      };
-    }catch(_){}
+    // setter: wrap setter ویژگی (accessor یا data property)
+    const setters = group("setter");
+    for (const target in setters){
+      try{
+        const r = resolve(target); if (!r) continue;
+        let desc = null;
+        for (let o = r.obj; o && !desc; o = Object.getPrototypeOf(o)) desc = Object.getOwnPropertyDescriptor(o, r.prop);
+        const list = setters[target];
+        let val = desc && "value" in desc ? desc.value : undefined;
+        Object.defineProperty(r.obj, r.prop, {
+          configurable:true,
+          enumerable: desc ? desc.enumerable : true,
+          get: desc && desc.get ? desc.get : function(){ return val; },
+          set: function(v){
+            const cs = callsite();
+            for (const h of list) log(h.kind, v, cs, h.taint ? [v] : null);
+            if (desc && desc.set) return desc.set.call(this, v);
+            val = v;
+          }
+        });
+      }catch(_){}
+    }
 
-    // listen: addEventListener("message", handler)
-    try{
-      const regMap = new WeakMap();
-      const _add = window.addEventListener;
-      window.addEventListener = function(type, handler, options){
-        if (String(type).toLowerCase()==="message" && typeof handler==="function"){
-          const cs = (function(){ const c=callsite(1); return c; })();
-          regMap.set(handler, cs);
-          // wrap برای لاگ گرفتن هنگام دریافت
-          const wrapped = function(ev){
-            const meta = regMap.get(handler) || {};
-            push({
-              kind: "postMessageRecv",
-              file: meta.file || "",
-              line: meta.line || 0,
-              col:  meta.col  || 0,
-              func: (handler.name||meta.func||""),
-              snippet: "",
-              extra: {origin: String(ev.origin||"")},
-              when: Date.now()
-            });
-            return handler.apply(this, arguments);
-          };
-          return _add.call(this, type, wrapped, options);
-        }
-        return _add.apply(this, arguments);
-      };
-    }catch(_){}
+    // attribute: setAttribute با نام منطبق
+    const attrs = hooks.filter(h => h.hook==="attribute").map(h => ({h, re:new RegExp(h.target, "i")}));
+    if (attrs.length){
+      try{
+        const _setAttr = Element.prototype.setAttribute;
+        Element.prototype.setAttribute = function(name, value){
+          const cs = callsite();
+          for (const a of attrs) if (a.re.test(String(name))) log(a.h.kind, value, cs, a.h.taint ? [value] : null);
+          return _setAttr.apply(this, arguments);
+        };
+      }catch(_){}
+    }
 
-    // listen: window.onmessage = fn
-    try{
-      let _h = null;
-      const cs = {};
-      Object.defineProperty(window, "onmessage", {
-        configurable:true,
-        get(){ return _h; },
-        set(fn){
-          _h = fn;
-          const c = callsite(1);
-          cs.file = c.file; cs.line=c.line; cs.col=c.col; cs.func=c.func;
-          if (typeof fn==="function"){
-            const wrap = function(ev){
-              push({
-                kind:"postMessageRecv",
-                file: cs.file||"",
-                line: cs.line||0,
-                col:  cs.col||0,
-                func: (fn.name||cs.func||""),
-                snippet:"",
-                extra:{origin:String(ev.origin||"")},
-                when: Date.now()
-              });
-              return fn.apply(this, arguments);
-            };
-            return Object.defineProperty(this, "onmessage", {value:wrap, writable:true, configurable:true});
-          }
-        }
-      });
-      // ثبت محل لیسنر
-      (function(){
-        const c = callsite(1);
-        push({ kind:"postMessageListen", file:c.file, line:c.line, col:c.col, func:c.func, snippet:"", extra:null, when:Date.now() });
-      })();
-    }catch(_){}
+    // listener: addEventListener با نوع رویداد (محل ثبت لیسنر)
+    const listeners = hooks.filter(h => h.hook==="listener");
+    if (listeners.length){
+      try{
+        const _add = EventTarget.prototype.addEventListener;
+        EventTarget.prototype.addEventListener = function(type, listener){
+          const t = String(type).toLowerCase();
+          const cs = callsite();
+          for (const h of listeners) if (h.target.toLowerCase()===t) log(h.kind, listener, cs);
+          return _add.apply(this, arguments);
+        };
+      }catch(_){}
+    }
   } catch(_){}
-})();`
+})(__HOOKS__ || [], __TAINT__ || {});
+//# sourceURL=__sc_instrument__.js`
 
-	// تزریق قبل از هر document
-	if err := chromedp.Run(bctx, chromedp.ActionFunc(func(c context.Context) error {
-		_, err := page.AddScriptToEvaluateOnNewDocument(instrument).Do(c)
+// InstallSinkInstrumentation: تزریق hookهای runtime قوانین (و کاشت markerهای taint) قبل از هر document
+func InstallSinkInstrumentation(markers map[string]string) chromedp.Action {
+	hooks, _ := json.Marshal(SinkRules().runtimeHooks())
+	taint, _ := json.Marshal(markers)
+	js := strings.Replace(sinkInstrumentJS, "__HOOKS__", string(hooks), 1)
+	js = strings.Replace(js, "__TAINT__", string(taint), 1)
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := page.AddScriptToEvaluateOnNewDocument(js).Do(ctx)
 		return err
-	})); err != nil {
-		return nil, err
-	}
-
-	// ناوبری و کمی صبر
-	if err := chromedp.Run(bctx,
-		chromedp.Navigate(pageURL),
-		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(8*time.Second),
-	); err != nil {
-		return nil, err
-	}
+	})
+}
 
-	// خواندن لاگ‌ها
+// CollectRuntimeSinks: خواندن window.__sinkLog از تبی که اینسترومنتیشن در آن نصب شده؛
+// فراخوانی‌هایی که marker یک source را دیدند confidence=taint و یک finding taint_flow هم می‌گیرند
+func CollectRuntimeSinks(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, []models.FindingDoc, error) {
 	var entries []sinkEntry
-	if err := chromedp.Run(bctx, chromedp.EvaluateAsDevTools(`window.__sinkLog || []`, &entries)); err != nil {
-		return nil, err
+	if err := chromedp.Run(ctx, chromedp.EvaluateAsDevTools(`window.__sinkLog || []`, &entries)); err != nil {
+		return nil, nil, err
 	}
 
 	out := make([]models.SinkDoc, 0, len(entries))
+	var flows []models.FindingDoc
 	now := time.Now()
 	for _, e := range entries {
-		out = append(out, models.SinkDoc{
-			SiteID:     "",
+		srcURL, _ := normalizeSourceURL(pageURL, e.File)
+		s := models.SinkDoc{
+			SiteID:     siteID,
 			PageURL:    pageURL,
 			SourceType: "runtime",
-			SourceURL:  e.File,
+			SourceURL:  srcURL,
 			Kind:       e.Kind,
 			Line:       e.Line,
 			Col:        e.Col,
 			Func:       e.Func,
 			Snippet:    e.Snippet,
 			DetectedAt: now,
-		})
+		}
+		// فقط قوانین taint: true (sinkهای XSS) flow می‌سازند
+		if len(e.Taint) > 0 && SinkRules().taintKind(e.Kind) {
+			s.Confidence = models.ConfidenceTaint
+			classifySink(&s)
+			flows = append(flows, taintFlowFinding(s, e.Taint, e.Stack))
+		}
+		out = append(out, s)
+	}
+	return out, flows, nil
+}
+
+// ScanSinksRuntime: با اینسترومنتیشن قبل از لود، سینک‌ها را با فایل/خط/ستون ثبت می‌کند
+func ScanSinksRuntime(ctx context.Context, pageURL, siteID string) ([]models.SinkDoc, []models.FindingDoc, error) {
+	res, err := scanSinksInTab(ctx, pageURL, pageURL, siteID, false)
+	return res.Runtime, res.Flows, err
+}
+
+// tabSinks: خروجی scanSinksInTab
+type tabSinks struct {
+	Runtime  []models.SinkDoc
+	Static   []models.SinkDoc
+	Flows    []models.FindingDoc // taint_flow
+	Handlers []models.FindingDoc // postmessage_handler
+}
+
+// scanSinksInTab: یک تب با اینسترومنتیشن و markerهای taint باز می‌کند، صفحه را لود می‌کند و
+// سینک‌های runtime (و اگر withStatic، اسکن regex درون همان تب) را برمی‌گرداند
+func scanSinksInTab(ctx context.Context, rawURL, urlNorm, siteID string, withStatic bool) (tabSinks, error) {
+	var res tabSinks
+	bctx, cancel := newBrowserCtx(ctx, scanProxyFrom(ctx))
+	defer cancel()
+
+	markers := taintMarkers(siteID)
+	seedURL, referrer := taintSeedURL(rawURL, markers), taintReferrer(markers)
+	// پروکسی و پروفایل اسکن؛ لاگین قبل از نصب اینسترومنتیشن تا صفحات لاگین در taint قاطی نشوند
+	if err := chromedp.Run(bctx, scanTabPrep(bctx, rawURL, nil)...); err != nil {
+		return res, err
+	}
+	if err := chromedp.Run(bctx,
+		InstallSinkInstrumentation(markers),
+		InstallSourceURLHooks(),
+	); err != nil {
+		return res, err
+	}
+	// ناوبری با referrer (chromedp.Navigate آن را نمی‌گیرد)؛ RunResponse تا لود صبر می‌کند
+	if _, err := chromedp.RunResponse(bctx, chromedp.ActionFunc(func(c context.Context) error {
+		_, _, errorText, _, err := page.Navigate(seedURL).WithReferrer(referrer).Do(c)
+		if err == nil && errorText != "" {
+			err = errors.New("page load error " + errorText)
+		}
+		return err
+	})); err != nil {
+		return res, err
+	}
+	if err := chromedp.Run(bctx,
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(6*time.Second),
+		chromedp.Evaluate(`window.__scSeedMessage && window.__scSeedMessage()`, nil),
+		chromedp.Sleep(1*time.Second),
+		chromedp.Evaluate(`window.__scForeignMessage && window.__scForeignMessage()`, nil),
+		chromedp.Sleep(2*time.Second),
+	); err != nil {
+		return res, err
+	}
+
+	var err error
+	if res.Runtime, res.Flows, err = CollectRuntimeSinks(bctx, urlNorm, siteID); err != nil {
+		return res, err
+	}
+	if res.Handlers, err = CollectMessageHandlers(bctx, urlNorm, siteID); err != nil {
+		return res, err
+	}
+	if withStatic {
+		if res.Static, err = ScanSinks(bctx, urlNorm, siteID); err != nil {
+			return res, err
+		}
 	}
-	return out, nil
+	return res, nil
 }
@@ -0,0 +1,303 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// savePageSnapshot: یک سند جدید در page_snapshots برای این اسکن؛ شناسه را برمی‌گرداند
+func savePageSnapshot(ctx context.Context, resp *models.ScanResponse, siteID, urlNorm string, endpoints []string,
+	externals map[string]models.ExternalGroup, now time.Time) (string, error) {
+	snap := models.PageSnapshot{
+		ID:            primitive.NewObjectID().Hex(),
+		SiteID:        siteID,
+		URLNorm:       urlNorm,
+		URL:           resp.URL,
+		ScannedAt:     now,
+		Origin:        resp.Origin,
+		CrawlID:       resp.CrawlID,
+		ImportID:      resp.ImportID,
+		Title:         resp.Title,
+		Endpoints:     uniqueStrings(append([]string{}, endpoints...)),
+		Scripts:       snapshotScripts(urlNorm, resp.ScriptBodies, resp.AllScripts),
+		ExternalHosts: []string{},
+	}
+	if snap.Origin == "" {
+		snap.Origin = models.OriginScan
+	}
+	if resp.HTML != "" {
+		snap.HTMLHash, snap.HTMLSize = sha256Hex(resp.HTML), len(resp.HTML)
+	}
+	if snap.Endpoints == nil {
+		snap.Endpoints = []string{}
+	}
+	for _, eg := range externals {
+		snap.ExternalHosts = append(snap.ExternalHosts, eg.Hosts...)
+	}
+	snap.ExternalHosts = uniqueStrings(snap.ExternalHosts)
+	seenSecret := map[string]bool{}
+	for _, sec := range resp.Secrets {
+		if seenSecret[sec.ValueHash] {
+			continue
+		}
+		seenSecret[sec.ValueHash] = true
+		snap.Secrets = append(snap.Secrets, models.SnapshotSecret{
+			ValueHash: sec.ValueHash, RuleID: sec.RuleID, Severity: sec.Severity, Masked: sec.Masked, SourceURL: sec.SourceURL,
+		})
+	}
+	sort.Strings(snap.Endpoints)
+	sort.Strings(snap.ExternalHosts)
+
+	if _, err := models.PageSnapshotsColl().InsertOne(ctx, snap); err != nil {
+		return "", err
+	}
+	return snap.ID, nil
+}
+
+// snapshotScripts: hash محتوای هر اسکریپت اجراشده؛ URLهای بدون محتوا (مثلاً SaveScanResults) بدون hash
+func snapshotScripts(pageURL string, bodies map[string]string, urls []string) []models.SnapshotScript {
+	out := make([]models.SnapshotScript, 0, len(bodies)+len(urls))
+	seen := make(map[string]bool, len(bodies))
+	for key, code := range bodies {
+		u, srcType := normalizeSourceURL(pageURL, key)
+		s := models.SnapshotScript{URL: u, SHA256: sha256Hex(code), Size: len(code), Inline: srcType == "inline"}
+		if seen[s.URL+"\x1f"+s.SHA256] {
+			continue
+		}
+		seen[s.URL+"\x1f"+s.SHA256] = true
+		seen[s.URL] = true
+		out = append(out, s)
+	}
+	for _, u := range urls {
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, models.SnapshotScript{URL: u})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].URL != out[j].URL {
+			return out[i].URL < out[j].URL
+		}
+		return out[i].SHA256 < out[j].SHA256
+	})
+	return out
+}
+
+// snapshotSinkKey: مثل sinkSig ولی برای sinkهای استاتیک بدون line/col (با جابه‌جایی کد عوض نشود)؛
+// snippet sinkهای runtime مقدار واقعی آرگومان است و هر اجرا فرق می‌کند، پس آن‌ها با callsite کلید می‌خورند
+func snapshotSinkKey(s models.SinkDoc) string {
+	if s.SourceType == "runtime" {
+		return sha256Hex(fmt.Sprintf("%s\x1f%s\x1f%d:%d", s.Kind, s.SourceURL, s.Line, s.Col))[:16]
+	}
+	return sha256Hex(s.Kind + "\x1f" + s.SourceURL + "\x1f" + s.Snippet)[:16]
+}
+
+// AttachSnapshotSinks: sinkهای همین اسکن (بعد از مرحلهٔ sinks) روی snapshot
+func AttachSnapshotSinks(ctx context.Context, snapshotID string, sinks []models.SinkDoc) error {
+	if snapshotID == "" {
+		return nil
+	}
+	out := make([]models.SnapshotSink, 0, len(sinks))
+	seen := make(map[string]bool, len(sinks))
+	for _, s := range sinks {
+		classifySink(&s)
+		key := snapshotSinkKey(s)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, models.SnapshotSink{
+			Key:       key,
+			Sig:       sinkSig(s.SiteID, s.PageURL, s.SourceURL, s.Kind, s.Line, s.Col, s.Snippet),
+			Kind:      s.Kind,
+			SourceURL: s.SourceURL,
+			Line:      s.Line,
+			Severity:  s.Severity,
+		})
+	}
+	_, err := models.PageSnapshotsColl().UpdateByID(ctx, snapshotID, bson.M{"$set": bson.M{"sinks": out, "sinks_at": time.Now()}})
+	return err
+}
+
+// DiffSnapshots: تغییرات from → to
+func DiffSnapshots(from, to *models.PageSnapshot) models.SnapshotDiff {
+	d := models.SnapshotDiff{
+		From:        snapshotRef(from),
+		To:          snapshotRef(to),
+		HTMLChanged: from.HTMLHash != "" && to.HTMLHash != "" && from.HTMLHash != to.HTMLHash,
+	}
+	d.Endpoints.Added, d.Endpoints.Removed = diffStrings(from.Endpoints, to.Endpoints)
+	d.ExternalHosts.Added, d.ExternalHosts.Removed = diffStrings(from.ExternalHosts, to.ExternalHosts)
+	d.Scripts = diffScripts(from.Scripts, to.Scripts)
+
+	// اگر یکی از دو اسکن مرحلهٔ sinks نداشت، مقایسه معنی ندارد
+	d.SinksCompared = from.SinksAt != nil && to.SinksAt != nil
+	d.Sinks.Added, d.Sinks.Removed = []models.SnapshotSink{}, []models.SnapshotSink{}
+	if d.SinksCompared {
+		d.Sinks.Added, d.Sinks.Removed = diffSinks(from.Sinks, to.Sinks)
+	}
+	d.Secrets.Added, d.Secrets.Removed = diffSecrets(from.Secrets, to.Secrets)
+	return d
+}
+
+func snapshotRef(s *models.PageSnapshot) models.SnapshotRef {
+	return models.SnapshotRef{ID: s.ID, ScannedAt: s.ScannedAt, Origin: s.Origin, HTMLHash: s.HTMLHash}
+}
+
+// diffStrings: added = در b نه a، removed = در a نه b (مرتب)
+func diffStrings(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, x := range a {
+		inA[x] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, x := range b {
+		inB[x] = true
+	}
+	added, removed = []string{}, []string{}
+	for x := range inB {
+		if !inA[x] {
+			added = append(added, x)
+		}
+	}
+	for x := range inA {
+		if !inB[x] {
+			removed = append(removed, x)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// diffScripts: اسکریپت‌های خارجی با URL (و changed اگر hash عوض شد)، inlineها فقط با hash
+func diffScripts(a, b []models.SnapshotScript) models.ScriptsDiff {
+	d := models.ScriptsDiff{Added: []models.SnapshotScript{}, Removed: []models.SnapshotScript{}, Changed: []models.ScriptChange{}}
+	index := func(list []models.SnapshotScript) map[string]models.SnapshotScript {
+		m := make(map[string]models.SnapshotScript, len(list))
+		for _, s := range list {
+			k := s.URL
+			if s.Inline {
+				k = s.URL + "\x1f" + s.SHA256
+			}
+			m[k] = s
+		}
+		return m
+	}
+	ia, ib := index(a), index(b)
+	for k, s := range ib {
+		old, ok := ia[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, s)
+		case old.SHA256 != "" && s.SHA256 != "" && old.SHA256 != s.SHA256:
+			d.Changed = append(d.Changed, models.ScriptChange{URL: s.URL, FromSHA256: old.SHA256, ToSHA256: s.SHA256, FromSize: old.Size, ToSize: s.Size})
+		}
+	}
+	for k, s := range ia {
+		if _, ok := ib[k]; !ok {
+			d.Removed = append(d.Removed, s)
+		}
+	}
+	byURL := func(list []models.SnapshotScript) {
+		sort.Slice(list, func(i, j int) bool { return list[i].URL+list[i].SHA256 < list[j].URL+list[j].SHA256 })
+	}
+	byURL(d.Added)
+	byURL(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].URL < d.Changed[j].URL })
+	return d
+}
+
+func diffSinks(a, b []models.SnapshotSink) (added, removed []models.SnapshotSink) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s.Key] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s.Key] = true
+	}
+	added, removed = []models.SnapshotSink{}, []models.SnapshotSink{}
+	for _, s := range b {
+		if !inA[s.Key] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s.Key] {
+			removed = append(removed, s)
+		}
+	}
+	less := func(list []models.SnapshotSink) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].Kind != list[j].Kind {
+				return list[i].Kind < list[j].Kind
+			}
+			return strings.Compare(list[i].SourceURL, list[j].SourceURL) < 0
+		}
+	}
+	sort.SliceStable(added, less(added))
+	sort.SliceStable(removed, less(removed))
+	return added, removed
+}
+
+func diffSecrets(a, b []models.SnapshotSecret) (added, removed []models.SnapshotSecret) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s.ValueHash] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s.ValueHash] = true
+	}
+	added, removed = []models.SnapshotSecret{}, []models.SnapshotSecret{}
+	for _, s := range b {
+		if !inA[s.ValueHash] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s.ValueHash] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// LoadPageSnapshot: snapshot با شناسه
+func LoadPageSnapshot(ctx context.Context, id string) (*models.PageSnapshot, error) {
+	var snap models.PageSnapshot
+	if err := models.PageSnapshotsColl().FindOne(ctx, bson.M{"_id": id}).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// previousPageSnapshot: آخرین snapshot صفحه قبل از before؛ withSinks یعنی فقط اسکن‌هایی که مرحلهٔ sinks داشتند
+func previousPageSnapshot(ctx context.Context, urlNorm string, before time.Time, withSinks bool) (*models.PageSnapshot, error) {
+	filter := bson.M{"url_norm": urlNorm, "scanned_at": bson.M{"$lt": before}}
+	if withSinks {
+		filter["sinks_at"] = bson.M{"$exists": true}
+	}
+	var snap models.PageSnapshot
+	opts := mopts.FindOne().SetSort(bson.D{{Key: "scanned_at", Value: -1}})
+	if err := models.PageSnapshotsColl().FindOne(ctx, filter, opts).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
@@ -0,0 +1,179 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	sinksAt := time.Now()
+	base := func() *models.PageSnapshot {
+		return &models.PageSnapshot{
+			ID:            "from",
+			HTMLHash:      "h1",
+			Endpoints:     []string{"/api/a", "/api/b"},
+			ExternalHosts: []string{"cdn.example.com"},
+			Scripts: []models.SnapshotScript{
+				{URL: "https://a.com/app.js", SHA256: "s1", Size: 10},
+				{URL: "https://a.com/#inline", SHA256: "i1", Inline: true},
+			},
+			Sinks: []models.SnapshotSink{
+				{Key: "innerHTML|https://a.com/app.js|1:1", Kind: "innerHTML"},
+			},
+			SinksAt: &sinksAt,
+			Secrets: []models.SnapshotSecret{{ValueHash: "v1", RuleID: "aws"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(to *models.PageSnapshot)
+		check  func(t *testing.T, d models.SnapshotDiff)
+	}{
+		{
+			name:   "identical",
+			change: func(to *models.PageSnapshot) {},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if d.HTMLChanged {
+					t.Error("HTMLChanged = true")
+				}
+				if len(d.Endpoints.Added)+len(d.Endpoints.Removed)+len(d.ExternalHosts.Added)+len(d.ExternalHosts.Removed) != 0 {
+					t.Errorf("endpoints/hosts diff = %+v %+v", d.Endpoints, d.ExternalHosts)
+				}
+				if len(d.Scripts.Added)+len(d.Scripts.Removed)+len(d.Scripts.Changed) != 0 {
+					t.Errorf("scripts diff = %+v", d.Scripts)
+				}
+				if !d.SinksCompared || len(d.Sinks.Added)+len(d.Sinks.Removed) != 0 {
+					t.Errorf("sinks diff = %+v compared=%v", d.Sinks, d.SinksCompared)
+				}
+				if len(d.Secrets.Added)+len(d.Secrets.Removed) != 0 {
+					t.Errorf("secrets diff = %+v", d.Secrets)
+				}
+			},
+		},
+		{
+			name: "endpoints and hosts sorted",
+			change: func(to *models.PageSnapshot) {
+				to.Endpoints = []string{"/api/z", "/api/a", "/api/c"}
+				to.ExternalHosts = []string{"x.com", "cdn.example.com", "b.com"}
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if want := []string{"/api/c", "/api/z"}; !reflect.DeepEqual(d.Endpoints.Added, want) {
+					t.Errorf("Endpoints.Added = %v, want %v", d.Endpoints.Added, want)
+				}
+				if want := []string{"/api/b"}; !reflect.DeepEqual(d.Endpoints.Removed, want) {
+					t.Errorf("Endpoints.Removed = %v, want %v", d.Endpoints.Removed, want)
+				}
+				if want := []string{"b.com", "x.com"}; !reflect.DeepEqual(d.ExternalHosts.Added, want) {
+					t.Errorf("ExternalHosts.Added = %v, want %v", d.ExternalHosts.Added, want)
+				}
+			},
+		},
+		{
+			name: "html hash changed",
+			change: func(to *models.PageSnapshot) {
+				to.HTMLHash = "h2"
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if !d.HTMLChanged {
+					t.Error("HTMLChanged = false")
+				}
+			},
+		},
+		{
+			name: "missing html hash is not a change",
+			change: func(to *models.PageSnapshot) {
+				to.HTMLHash = ""
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if d.HTMLChanged {
+					t.Error("HTMLChanged = true")
+				}
+			},
+		},
+		{
+			name: "external script changed, inline script replaced",
+			change: func(to *models.PageSnapshot) {
+				to.Scripts = []models.SnapshotScript{
+					{URL: "https://a.com/app.js", SHA256: "s2", Size: 12},
+					{URL: "https://a.com/#inline", SHA256: "i2", Inline: true},
+					{URL: "https://a.com/new.js", SHA256: "n1"},
+				}
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				wantChanged := []models.ScriptChange{{URL: "https://a.com/app.js", FromSHA256: "s1", ToSHA256: "s2", FromSize: 10, ToSize: 12}}
+				if !reflect.DeepEqual(d.Scripts.Changed, wantChanged) {
+					t.Errorf("Scripts.Changed = %+v, want %+v", d.Scripts.Changed, wantChanged)
+				}
+				if len(d.Scripts.Added) != 2 || d.Scripts.Added[0].SHA256 != "i2" || d.Scripts.Added[1].URL != "https://a.com/new.js" {
+					t.Errorf("Scripts.Added = %+v", d.Scripts.Added)
+				}
+				if len(d.Scripts.Removed) != 1 || d.Scripts.Removed[0].SHA256 != "i1" {
+					t.Errorf("Scripts.Removed = %+v", d.Scripts.Removed)
+				}
+			},
+		},
+		{
+			name: "sinks by key",
+			change: func(to *models.PageSnapshot) {
+				to.Sinks = []models.SnapshotSink{
+					{Key: "eval|https://a.com/app.js|9:3", Kind: "eval"},
+				}
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if len(d.Sinks.Added) != 1 || d.Sinks.Added[0].Kind != "eval" {
+					t.Errorf("Sinks.Added = %+v", d.Sinks.Added)
+				}
+				if len(d.Sinks.Removed) != 1 || d.Sinks.Removed[0].Kind != "innerHTML" {
+					t.Errorf("Sinks.Removed = %+v", d.Sinks.Removed)
+				}
+			},
+		},
+		{
+			name: "sinks not compared without a sinks stage",
+			change: func(to *models.PageSnapshot) {
+				to.SinksAt = nil
+				to.Sinks = nil
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if d.SinksCompared {
+					t.Error("SinksCompared = true")
+				}
+				if d.Sinks.Added == nil || d.Sinks.Removed == nil || len(d.Sinks.Added)+len(d.Sinks.Removed) != 0 {
+					t.Errorf("Sinks = %+v, want empty non-nil lists", d.Sinks)
+				}
+			},
+		},
+		{
+			name: "secrets by value hash",
+			change: func(to *models.PageSnapshot) {
+				to.Secrets = []models.SnapshotSecret{
+					{ValueHash: "v1", RuleID: "aws", SourceURL: "https://a.com/moved.js"},
+					{ValueHash: "v2", RuleID: "stripe"},
+				}
+			},
+			check: func(t *testing.T, d models.SnapshotDiff) {
+				if len(d.Secrets.Added) != 1 || d.Secrets.Added[0].ValueHash != "v2" {
+					t.Errorf("Secrets.Added = %+v", d.Secrets.Added)
+				}
+				if len(d.Secrets.Removed) != 0 {
+					t.Errorf("Secrets.Removed = %+v", d.Secrets.Removed)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := base(), base()
+			to.ID = "to"
+			tt.change(to)
+			d := DiffSnapshots(from, to)
+			if d.From.ID != "from" || d.To.ID != "to" {
+				t.Errorf("refs = %+v → %+v", d.From, d.To)
+			}
+			tt.check(t, d)
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-sourcemap/sourcemap"
+)
+
+// کامنت استاندارد انتهای bundle (قالب قدیمی //@ هم هنوز دیده می‌شود)
+var sourceMapCommentRe = regexp.MustCompile(`(?m)^[ \t]*//[#@][ \t]*sourceMappingURL=([^\s'"]+)[ \t]*$`)
+
+type sourceMapRef struct {
+	ScriptURL string
+	MapURL    string // مطلق (http/https) یا data:
+	Via       string // debugger | comment | header
+}
+
+// sourceMapResult: خروجی تحلیل source mapهای یک صفحه
+type sourceMapResult struct {
+	Infos    []models.SourceMapInfo
+	Hints    endpointHints
+	Sinks    []models.SinkDoc
+	Findings []models.FindingDoc
+}
+
+// discoverSourceMaps: ارجاع هر اسکریپت به map خودش؛ اولویت با گزارش Chrome، بعد کامنت، بعد هدر SourceMap
+func discoverSourceMaps(pageURL string, scripts, reported map[string]string, requests []models.NetworkRequest) []sourceMapRef {
+	headerMaps := map[string]string{}
+	for _, nr := range requests {
+		if h := headerValue(nr.ResponseHeaders, "SourceMap"); h != "" {
+			headerMaps[nr.URL] = h
+		} else if h := headerValue(nr.ResponseHeaders, "X-SourceMap"); h != "" {
+			headerMaps[nr.URL] = h
+		}
+	}
+
+	keys := make([]string, 0, len(scripts))
+	for k := range scripts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var refs []sourceMapRef
+	seen := map[string]struct{}{}
+	for _, key := range keys {
+		raw, via := reported[key], "debugger"
+		if raw == "" {
+			if m := sourceMapCommentRe.FindAllStringSubmatch(scripts[key], -1); len(m) > 0 {
+				raw, via = m[len(m)-1][1], "comment"
+			}
+		}
+		if raw == "" {
+			raw, via = headerMaps[key], "header"
+		}
+		if raw == "" {
+			continue
+		}
+
+		mapURL := resolveSourceMapURL(pageURL, key, raw)
+		if mapURL == "" {
+			continue
+		}
+		dedup := mapURL
+		if strings.HasPrefix(mapURL, "data:") {
+			dedup = key
+		}
+		if _, ok := seen[dedup]; ok {
+			continue
+		}
+		seen[dedup] = struct{}{}
+		refs = append(refs, sourceMapRef{ScriptURL: key, MapURL: mapURL, Via: via})
+	}
+	return refs
+}
+
+// resolveSourceMapURL: آدرس map نسبت به خود اسکریپت (اسکریپت inline نسبت به صفحه)
+func resolveSourceMapURL(pageURL, scriptKey, raw string) string {
+	if strings.HasPrefix(raw, "data:") {
+		return raw
+	}
+	base := scriptKey
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = pageURL
+	}
+	bu, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u := bu.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// analyzeSourceMaps: mapها را می‌گیرد، سورس اصلی را بازسازی و روی آن استخراج مسیر و سینک اجرا می‌کند
+func analyzeSourceMaps(ctx context.Context, pageURL, siteID string, scripts, reported map[string]string, requests []models.NetworkRequest, timeoutSec int) sourceMapResult {
+	res := sourceMapResult{Hints: endpointHints{}}
+	refs := discoverSourceMaps(pageURL, scripts, reported, requests)
+	if limit := envInt("SOURCEMAP_MAX", 40); len(refs) > limit {
+		refs = refs[:limit]
+	}
+	if len(refs) == 0 {
+		return res
+	}
+
+	client := sourceMapClient(ctx, time.Duration(timeoutSec)*time.Second, pageURL)
+	maxBytes := int64(envInt("SOURCEMAP_MAX_BYTES", 20<<20))
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 4)
+	)
+	res.Infos = make([]models.SourceMapInfo, len(refs))
+	for i, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ref sourceMapRef) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			info := models.SourceMapInfo{ScriptURL: ref.ScriptURL, MapURL: ref.MapURL, Via: ref.Via}
+			if strings.HasPrefix(ref.MapURL, "data:") {
+				info.MapURL = "data:"
+			}
+
+			raw, err := loadSourceMap(ctx, client, sourceMapBaseHost(pageURL, ref.ScriptURL), ref.MapURL, maxBytes)
+			var sm *parsedSourceMap
+			if err == nil {
+				sm, err = parseSourceMap(ref, raw)
+			}
+			if err != nil {
+				info.Error = err.Error()
+				emitScanEvent(ctx, ScanEvent{Type: EvScriptError, Stage: "sourcemaps", URL: ref.MapURL, Error: err.Error()})
+				res.Infos[i] = info
+				return
+			}
+			info.Sources = len(sm.sources)
+			for _, s := range sm.sources {
+				if s.content != "" {
+					info.WithContent++
+				}
+			}
+			res.Infos[i] = info
+
+			hints, sinks := sm.extract(pageURL, siteID, scripts[ref.ScriptURL])
+			finding := sourceMapFinding(pageURL, siteID, ref, info)
+
+			mu.Lock()
+			res.Hints.merge(hints)
+			res.Sinks = append(res.Sinks, sinks...)
+			res.Findings = append(res.Findings, finding)
+			mu.Unlock()
+		}(i, ref)
+	}
+	wg.Wait()
+	return res
+}
+
+var errSourceMapBlocked = errors.New("source map url blocked")
+
+// sourceMapBaseHost: host اسکریپت (اسکریپت inline: host صفحه)
+func sourceMapBaseHost(pageURL, scriptKey string) string {
+	base := scriptKey
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = pageURL
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// checkSourceMapURL: آدرس map از محتوای سایت می‌آید؛ فقط http(s) روی host اسکریپت یا هم‌سایت آن.
+// IP داخلی را dialer کلاینت sourceMapClient هنگام اتصال رد می‌کند
+func checkSourceMapURL(baseHost string, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", errSourceMapBlocked, u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" || baseHost == "" || (host != baseHost && !sameETLDPlusOne(host, baseHost)) {
+		return fmt.Errorf("%w: host %s is not same-site with %s", errSourceMapBlocked, host, baseHost)
+	}
+	return nil
+}
+
+func internalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// hostIsInternal: host صفحه localhost یا IP داخلی است (اسکن اینترانت)
+func hostIsInternal(ctx context.Context, host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if internalIP(a.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceMapClient: کلاینت اسکن (با همان پروکسی) که اتصال مستقیم به IP داخلی را در Control خود dialer رد می‌کند؛
+// بررسی روی IP واقعی اتصال است پس DNS rebinding بین lookup و dial بی‌اثر است. اگر خود صفحه داخلی باشد
+// محدودیت IP برداشته می‌شود. اتصال به پروکسی اسکن آزاد است و host مقصد را خود پروکسی resolve می‌کند
+func sourceMapClient(ctx context.Context, timeout time.Duration, pageURL string) *http.Client {
+	c := ScanHTTPClient(ctx, timeout)
+	if u, err := url.Parse(pageURL); err == nil && hostIsInternal(ctx, u.Hostname()) {
+		return c
+	}
+	tr, ok := c.Transport.(*http.Transport)
+	if !ok {
+		tr = http.DefaultTransport.(*http.Transport)
+	}
+	tr = tr.Clone()
+
+	var proxies sync.Map // host:port پروکسی‌هایی که Proxy برگردانده
+	if base := tr.Proxy; base != nil {
+		tr.Proxy = func(r *http.Request) (*url.URL, error) {
+			pu, err := base(r)
+			if pu != nil {
+				proxies.Store(proxyDialAddr(pu), true)
+			}
+			return pu, err
+		}
+	}
+	plain := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	guarded := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+				return fmt.Errorf("%w: internal address %s", errSourceMapBlocked, host)
+			}
+			return nil
+		},
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := proxies.Load(addr); ok {
+			return plain.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+	c.Transport = tr
+	return c
+}
+
+// proxyDialAddr: همان host:port که Transport برای اتصال به پروکسی dial می‌کند
+func proxyDialAddr(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func loadSourceMap(ctx context.Context, client *http.Client, baseHost, mapURL string, maxBytes int64) ([]byte, error) {
+	if strings.HasPrefix(mapURL, "data:") {
+		return decodeDataURL(mapURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSourceMapURL(baseHost, req.URL); err != nil {
+		return nil, err
+	}
+	// redirect هم همان بررسی را می‌گذراند
+	c := *client
+	c.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checkSourceMapURL(baseHost, r.URL)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxBytes {
+		return nil, fmt.Errorf("source map larger than %d bytes", maxBytes)
+	}
+	return b, nil
+}
+
+// decodeDataURL: فقط حالت‌های رایج map درون‌خطی (base64 یا percent-encoded)
+func decodeDataURL(s string) ([]byte, error) {
+	comma := strings.IndexByte(s, ',')
+	if comma < 0 {
+		return nil, errors.New("malformed data url")
+	}
+	meta, data := s[len("data:"):comma], s[comma+1:]
+	if strings.HasSuffix(meta, ";base64") {
+		if b, err := base64.StdEncoding.DecodeString(data); err == nil {
+			return b, nil
+		}
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	d, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(d), nil
+}
+
+type originalSource struct {
+	name    string
+	content string
+}
+
+type parsedSourceMap struct {
+	ref      sourceMapRef
+	consumer *sourcemap.Consumer
+	sources  []originalSource
+}
+
+// rawSourceMap: فقط فیلدهایی که Consumer بیرون نمی‌دهد (فهرست sources و محتوا)
+type rawSourceMap struct {
+	SourceRoot     string    `json:"sourceRoot"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+	Sections       []struct {
+		Map *rawSourceMap `json:"map"`
+	} `json:"sections"`
+}
+
+func parseSourceMap(ref sourceMapRef, raw []byte) (*parsedSourceMap, error) {
+	// برای map درون‌خطی، مسیر sources نسبت به خود اسکریپت است
+	base := ref.MapURL
+	if strings.HasPrefix(base, "data:") {
+		base = ref.ScriptURL
+	}
+	// پیشوند ضد XSSI که بعضی سرورها جلوی map می‌گذارند
+	raw = bytes.TrimPrefix(bytes.TrimSpace(raw), []byte(")]}'"))
+	c, err := sourcemap.Parse(base, raw)
+	if err != nil {
+		return nil, err
+	}
+	var rm rawSourceMap
+	if err := json.Unmarshal(raw, &rm); err != nil {
+		return nil, err
+	}
+
+	sm := &parsedSourceMap{ref: ref, consumer: c}
+	var walk func(m *rawSourceMap)
+	walk = func(m *rawSourceMap) {
+		for i, s := range m.Sources {
+			src := originalSource{name: resolveSourceName(base, m.SourceRoot, s)}
+			if i < len(m.SourcesContent) && m.SourcesContent[i] != nil {
+				src.content = *m.SourcesContent[i]
+			}
+			sm.sources = append(sm.sources, src)
+		}
+		for _, sec := range m.Sections {
+			if sec.Map != nil {
+				walk(sec.Map)
+			}
+		}
+	}
+	walk(&rm)
+	return sm, nil
+}
+
+// resolveSourceName: نام نمایشی فایل اصلی (webpack:// و مانند آن دست‌نخورده می‌مانند)
+func resolveSourceName(base, root, name string) string {
+	if u, err := url.Parse(name); err == nil && u.IsAbs() {
+		return name
+	}
+	if root != "" {
+		name = strings.TrimSuffix(root, "/") + "/" + strings.TrimPrefix(name, "/")
+		if u, err := url.Parse(name); err == nil && u.IsAbs() {
+			return name
+		}
+	}
+	bu, err := url.Parse(base)
+	if err != nil || !bu.IsAbs() {
+		return name
+	}
+	ref, err := url.Parse(name)
+	if err != nil {
+		return name
+	}
+	return bu.ResolveReference(ref).String()
+}
+
+// isVendorSource: کد کتابخانه‌ها و runtime باندلر؛ مسیرهای آن‌ها endpoint سایت نیستند
+func isVendorSource(name string) bool {
+	return strings.Contains(name, "/node_modules/") ||
+		strings.Contains(name, "webpack/bootstrap") ||
+		strings.Contains(name, "(webpack)") ||
+		strings.HasPrefix(name, "webpack/runtime")
+}
+
+// extract: مسیرها از سورس‌های اصلی غیرکتابخانه‌ای، سینک‌ها با فایل/خط اصلی
+func (sm *parsedSourceMap) extract(pageURL, siteID, bundle string) (hints endpointHints, sinks []models.SinkDoc) {
+	hints = endpointHints{}
+	withContent := map[string]struct{}{}
+	for _, s := range sm.sources {
+		if s.content == "" || len(s.content) > 2<<20 {
+			continue
+		}
+		withContent[s.name] = struct{}{}
+		if !isVendorSource(s.name) {
+			// سورس اصلی ممکن است TS/JSX باشد؛ آن‌وقت فقط سهم regex می‌ماند
+			_ = extractEndpointsFromJS(s.content, hints)
+		}
+		scanScriptSinks("sourcemap", s.name, s.content, &sinks, siteID, pageURL)
+	}
+
+	// سورس‌هایی که محتوا ندارند: سینک‌های bundle را با mappings به فایل/خط اصلی برمی‌گردانیم
+	if bundle != "" && len(withContent) < len(sm.sources) {
+		var bundleSinks []models.SinkDoc
+		scanScriptSinks("script", sm.ref.ScriptURL, bundle, &bundleSinks, siteID, pageURL)
+		for _, s := range bundleSinks {
+			src, _, line, col, ok := sm.consumer.Source(s.Line, s.Col-1)
+			if !ok || src == "" || sm.consumer.SourceContent(src) != "" {
+				continue
+			}
+			s.SourceType = "sourcemap"
+			s.SourceURL = src
+			s.Line = line
+			s.Col = col + 1
+			sinks = append(sinks, s)
+		}
+	}
+
+	bundleURL := sm.ref.ScriptURL
+	if !strings.HasPrefix(bundleURL, "http://") && !strings.HasPrefix(bundleURL, "https://") {
+		bundleURL = pageURL + "#inline"
+	}
+	for i := range sinks {
+		sinks[i].BundleURL = bundleURL
+	}
+	return hints, sinks
+}
+
+// sourceMapFinding: map قابل دریافت یعنی کد اصلی (گاهی با کامنت‌ها و مسیرهای داخلی) عمومی است
+func sourceMapFinding(pageURL, siteID string, ref sourceMapRef, info models.SourceMapInfo) models.FindingDoc {
+	target := ref.MapURL
+	inline := strings.HasPrefix(target, "data:")
+	if inline {
+		// URL خود اسکریپت؛ inline:<id> بین اسکن‌ها ثابت نیست
+		target = ref.ScriptURL
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			target = pageURL + "#inline"
+		}
+	}
+	severity := models.SeverityLow
+	if info.WithContent > 0 {
+		severity = models.SeverityMedium
+	}
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + models.FindingExposedSourceMap + "\x1f" + target))
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   siteID,
+		PageURL:  pageURL,
+		Type:     models.FindingExposedSourceMap,
+		Severity: severity,
+		Title:    "Source map exposed for " + ref.ScriptURL,
+		URL:      target,
+		Details: map[string]any{
+			"script_url":   ref.ScriptURL,
+			"via":          ref.Via,
+			"inline":       inline,
+			"sources":      info.Sources,
+			"with_content": info.WithContent,
+		},
+	}
+}
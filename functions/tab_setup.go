@@ -0,0 +1,119 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// tabOptions: رهگیری درخواست‌های یک تب. هر تب فقط یک Fetch.enable دارد، پس احراز هویت پروکسی،
+// هدرهای احراز هویت و لیست مجاز hostها در یک listener جمع می‌شوند
+type tabOptions struct {
+	Proxy      *models.ProxyConfig
+	Headers    network.Headers // فقط روی درخواست‌های هم‌سایت با SiteID
+	SiteID     string
+	AllowHosts []string // اگر پر باشد هر درخواست به host خارج از آن fail می‌شود
+}
+
+// tabSetup: listener را یک‌بار روی تب ثبت می‌کند و اکشن‌های لازم قبل از Navigate را برمی‌گرداند
+func tabSetup(ctx context.Context, o tabOptions) []chromedp.Action {
+	var acts []chromedp.Action
+	if o.Proxy != nil && o.Proxy.IgnoreTLS {
+		acts = append(acts, security.SetIgnoreCertificateErrors(true))
+	}
+	proxyAuth := o.Proxy != nil && o.Proxy.Username != ""
+	if !proxyAuth && len(o.Headers) == 0 && len(o.AllowHosts) == 0 {
+		return acts
+	}
+
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				var host string
+				if u, err := url.Parse(e.Request.URL); err == nil {
+					host = u.Hostname()
+				}
+				switch {
+				case len(o.AllowHosts) > 0 && !hostAllowed(host, o.AllowHosts):
+					_ = chromedp.Run(ctx, fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient))
+				case len(o.Headers) > 0 && host != "" && sameETLDPlusOne(host, o.SiteID):
+					_ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID).WithHeaders(mergeHeaders(e.Request.Headers, o.Headers)))
+				default:
+					_ = chromedp.Run(ctx, fetch.ContinueRequest(e.RequestID))
+				}
+			}()
+		case *fetch.EventAuthRequired:
+			// Chromium رمز پروکسی را از URL نمی‌پذیرد؛ با Fetch.authRequired جواب می‌دهیم
+			resp := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			if proxyAuth && e.AuthChallenge != nil && e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				resp = &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: o.Proxy.Username,
+					Password: o.Proxy.Password,
+				}
+			}
+			go func() {
+				_ = chromedp.Run(ctx, fetch.ContinueWithAuth(e.RequestID, resp))
+			}()
+		}
+	})
+	return append(acts, fetch.Enable().WithHandleAuthRequests(proxyAuth))
+}
+
+// scanTabOptions: پروکسی و هدرهای پروفایل احراز هویت اسکن جاری (از ctx)
+func scanTabOptions(ctx context.Context, targetURL string) tabOptions {
+	o := tabOptions{Proxy: scanProxyFrom(ctx)}
+	if auth := scanAuthFrom(ctx); auth != nil {
+		o.Headers = network.Headers{}
+		applyAuthHeaders(o.Headers, auth)
+		o.SiteID, _, _ = PageKeys(targetURL)
+	}
+	return o
+}
+
+// scanTabPrep: تنظیم تب‌های جانبی اسکن (sinks، verify، prototype) و سپس کوکی/قدم‌های لاگین پروفایل؛
+// allow اگر پر باشد درخواست به hostهای دیگر fail می‌شود
+func scanTabPrep(ctx context.Context, targetURL string, allow []string) []chromedp.Action {
+	o := scanTabOptions(ctx, targetURL)
+	o.AllowHosts = allow
+	acts := tabSetup(ctx, o)
+	if auth := scanAuthFrom(ctx); auth != nil {
+		acts = append(acts, authActions(auth, targetURL)...)
+	}
+	return acts
+}
+
+// mergeHeaders: هدرهای درخواست با override (نام هدر حساس به حروف نیست)
+func mergeHeaders(orig, override network.Headers) []*fetch.HeaderEntry {
+	out := make([]*fetch.HeaderEntry, 0, len(orig)+len(override))
+	for k, v := range orig {
+		if _, ok := headerLookup(override, k); ok {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			out = append(out, &fetch.HeaderEntry{Name: k, Value: s})
+		}
+	}
+	for k, v := range override {
+		if s, ok := v.(string); ok {
+			out = append(out, &fetch.HeaderEntry{Name: k, Value: s})
+		}
+	}
+	return out
+}
+
+func headerLookup(h network.Headers, name string) (any, bool) {
+	for k, v := range h {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,91 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// منابع taint؛ هر کدام یک marker ثابت (به ازای سایت) می‌گیرد تا snippet و sig بین اسکن‌ها عوض نشود
+var taintSources = []string{
+	"location.hash", "location.search", "document.referrer", "window.name",
+	"document.cookie", "localStorage", "sessionStorage", "postMessage",
+}
+
+// taintMarkers: source → marker (فقط حروف/عدد تا در URL و HTML دست نخورد)
+func taintMarkers(siteID string) map[string]string {
+	out := make(map[string]string, len(taintSources))
+	for _, src := range taintSources {
+		sum := sha256.Sum256([]byte(siteID + "\x1f" + src))
+		out[src] = "sct" + hex.EncodeToString(sum[:5])
+	}
+	return out
+}
+
+// taintSeedURL: marker در query (sc_taint) و اگر fragment نداشت در hash
+func taintSeedURL(rawURL string, markers map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Set("sc_taint", markers["location.search"])
+	u.RawQuery = q.Encode()
+	if u.Fragment == "" { // hash routerها را خراب نکن
+		u.Fragment = markers["location.hash"]
+	}
+	return u.String()
+}
+
+// taintReferrer: marker در host تا با referrer-policy پیش‌فرض (فقط origin) هم برسد
+func taintReferrer(markers map[string]string) string {
+	return "https://" + markers["document.referrer"] + ".invalid/"
+}
+
+// taintHit: رسیدن marker یک source به آرگومان sink
+type taintHit struct {
+	Source string `json:"source"`
+	Value  string `json:"value"` // اطراف marker
+}
+
+// taintFlowFinding: finding جریان source → sink؛ sink_sig به SinkDoc همان فراخوانی اشاره می‌کند
+func taintFlowFinding(s models.SinkDoc, hits []taintHit, stack string) models.FindingDoc {
+	sources := make([]string, 0, len(hits))
+	for _, h := range hits {
+		sources = append(sources, h.Source)
+	}
+	severity := s.Severity
+	if models.SeverityRank(severity) < models.SeverityRank(models.SeverityMedium) {
+		// داده قابل کنترل مهاجم به sink XSS (قانون taint: true) رسیده؛ حتی sink کم‌خطر هم حداقل medium است
+		severity = models.SeverityMedium
+	}
+	target := fmt.Sprintf("%s:%d:%d", s.SourceURL, s.Line, s.Col)
+	sum := sha256.Sum256([]byte(s.SiteID + "\x1f" + models.FindingTaintFlow + "\x1f" + s.PageURL + "\x1f" +
+		strings.Join(sources, ",") + "\x1f" + s.Kind + "\x1f" + target))
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   s.SiteID,
+		PageURL:  s.PageURL,
+		Type:     models.FindingTaintFlow,
+		Severity: severity,
+		Title:    fmt.Sprintf("%s reaches %s", strings.Join(sources, ", "), s.Kind),
+		URL:      s.SourceURL,
+		Details: map[string]any{
+			"sources":    sources,
+			"sink":       s.Kind,
+			"sink_sig":   sinkSig(s.SiteID, s.PageURL, s.SourceURL, s.Kind, s.Line, s.Col, s.Snippet),
+			"cwe":        s.CWE,
+			"owasp":      s.OWASP,
+			"value":      hits[0].Value,
+			"hits":       hits,
+			"stack":      stack,
+			"line":       s.Line,
+			"col":        s.Col,
+			"func":       s.Func,
+			"confidence": models.ConfidenceTaint,
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffMaxEdits: بیشتر از این تعداد خط تغییر، بخش میانی یک‌جا حذف/اضافه نشان داده می‌شود (حافظهٔ Myers ~ D²)
+const diffMaxEdits = 2000
+
+type lineOp struct {
+	kind byte // ' ' | '-' | '+'
+	a, b int  // اندیس خط در a و b (برای +/- موقعیت فعلی طرف دیگر)
+}
+
+// UnifiedDiff: diff خطی به فرمت unified؛ exact=false یعنی تغییرات زیاد بود و بخش میانی درشت آمد
+func UnifiedDiff(fromName, toName, a, b string, context int) (out string, exact bool) {
+	al, bl := splitLines(a), splitLines(b)
+	ops, exact := diffLines(al, bl)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	hunks := 0
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			j := end
+			for j < len(ops) && ops[j].kind == ' ' {
+				j++
+			}
+			if j == len(ops) || j-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = j
+		}
+		writeHunk(&sb, ops[start:end], al, bl)
+		hunks++
+		i = end
+	}
+	if hunks == 0 {
+		return "", exact
+	}
+	return sb.String(), exact
+}
+
+func writeHunk(sb *strings.Builder, ops []lineOp, al, bl []string) {
+	aStart, bStart := ops[0].a, ops[0].b
+	aCount, bCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	// در فرمت unified شروع ۱-مبناست؛ بازهٔ خالی به خط قبل اشاره می‌کند
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			sb.WriteString("+" + bl[op.b] + "\n")
+		case '-':
+			sb.WriteString("-" + al[op.a] + "\n")
+		default:
+			sb.WriteString(" " + al[op.a] + "\n")
+		}
+	}
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines: پیشوند/پسوند مشترک جدا می‌شود و میانه با Myers مقایسه می‌شود
+func diffLines(a, b []string) ([]lineOp, bool) {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops := make([]lineOp, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		ops = append(ops, lineOp{' ', i, i})
+	}
+	mid, exact := myersDiff(a[pre:len(a)-suf], b[pre:len(b)-suf])
+	for _, op := range mid {
+		ops = append(ops, lineOp{op.kind, op.a + pre, op.b + pre})
+	}
+	for i := suf; i > 0; i-- {
+		ops = append(ops, lineOp{' ', len(a) - i, len(b) - i})
+	}
+	return ops, exact
+}
+
+func myersDiff(a, b []string) ([]lineOp, bool) {
+	n, m := len(a), len(b)
+	maxD := min(n+m, diffMaxEdits)
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int // v قبل از هر مرحلهٔ d، فقط بازهٔ [-d-1, d+1]
+
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return myersBacktrack(trace, n, m), true
+			}
+		}
+	}
+
+	// تغییرات بیش از حد: کل میانه حذف و دوباره اضافه
+	ops := make([]lineOp, 0, n+m)
+	for i := 0; i < n; i++ {
+		ops = append(ops, lineOp{'-', i, 0})
+	}
+	for j := 0; j < m; j++ {
+		ops = append(ops, lineOp{'+', n, j})
+	}
+	return ops, false
+}
+
+func myersBacktrack(trace [][]int, n, m int) []lineOp {
+	var rev []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, lineOp{' ', x, y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			rev = append(rev, lineOp{'+', x, y})
+		} else {
+			x--
+			rev = append(rev, lineOp{'-', x, y})
+		}
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev
+}
@@ -0,0 +1,91 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		context   int
+		want      string
+		wantExact bool
+	}{
+		{
+			name: "identical", a: "a\nb\nc\n", b: "a\nb\nc\n", context: 3,
+			want: "", wantExact: true,
+		},
+		{
+			name: "both empty", a: "", b: "", context: 3,
+			want: "", wantExact: true,
+		},
+		{
+			name: "changed line", a: "a\nb\nc\n", b: "a\nx\nc\n", context: 1,
+			want:      "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+			wantExact: true,
+		},
+		{
+			name: "added to empty", a: "", b: "a\nb\n", context: 3,
+			want:      "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+			wantExact: true,
+		},
+		{
+			name: "removed all", a: "a\nb\n", b: "", context: 3,
+			want:      "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+			wantExact: true,
+		},
+		{
+			name: "insert without context", a: "a\nc\n", b: "a\nb\nc\n", context: 0,
+			want:      "--- old\n+++ new\n@@ -1,0 +2,1 @@\n+b\n",
+			wantExact: true,
+		},
+		{
+			name: "trailing newline ignored", a: "a\nb", b: "a\nb\n", context: 3,
+			want: "", wantExact: true,
+		},
+		{
+			name: "separate hunks", a: "1\n2\n3\n4\n5\n6\n7\n8\n", b: "1\nX\n3\n4\n5\n6\nY\n8\n", context: 1,
+			want:      "--- old\n+++ new\n@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n@@ -6,3 +6,3 @@\n 6\n-7\n+Y\n 8\n",
+			wantExact: true,
+		},
+		{
+			name: "close changes share a hunk", a: "1\n2\n3\n4\n5\n", b: "1\nX\n3\nY\n5\n", context: 1,
+			want:      "--- old\n+++ new\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n-4\n+Y\n 5\n",
+			wantExact: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exact := UnifiedDiff("old", "new", tt.a, tt.b, tt.context)
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if exact != tt.wantExact {
+				t.Errorf("UnifiedDiff() exact = %v, want %v", exact, tt.wantExact)
+			}
+		})
+	}
+}
+
+// بیش از diffMaxEdits تغییر: میانه درشت (حذف کامل + اضافهٔ کامل) و exact=false
+func TestUnifiedDiffTooManyEdits(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < diffMaxEdits; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+	got, exact := UnifiedDiff("old", "new", "head\n"+a.String()+"tail\n", "head\n"+b.String()+"tail\n", 1)
+	if exact {
+		t.Fatal("UnifiedDiff() exact = true, want false")
+	}
+	wantHeader := fmt.Sprintf("@@ -1,%d +1,%d @@\n head\n-a0\n", diffMaxEdits+2, diffMaxEdits+2)
+	if !strings.Contains(got, wantHeader) {
+		t.Errorf("UnifiedDiff() missing %q in\n%.300s", wantHeader, got)
+	}
+	if wantTail := fmt.Sprintf("+b%d\n tail\n", diffMaxEdits-1); !strings.HasSuffix(got, wantTail) {
+		t.Errorf("UnifiedDiff() tail = %q", got[max(0, len(got)-40):])
+	}
+}
@@ -0,0 +1,118 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// computeWatchDelta: snapshot این اجرا در برابر snapshot اجرای قبلی واچ
+// (برای واچ‌های قدیمی بدون last_snapshot_id: اسکن قبلی همان صفحه)
+func computeWatchDelta(ctx context.Context, w *models.WatchDoc, snapshotID string) (*models.WatchDelta, error) {
+	to, err := LoadPageSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	d := &models.WatchDelta{ToSnapshot: to.ID}
+
+	var from *models.PageSnapshot
+	if w.LastSnapshotID != "" && w.LastSnapshotID != to.ID {
+		from, _ = LoadPageSnapshot(ctx, w.LastSnapshotID)
+	}
+	if from == nil {
+		from, _ = previousPageSnapshot(ctx, to.URLNorm, to.ScannedAt, false)
+	}
+	if from == nil {
+		d.Baseline = true
+		return d, nil
+	}
+	d.FromSnapshot = from.ID
+
+	// اگر اجرای قبل مرحلهٔ sinks نداشت، sinkها با آخرین اسکنی که داشت مقایسه می‌شوند
+	base := *from
+	if to.SinksAt != nil && from.SinksAt == nil {
+		if prev, err := previousPageSnapshot(ctx, to.URLNorm, to.ScannedAt, true); err == nil {
+			base.Sinks, base.SinksAt = prev.Sinks, prev.SinksAt
+		}
+	}
+
+	diff := DiffSnapshots(&base, to)
+	d.EndpointsAdded, d.EndpointsRemoved = diff.Endpoints.Added, diff.Endpoints.Removed
+	d.SinksCompared, d.SinksAdded = diff.SinksCompared, diff.Sinks.Added
+	d.ExternalHostsAdded = diff.ExternalHosts.Added
+	d.SecretsAdded = diff.Secrets.Added
+	// inlineها (nonce، timestamp و ...) هر بار hash تازه دارند؛ فقط اسکریپت‌های خارجی
+	for _, s := range diff.Scripts.Added {
+		if !s.Inline {
+			d.ScriptsAdded = append(d.ScriptsAdded, s)
+		}
+	}
+	d.ScriptsChanged = diff.Scripts.Changed
+	return d, nil
+}
+
+// watchDeltaMatches: با min_severity فقط sink/secret جدید >= آن سطح اعلان می‌شود، وگرنه هر تغییری
+func watchDeltaMatches(d *models.WatchDelta, minSeverity string) bool {
+	if d == nil || d.Empty() {
+		return false
+	}
+	if minSeverity == "" {
+		return true
+	}
+	min := models.SeverityRank(minSeverity)
+	for _, s := range d.SinksAdded {
+		if models.SeverityRank(s.Severity) >= min {
+			return true
+		}
+	}
+	for _, s := range d.SecretsAdded {
+		if models.SeverityRank(s.Severity) >= min {
+			return true
+		}
+	}
+	return false
+}
+
+// formatWatchDelta: خطوط پیام دیسکورد برای delta
+func formatWatchDelta(d *models.WatchDelta) string {
+	var sb strings.Builder
+	if len(d.EndpointsAdded) > 0 {
+		fmt.Fprintf(&sb, "+ endpoints (%d): %s\n", len(d.EndpointsAdded), joinLimit(d.EndpointsAdded, 8))
+	}
+	if len(d.EndpointsRemoved) > 0 {
+		fmt.Fprintf(&sb, "- endpoints (%d): %s\n", len(d.EndpointsRemoved), joinLimit(d.EndpointsRemoved, 5))
+	}
+	if len(d.SinksAdded) > 0 {
+		sinks := make([]string, 0, len(d.SinksAdded))
+		for _, s := range d.SinksAdded {
+			sinks = append(sinks, fmt.Sprintf("%s [%s] %s", s.Kind, s.Severity, s.SourceURL))
+		}
+		fmt.Fprintf(&sb, "+ sinks (%d): %s\n", len(d.SinksAdded), joinLimit(sinks, 5))
+	}
+	if len(d.SecretsAdded) > 0 {
+		secrets := make([]string, 0, len(d.SecretsAdded))
+		for _, s := range d.SecretsAdded {
+			secrets = append(secrets, fmt.Sprintf("%s [%s] %s", s.RuleID, s.Severity, s.Masked))
+		}
+		fmt.Fprintf(&sb, "+ secrets (%d): %s\n", len(d.SecretsAdded), joinLimit(secrets, 5))
+	}
+	if len(d.ExternalHostsAdded) > 0 {
+		fmt.Fprintf(&sb, "+ external hosts (%d): %s\n", len(d.ExternalHostsAdded), joinLimit(d.ExternalHostsAdded, 8))
+	}
+	if len(d.ScriptsAdded) > 0 {
+		urls := make([]string, 0, len(d.ScriptsAdded))
+		for _, s := range d.ScriptsAdded {
+			urls = append(urls, s.URL)
+		}
+		fmt.Fprintf(&sb, "+ scripts (%d): %s\n", len(urls), joinLimit(urls, 5))
+	}
+	if len(d.ScriptsChanged) > 0 {
+		urls := make([]string, 0, len(d.ScriptsChanged))
+		for _, s := range d.ScriptsChanged {
+			urls = append(urls, s.URL)
+		}
+		fmt.Fprintf(&sb, "~ scripts changed (%d): %s\n", len(urls), joinLimit(urls, 5))
+	}
+	return sb.String()
+}
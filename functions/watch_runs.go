@@ -0,0 +1,159 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RunWatch: یک اجرای کامل واچ (اسکن، delta، اعلان، زمان‌بندی بعدی) که در watch_runs ثبت می‌شود
+func RunWatch(ctx context.Context, w models.WatchDoc, trigger string) *models.WatchRunDoc {
+	run := &models.WatchRunDoc{
+		ID:        primitive.NewObjectID().Hex(),
+		SiteID:    w.SiteID,
+		URLNorm:   w.URLNorm,
+		URL:       w.URL,
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+	}
+	now := run.StartedAt
+
+	// 1) اسکن
+	req := models.ScanRequest{URL: w.URL, WaitSec: 7, JSFetchTimeout: 8, AuthProfileID: w.AuthProfile}
+	var resp *models.ScanResponse
+	// پروکسی و پروفایل یک‌بار؛ تب sinks هم با همان پروکسی و کوکی/هدرها باز می‌شود
+	scanCtx, err := resolveScanContext(ctx, req)
+	if err == nil {
+		resp, err = RunScanContext(scanCtx, req)
+	}
+	if err != nil {
+		log.Printf("[watch] scan error url=%s err=%v", w.URL, err)
+		run.Error = err.Error()
+	}
+
+	var (
+		scriptChanges []models.ScriptVersionChange
+		delta         *models.WatchDelta
+	)
+	if resp != nil {
+		run.ScanErrors = resp.Errors
+		// ذخیره نتایج صفحه/اندپوینت‌ها و سینک‌ها
+		if err := SaveScanResponse(ctx, resp); err != nil {
+			run.Errors = append(run.Errors, "save: "+err.Error())
+		}
+		sinksCtx, cancelSinks := context.WithTimeout(scanCtx, 60*time.Second)
+		ScanAndPersistSinks(sinksCtx, w.URL, w.SiteID, w.URLNorm, resp)
+		cancelSinks()
+		if len(w.Scripts) > 0 {
+			scriptChanges, w.LastScripts = detectScriptChanges(ctx, &w, resp)
+		}
+
+		// 2) تفاضل با اجرای قبلی (snapshot به snapshot)
+		run.SnapshotID = resp.SnapshotID
+		if resp.SnapshotID != "" {
+			if delta, err = computeWatchDelta(ctx, &w, resp.SnapshotID); err != nil {
+				log.Printf("[watch] delta error url=%s err=%v", w.URL, err)
+				run.Errors = append(run.Errors, "delta: "+err.Error())
+			}
+		}
+	}
+	summary := computeWatchSummary(ctx, w.SiteID, w.URLNorm, w.MinSeverity)
+	summary.ScriptChanges = scriptChanges
+	run.Changed = delta != nil && !delta.Empty()
+	run.Delta, run.Summary = delta, summary
+
+	// 3) آپدیت زمان‌بندی
+	upd := bson.M{
+		"$set": bson.M{
+			"last_run_at":  now,
+			"next_run_at":  now.Add(time.Duration(max(5, w.FreqMin)) * time.Minute),
+			"last_summary": summary,
+			"last_scripts": w.LastScripts,
+			"updated_at":   time.Now(),
+		},
+	}
+	if delta != nil {
+		upd["$set"].(bson.M)["last_delta"] = delta
+		upd["$set"].(bson.M)["last_snapshot_id"] = delta.ToSnapshot
+	}
+	if run.Changed || len(scriptChanges) > 0 {
+		upd["$set"].(bson.M)["last_change_at"] = time.Now()
+	}
+
+	// 4) Notify Discord — فقط delta غیرخالی که با معیار واچ جور است
+	if run.Changed {
+		if watchDeltaMatches(delta, w.MinSeverity) {
+			run.Notifications = append(run.Notifications, notifyDiscord(ctx, w.SiteID, w.URL, w.MinSeverity, summary, delta))
+		} else {
+			run.Notifications = append(run.Notifications, models.WatchNotification{
+				Channel: "discord", Kind: "changes", Status: models.NotifySkipped, Reason: "below min_severity " + w.MinSeverity, At: time.Now(),
+			})
+		}
+	}
+	if len(scriptChanges) > 0 {
+		run.Notifications = append(run.Notifications, notifyScriptChanges(ctx, w.SiteID, w.URL, scriptChanges))
+	}
+	_, _ = models.WatchesColl().UpdateOne(ctx, bson.M{"site_id": w.SiteID, "url_norm": w.URLNorm}, upd)
+
+	run.EndedAt = time.Now()
+	run.DurationMs = run.EndedAt.Sub(run.StartedAt).Milliseconds()
+	switch {
+	case resp == nil:
+		run.Status = models.WatchRunFailed
+	case len(run.Errors) > 0 || len(run.ScanErrors) > 0:
+		run.Status = models.WatchRunPartial
+	default:
+		run.Status = models.WatchRunOK
+	}
+	if err := saveWatchRun(ctx, run); err != nil {
+		log.Printf("[watch] run save error url=%s err=%v", w.URL, err)
+	}
+	return run
+}
+
+func saveWatchRun(ctx context.Context, run *models.WatchRunDoc) error {
+	cfg, err := models.GetWatchRunsSetting(ctx)
+	if err != nil {
+		cfg.RetentionDays = models.DefaultWatchRunRetentionDays
+	}
+	run.ExpireAt = run.StartedAt.AddDate(0, 0, cfg.RetentionDays)
+	_, err = models.WatchRunsColl().InsertOne(ctx, run)
+	return err
+}
+
+// ApplyWatchRunRetention: expire_at اجراهای موجود با retention جدید دوباره حساب می‌شود
+func ApplyWatchRunRetention(ctx context.Context, days int) error {
+	_, err := models.WatchRunsColl().UpdateMany(ctx, bson.M{}, bson.A{
+		bson.M{"$set": bson.M{"expire_at": bson.M{"$add": bson.A{"$started_at", int64(days) * int64(24*time.Hour/time.Millisecond)}}}},
+	})
+	return err
+}
+
+// sendWatchNotification: ارسال به دیسکورد و ثبت نتیجه (غیرفعال بودن = skipped)
+func sendWatchNotification(ctx context.Context, kind, msg string) models.WatchNotification {
+	n := models.WatchNotification{Channel: "discord", Kind: kind, At: time.Now()}
+	doc, err := models.GetDiscordSettings(ctx)
+	switch {
+	case err != nil:
+		n.Status, n.Error = models.NotifyFailed, err.Error()
+		return n
+	case !doc.Enabled || strings.TrimSpace(doc.WebhookURL) == "":
+		n.Status, n.Reason = models.NotifySkipped, "discord disabled"
+		return n
+	}
+	// سقف پیام دیسکورد ۲۰۰۰ کاراکتر است
+	if r := []rune(msg); len(r) > 1900 {
+		msg = string(r[:1900]) + "\n…"
+	}
+	if err := SendDiscordWebhook(ctx, doc.WebhookURL, msg); err != nil {
+		n.Status, n.Error = models.NotifyFailed, err.Error()
+		return n
+	}
+	n.Status = models.NotifySent
+	return n
+}
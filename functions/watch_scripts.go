@@ -0,0 +1,104 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchesScript: آیا این URL در لیست اسکریپت‌های واچ است
+func watchesScript(w *models.WatchDoc, srcURL string) bool {
+	for _, s := range w.Scripts {
+		if s == "*" || s == srcURL {
+			return true
+		}
+	}
+	return false
+}
+
+// detectScriptChanges: hash اسکریپت‌های واچ‌شده در این اسکن در برابر last_scripts واچ؛
+// اسکریپتی که بار اول دیده می‌شود فقط baseline است. خروجی دوم last_scripts جدید است
+func detectScriptChanges(ctx context.Context, w *models.WatchDoc, resp *models.ScanResponse) ([]models.ScriptVersionChange, []models.WatchScript) {
+	siteID, urlNorm, _ := PageKeys(resp.URL)
+	current := map[string]string{}
+	for key, code := range resp.ScriptBodies {
+		srcURL, srcType := normalizeSourceURL(urlNorm, key)
+		if srcType == "script" && code != "" && watchesScript(w, srcURL) {
+			current[srcURL] = sha256Hex(code)
+		}
+	}
+
+	var changes []models.ScriptVersionChange
+	last := make([]models.WatchScript, 0, len(current)+len(w.LastScripts))
+	for _, ls := range w.LastScripts {
+		sha, ok := current[ls.URL]
+		switch {
+		case !ok:
+			// این بار لود نشد؛ baseline قبلی می‌ماند (اگر هنوز واچ می‌شود)
+			if watchesScript(w, ls.URL) {
+				last = append(last, ls)
+			}
+			continue
+		case sha != ls.SHA256:
+			changes = append(changes, scriptChangeSummary(ctx, siteID, ls.URL, ls.SHA256, sha))
+		}
+	}
+	for u, sha := range current {
+		last = append(last, models.WatchScript{URL: u, SHA256: sha})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].URL < changes[j].URL })
+	sort.Slice(last, func(i, j int) bool { return last[i].URL < last[j].URL })
+	return changes, last
+}
+
+// scriptChangeSummary: اگر هر دو نسخه ذخیره شده باشند endpoint/sinkهای جدید هم می‌آید
+func scriptChangeSummary(ctx context.Context, siteID, srcURL, fromSHA, toSHA string) models.ScriptVersionChange {
+	from, errFrom := FindScriptVersion(ctx, siteID, srcURL, fromSHA, time.Time{})
+	to, errTo := FindScriptVersion(ctx, siteID, srcURL, toSHA, time.Time{})
+	if errFrom != nil || errTo != nil {
+		return models.ScriptVersionChange{URL: srcURL, FromSHA256: fromSHA, ToSHA256: toSHA}
+	}
+	return CompareScriptVersions(from, to)
+}
+
+// notifyScriptChanges: پیام جدای «اسکریپت X عوض شد» با endpoint/sinkهای اضافه‌شده
+func notifyScriptChanges(ctx context.Context, siteID, pageURL string, changes []models.ScriptVersionChange) models.WatchNotification {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧩 *SiteChecker* script changed\nSite: `%s`\nPage: %s\n", siteID, pageURL)
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "\nScript: %s\nVersion: `%s` → `%s`", c.URL, shortSHA(c.FromSHA256), shortSHA(c.ToSHA256))
+		if c.FromSize > 0 || c.ToSize > 0 {
+			fmt.Fprintf(&sb, " (%d → %d bytes)", c.FromSize, c.ToSize)
+		}
+		sb.WriteString("\n")
+		if len(c.AddedEndpoints) > 0 {
+			fmt.Fprintf(&sb, "+ endpoints (%d): %s\n", len(c.AddedEndpoints), joinLimit(c.AddedEndpoints, 8))
+		}
+		if len(c.AddedSinks) > 0 {
+			sinks := make([]string, 0, len(c.AddedSinks))
+			for _, s := range c.AddedSinks {
+				sinks = append(sinks, fmt.Sprintf("%s@%d [%s]", s.Kind, s.Line, s.Severity))
+			}
+			fmt.Fprintf(&sb, "+ sinks (%d): %s\n", len(c.AddedSinks), joinLimit(sinks, 8))
+		}
+	}
+	sb.WriteString("Time: " + time.Now().Format(time.RFC3339))
+	return sendWatchNotification(ctx, "scripts", sb.String())
+}
+
+func shortSHA(s string) string {
+	if len(s) > 12 {
+		return s[:12]
+	}
+	return s
+}
+
+func joinLimit(list []string, n int) string {
+	if len(list) <= n {
+		return strings.Join(list, ", ")
+	}
+	return strings.Join(list[:n], ", ") + fmt.Sprintf(", … +%d", len(list)-n)
+}
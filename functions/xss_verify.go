@@ -0,0 +1,437 @@
+package functions
+
+import (
+	"SiteChecker/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrXSSVerifyNoAllowList = errors.New("xss verification: no allowed hosts configured for this site")
+	ErrXSSVerifyOutOfScope  = errors.New("xss verification: page host is not in the allow list")
+)
+
+// sourceهایی که می‌توان payload را در آن‌ها کاشت
+var xssVerifiableSources = map[string]bool{
+	"location.hash": true, "location.search": true, "window.name": true, "postMessage": true,
+}
+
+const (
+	xssBinding     = "__scXSS" // runtime binding؛ صدا زدنش = اجرای payload
+	xssNavTimeout  = 20 * time.Second
+	xssSettleDelay = 4 * time.Second
+)
+
+// xssPayload: payload HTML (img/onerror) یا JS (برای sinkهای CWE-95 مثل eval/setTimeout)
+func xssPayload(marker string, js bool) string {
+	if js {
+		// هم eval مستقیم و هم داخل رشتهٔ '…'
+		return xssBinding + `("` + marker + `")//';` + xssBinding + `("` + marker + `")//`
+	}
+	return `"'><img src=x data-sc=` + marker + ` onerror=` + xssBinding + `(this.dataset.sc)>`
+}
+
+func xssMarker() string { return randomMarker("scx") }
+
+// randomMarker: prefix + 10 hex تصادفی (فقط حروف/عدد؛ در URL، HTML و نام property سالم می‌ماند)
+func randomMarker(prefix string) string {
+	b := make([]byte, 5)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// NormalizeAllowHost: "example.com" یا "*.example.com"؛ بدون scheme/پورت/مسیر و بدون wildcard سراسری
+func NormalizeAllowHost(h string) (string, error) {
+	h = strings.ToLower(strings.TrimSpace(h))
+	name := strings.TrimPrefix(h, "*.")
+	if name == "" || strings.ContainsAny(name, "*/:@?# ") || !strings.Contains(name, ".") && name != "localhost" {
+		return "", fmt.Errorf("invalid allow host %q (use example.com or *.example.com)", h)
+	}
+	return h, nil
+}
+
+// hostAllowed: تطبیق دقیق یا wildcard "*.example.com" (خود example.com را هم شامل می‌شود)
+func hostAllowed(host string, allow []string) bool {
+	host = strings.ToLower(host)
+	for _, a := range allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if strings.HasPrefix(a, "*.") {
+			if host == a[2:] || strings.HasSuffix(host, a[1:]) {
+				return true
+			}
+		} else if host == a {
+			return true
+		}
+	}
+	return false
+}
+
+// activeProbeAllowHosts: دروازهٔ مشترک probeهای فعال (verify XSS و prototype pollution)؛
+// بدون لیست مجاز سایت یا با host خارج از آن هیچ payloadی فرستاده نمی‌شود
+func activeProbeAllowHosts(ctx context.Context, siteID, rawURL string) ([]string, error) {
+	setting, err := models.GetXSSVerifySetting(ctx, siteID)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil || len(setting.AllowHosts) == 0 {
+		return nil, ErrXSSVerifyNoAllowList
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !hostAllowed(u.Hostname(), setting.AllowHosts) {
+		return nil, ErrXSSVerifyOutOfScope
+	}
+	return setting.AllowHosts, nil
+}
+
+// VerifyDOMXSS: برای taint flowهای صفحه payload canary می‌فرستد و sinkهای تأییدشده را علامت می‌زند.
+// pageURL همان url_norm است؛ rawURL آدرسی که ناوبری می‌شود.
+func VerifyDOMXSS(ctx context.Context, siteID, pageURL, rawURL string) (*models.XSSVerifyResult, error) {
+	allow, err := activeProbeAllowHosts(ctx, siteID, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// در اسکن پروکسی و پروفایل از job/واچ روی ctx هستند؛ از API مستقیم فقط تنظیم پروکسی سایت اعمال می‌شود
+	if ctx, err = resolveScanContext(ctx, models.ScanRequest{URL: rawURL}); err != nil {
+		return nil, err
+	}
+
+	var flows []models.FindingDoc
+	cur, err := models.FindingsColl().Find(ctx, bson.M{"site_id": siteID, "page_url": pageURL, "type": models.FindingTaintFlow})
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(ctx, &flows); err != nil {
+		return nil, err
+	}
+
+	// source → آیا sinkی از نوع اجرای کد (CWE-95) دارد
+	res := &models.XSSVerifyResult{PageURL: pageURL, Attempts: []models.XSSVerifyAttempt{}}
+	jsSink := map[string]bool{}
+	var order []string
+	skipped := map[string]bool{}
+	for _, f := range flows {
+		cwe, _ := f.Details["cwe"].(string)
+		for _, src := range detailStrings(f.Details["sources"]) {
+			if !xssVerifiableSources[src] {
+				skipped[src] = true
+				continue
+			}
+			if _, ok := jsSink[src]; !ok {
+				order = append(order, src)
+			}
+			jsSink[src] = jsSink[src] || cwe == "CWE-95"
+		}
+	}
+	for src := range skipped {
+		res.Skipped = append(res.Skipped, src)
+	}
+
+	for _, src := range order {
+		payloads := []bool{false}
+		if jsSink[src] {
+			payloads = []bool{true, false}
+		}
+		for _, js := range payloads {
+			marker := xssMarker()
+			att := verifyXSSAttempt(ctx, rawURL, pageURL, src, marker, xssPayload(marker, js), allow)
+			res.Attempts = append(res.Attempts, att)
+			if !att.Verified {
+				continue
+			}
+			n, err := markXSSVerified(ctx, siteID, pageURL, att)
+			if err != nil {
+				return res, err
+			}
+			res.Verified += n
+			break
+		}
+	}
+	return res, nil
+}
+
+// verifyXSSAttempt: یک تب تازه، payload در source، و بررسی اجرا (binding) یا رندر (data-sc در DOM).
+// اینسترومنتیشن sinkها با marker همین payload نصب می‌شود تا معلوم شود payload به کدام sink رسید
+func verifyXSSAttempt(ctx context.Context, rawURL, pageURL, source, marker, payload string, allow []string) models.XSSVerifyAttempt {
+	att := models.XSSVerifyAttempt{Source: source, Payload: payload, URL: rawURL}
+
+	u, _ := url.Parse(rawURL)
+	switch source {
+	case "location.hash":
+		u.Fragment = ""
+		att.URL = u.String() + "#" + payload
+	case "location.search":
+		q := u.Query()
+		for k := range q {
+			q.Set(k, payload)
+		}
+		q.Set("sc_taint", payload)
+		u.RawQuery = q.Encode()
+		att.URL = u.String()
+	}
+
+	bctx, cancel := newBrowserCtx(ctx, scanProxyFrom(ctx))
+	defer cancel()
+	tctx, cancelT := context.WithTimeout(bctx, xssNavTimeout)
+	defer cancelT()
+
+	var executed atomic.Bool
+	chromedp.ListenTarget(tctx, func(ev interface{}) {
+		switch e := ev.(type) {
+		case *runtime.EventBindingCalled:
+			if e.Name == xssBinding && e.Payload == marker {
+				executed.Store(true)
+			}
+		}
+	})
+
+	// همهٔ درخواست‌ها (document، iframe، script، XHR، beacon و ...) رهگیری می‌شوند؛
+	// host خارج از لیست مجاز هیچ درخواستی از صفحهٔ payload‌دار نمی‌گیرد. پروفایل اسکن (کوکی/لاگین) هم اعمال می‌شود
+	actions := append(scanTabPrep(tctx, rawURL, allow),
+		runtime.AddBinding(xssBinding),
+	)
+	if source == "window.name" {
+		name, _ := json.Marshal(payload)
+		actions = append(actions, chromedp.ActionFunc(func(c context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(`if (window.top === window && !window.name) window.name = ` + string(name) + `;`).Do(c)
+			return err
+		}))
+	}
+	// کلید "payload" هیچ sourceی را seed نمی‌کند؛ فقط رسیدن marker به sink ثبت می‌شود
+	actions = append(actions,
+		InstallSinkInstrumentation(map[string]string{"payload": marker}),
+		chromedp.Navigate(att.URL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(xssSettleDelay),
+	)
+	if source == "postMessage" {
+		msg, _ := json.Marshal(payload)
+		actions = append(actions,
+			chromedp.Evaluate(fmt.Sprintf(`window.postMessage(%s, "*"); window.postMessage({data: %s}, "*");`, msg, msg), nil),
+			chromedp.Sleep(2*time.Second),
+		)
+	}
+
+	var (
+		rendered bool
+		fired    []sinkEntry
+	)
+	actions = append(actions,
+		chromedp.Evaluate(`!!document.querySelector('[data-sc="`+marker+`"]')`, &rendered),
+		chromedp.EvaluateAsDevTools(`(window.__sinkLog || []).filter(e => e.taint)`, &fired),
+	)
+	if err := chromedp.Run(tctx, actions...); err != nil && !executed.Load() {
+		att.Error = err.Error()
+		return att
+	}
+	seen := map[models.XSSFiredSink]bool{}
+	for _, e := range fired {
+		srcURL, _ := normalizeSourceURL(pageURL, e.File)
+		fs := models.XSSFiredSink{Kind: e.Kind, SourceURL: srcURL, Line: e.Line, Col: e.Col}
+		if !seen[fs] {
+			seen[fs] = true
+			att.Sinks = append(att.Sinks, fs)
+		}
+	}
+
+	switch {
+	case executed.Load():
+		att.Verified, att.Method = true, "executed"
+	case rendered:
+		att.Verified, att.Method = true, "rendered"
+	}
+	return att
+}
+
+// markXSSVerified: تلاش موفق همیشه یک finding xss_verified (کلید: صفحه + source) با URL و payload بازتولید
+// می‌گیرد؛ taint flowهایی از این source که sink و callsiteشان با sinkهای fire‌شدهٔ تلاش یکی است
+// (و SinkDocهای لینک‌شده با sink_sig) هم تأییدشده علامت می‌خورند
+func markXSSVerified(ctx context.Context, siteID, pageURL string, att models.XSSVerifyAttempt) (int, error) {
+	v := models.SinkVerification{Source: att.Source, Method: att.Method, URL: att.URL, Payload: att.Payload, VerifiedAt: time.Now()}
+
+	var flowSigs, sinkSigs []string
+	if len(att.Sinks) > 0 {
+		var flows []models.FindingDoc
+		cur, err := models.FindingsColl().Find(ctx, bson.M{"site_id": siteID, "page_url": pageURL, "type": models.FindingTaintFlow, "details.sources": att.Source})
+		if err != nil {
+			return 0, err
+		}
+		if err := cur.All(ctx, &flows); err != nil {
+			return 0, err
+		}
+		fired := make(map[models.XSSFiredSink]bool, len(att.Sinks))
+		for _, s := range att.Sinks {
+			fired[s] = true
+		}
+		for _, f := range flows {
+			kind, _ := f.Details["sink"].(string)
+			key := models.XSSFiredSink{Kind: kind, SourceURL: f.URL, Line: detailInt(f.Details["line"]), Col: detailInt(f.Details["col"])}
+			if !fired[key] {
+				continue
+			}
+			flowSigs = append(flowSigs, f.Sig)
+			if sig, _ := f.Details["sink_sig"].(string); sig != "" {
+				sinkSigs = append(sinkSigs, sig)
+			}
+		}
+	}
+
+	if err := PersistFindings(ctx, []models.FindingDoc{xssVerifiedFinding(siteID, pageURL, att, v, flowSigs)}); err != nil {
+		return 0, err
+	}
+	if len(flowSigs) == 0 {
+		return 1, nil
+	}
+
+	if _, err := models.FindingsColl().UpdateMany(ctx, bson.M{"sig": bson.M{"$in": flowSigs}}, bson.M{"$set": bson.M{
+		"severity":             models.SeverityHigh,
+		"details.verified":     true,
+		"details.verification": v,
+	}}); err != nil {
+		return 0, err
+	}
+	if len(sinkSigs) == 0 {
+		return 1, nil
+	}
+	upd, err := models.SinksColl().UpdateMany(ctx, bson.M{"sig": bson.M{"$in": sinkSigs}}, bson.M{"$set": bson.M{
+		"verification": v,
+		"confidence":   models.ConfidenceTaint,
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return max(int(upd.MatchedCount), 1), nil
+}
+
+// xssVerifiedFinding: sig بدون marker تصادفی تا تأیید بعدی همان finding را به‌روز کند
+func xssVerifiedFinding(siteID, pageURL string, att models.XSSVerifyAttempt, v models.SinkVerification, flowSigs []string) models.FindingDoc {
+	sum := sha256.Sum256([]byte(siteID + "\x1f" + models.FindingXSSVerified + "\x1f" + pageURL + "\x1f" + att.Source))
+	sinks := att.Sinks
+	if sinks == nil {
+		sinks = []models.XSSFiredSink{}
+	}
+	if flowSigs == nil {
+		flowSigs = []string{}
+	}
+	return models.FindingDoc{
+		Sig:      hex.EncodeToString(sum[:]),
+		SiteID:   siteID,
+		PageURL:  pageURL,
+		Type:     models.FindingXSSVerified,
+		Severity: models.SeverityHigh,
+		Title:    fmt.Sprintf("DOM XSS verified via %s (%s)", att.Source, att.Method),
+		URL:      att.URL,
+		Details: map[string]any{
+			"source":       att.Source,
+			"method":       att.Method,
+			"payload":      att.Payload,
+			"repro_url":    att.URL,
+			"sinks":        sinks,    // sinkهایی که payload به آن‌ها رسید
+			"flow_sigs":    flowSigs, // taint flowهای منطبق (ممکن است خالی باشد)
+			"verification": v,
+			"cwe":          "CWE-79",
+			"confidence":   models.ConfidenceRuntime,
+		},
+	}
+}
+
+// detailInt: عدد از details (بعد از decode از Mongo int32/int64 است)
+func detailInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// detailStrings: آرایهٔ رشته از details (بعد از decode از Mongo نوعش bson.A است)
+func detailStrings(v any) []string {
+	var out []string
+	switch arr := v.(type) {
+	case []string:
+		out = arr
+	case bson.A:
+		for _, x := range arr {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+	case []any:
+		for _, x := range arr {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// VerifyAndPersistXSS: مرحلهٔ verify در اسکن/crawl؛ بدون taint flow یا لیست مجاز بی‌صدا رد می‌شود
+func VerifyAndPersistXSS(ctx context.Context, rawURL, siteID, urlNorm string) int {
+	n, err := models.FindingsColl().CountDocuments(ctx, bson.M{"site_id": siteID, "page_url": urlNorm, "type": models.FindingTaintFlow})
+	if err != nil || n == 0 {
+		return 0
+	}
+	res, err := VerifyDOMXSS(ctx, siteID, urlNorm, rawURL)
+	if err != nil {
+		if !errors.Is(err, ErrXSSVerifyNoAllowList) && !errors.Is(err, ErrXSSVerifyOutOfScope) {
+			log.Printf("[xss verify] url=%s err=%v", rawURL, err)
+		}
+		return 0
+	}
+	return res.Verified
+}
+
+// carryXSSVerification: PersistFindings کل details را بازنویسی می‌کند؛ تأیید قبلی flowها (و severity high) حفظ شود
+func carryXSSVerification(ctx context.Context, flows []models.FindingDoc) {
+	sigs := make([]string, 0, len(flows))
+	for _, f := range flows {
+		sigs = append(sigs, f.Sig)
+	}
+	cur, err := models.FindingsColl().Find(ctx, bson.M{"sig": bson.M{"$in": sigs}, "details.verified": true})
+	if err != nil {
+		return
+	}
+	var verified []models.FindingDoc
+	if err := cur.All(ctx, &verified); err != nil {
+		return
+	}
+	bySig := make(map[string]models.FindingDoc, len(verified))
+	for _, f := range verified {
+		bySig[f.Sig] = f
+	}
+	for i := range flows {
+		old, ok := bySig[flows[i].Sig]
+		if !ok {
+			continue
+		}
+		flows[i].Details["verified"] = true
+		flows[i].Details["verification"] = old.Details["verification"]
+		if models.SeverityRank(flows[i].Severity) < models.SeverityRank(models.SeverityHigh) {
+			flows[i].Severity = models.SeverityHigh
+		}
+	}
+}
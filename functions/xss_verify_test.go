@@ -0,0 +1,36 @@
+package functions
+
+import "testing"
+
+func TestHostAllowed(t *testing.T) {
+	allow := []string{"app.example.com", "*.test.org", " Shop.Example.NET ", ""}
+	tests := []struct {
+		name string
+		host string
+		want bool
+	}{
+		{"exact", "app.example.com", true},
+		{"exact case-insensitive", "APP.Example.com", true},
+		{"exact does not cover subdomain", "x.app.example.com", false},
+		{"exact does not cover parent", "example.com", false},
+		{"wildcard subdomain", "a.test.org", true},
+		{"wildcard nested subdomain", "a.b.test.org", true},
+		{"wildcard apex", "test.org", true},
+		{"wildcard suffix without dot", "eviltest.org", false},
+		{"wildcard suffix as label", "test.org.evil.com", false},
+		{"trimmed and lowercased entry", "shop.example.net", true},
+		{"empty entry matches nothing", "", false},
+		{"unlisted host", "other.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostAllowed(tt.host, allow); got != tt.want {
+				t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+
+	if hostAllowed("app.example.com", nil) {
+		t.Error("hostAllowed with empty allow list = true, want false")
+	}
+}
@@ -4,11 +4,16 @@ go 1.24.5
 
 require (
 	github.com/chromedp/chromedp v0.14.1
+	github.com/dop251/goja v0.0.0-20260106131823-651366fbe6e3
+	github.com/go-sourcemap/sourcemap v2.1.4+incompatible
 	github.com/gorilla/mux v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
@@ -30,5 +35,4 @@ require (
 	go.mongodb.org/mongo-driver v1.17.4
 	golang.org/x/net v0.30.0
 	golang.org/x/sys v0.34.0 // indirect
-
 )
@@ -77,6 +77,17 @@ func qSort(r *http.Request, defField string, defOrder int) bson.D {
 	return bson.D{{Key: field, Value: order}}
 }
 
+// qCSV: پارامتر کاما-جدا ("a, b") → []string بدون مقدار خالی
+func qCSV(r *http.Request, key string) []string {
+	var out []string
+	for _, v := range strings.Split(r.URL.Query().Get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func qTime(r *http.Request, key string) (time.Time, bool) {
 	val := r.URL.Query().Get(key)
 	if val == "" {
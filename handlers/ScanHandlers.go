@@ -6,15 +6,17 @@ import (
 	"SiteChecker/models"
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	"golang.org/x/net/publicsuffix"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// POST /api/scan — اسکن را در صف می‌گذارد و شناسهٔ job را برمی‌گرداند
 func ScanHandler(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if rec := recover(); rec != nil {
@@ -36,6 +38,7 @@ func ScanHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "url is required", http.StatusBadRequest)
 		return
 	}
+	req.HARImport = nil // فقط از /api/import/har
 	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
 		req.URL = "https://" + req.URL
 	}
@@ -45,75 +48,124 @@ func ScanHandler(w http.ResponseWriter, r *http.Request) {
 	if req.JSFetchTimeout <= 0 {
 		req.JSFetchTimeout = 8
 	}
-
-	// ——— Normalize ها را همین اول بساز تا همه‌جا داشته باشیم
-	u, _ := url.Parse(req.URL)
-	host := strings.ToLower(u.Hostname())
-	siteID, _ := publicsuffix.EffectiveTLDPlusOne(host)
-	if siteID == "" {
-		siteID = host
+	if req.Proxy != nil {
+		if err := functions.ValidateProxy(req.Proxy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Crawl != nil {
+		if err := functions.NormalizeCrawlOptions(req.Crawl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
-	urlNorm := u.Scheme + "://" + u.Host + u.EscapedPath()
-	if u.RawQuery != "" {
-		urlNorm += "?" + u.RawQuery
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if req.AuthProfileID != "" {
+		if _, err := functions.AuthProfileForURL(ctx, req.AuthProfileID, req.URL); err != nil {
+			if errors.Is(err, functions.ErrAuthProfileSite) {
+				badRequest(w, err.Error())
+				return
+			}
+			badRequest(w, "unknown auth_profile_id")
+			return
+		}
 	}
 
-	start := time.Now()
-	resp, err := functions.RunScan(req)
+	job, err := functions.EnqueueScanJob(ctx, req)
+	if errors.Is(err, functions.ErrScanQueueFull) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		http.Error(w, "scan error: "+err.Error(), http.StatusInternalServerError)
+		srvError(w, err)
 		return
 	}
-	resp.ProcessedAt = time.Now().Format(time.RFC3339)
-	resp.PageDuration = time.Since(start).String()
 
-	// ذخیرهٔ نتایج صفحه/اندپوینت‌ها
-	saveCtx, cancelSave := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancelSave()
-	if err := functions.SaveScanResults(saveCtx, req.URL, resp.Resources, resp.UniquePaths, resp.AllScripts); err != nil {
-		log.Printf("[mongo save] url=%s err=%v", req.URL, err)
+	writeJSON(w, http.StatusAccepted, bson.M{
+		"job_id":     job.ID,
+		"state":      job.State,
+		"site_id":    job.SiteID,
+		"url_norm":   job.URLNorm,
+		"status_url": "/api/scan/jobs/" + job.ID,
+	})
+}
+
+// GET /api/scan/jobs/{id}
+func ScanJobHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		badRequest(w, "id is required")
+		return
 	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	// اسکن سینک‌ها: هم استاتیک هم runtime
-	sinksCtx, cancelSinks := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancelSinks()
+	job, err := functions.GetScanJob(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	maskJobSecrets(job)
+	writeJSON(w, http.StatusOK, bson.M{"item": job})
+}
 
-	var sinks []models.SinkDoc
+// GET /api/scan/jobs?site_id=&state=
+func ScanJobsListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
+	defer cancel()
 
-	// 1) اسکن JS/HTML (نسخهٔ موجود خودت: ScanSinks یا ScanSinksGo)
-	if s, err := functions.ScanSinks(sinksCtx, req.URL, siteID); err == nil {
-		sinks = append(sinks, s...)
-	} else {
-		log.Printf("[sinks] static scan error: %v", err)
+	filter := bson.M{}
+	if siteID := strings.TrimSpace(r.URL.Query().Get("site_id")); siteID != "" {
+		filter["site_id"] = siteID
 	}
-
-	// 2) کالکتور runtime (postMessage و …) — urlNorm و siteID را داریم
-	if rt, err := functions.CollectRuntimeSinks(sinksCtx, urlNorm, siteID); err == nil {
-		sinks = append(sinks, rt...)
-	} else {
-		log.Printf("[sinks] runtime collect error: %v", err)
+	if states := strings.TrimSpace(r.URL.Query().Get("state")); states != "" {
+		arr := strings.Split(states, ",")
+		for i := range arr {
+			arr[i] = strings.TrimSpace(arr[i])
+		}
+		filter["state"] = bson.M{"$in": arr}
 	}
 
-	// ست کردن شناسه‌ها اگر خالی باشند
-	for i := range sinks {
-		if sinks[i].SiteID == "" {
-			sinks[i].SiteID = siteID
-		}
-		if sinks[i].PageURL == "" {
-			sinks[i].PageURL = urlNorm
-		}
+	opts := mopts.Find().
+		SetSort(qSort(r, "created_at", -1)).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r))
+
+	cur, err := models.ScanJobsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
 	}
+	defer cur.Close(ctx)
 
-	// Persist فقط یک‌بار، بعد از ست شدن SiteID/PageURL
-	if len(sinks) > 0 {
-		if bwRes, bwErr := functions.PersistSinks(sinksCtx, sinks); bwErr != nil {
-			log.Printf("[sinks] persist error: %v", bwErr)
-		} else {
-			log.Printf("[sinks] bulk write matched=%d modified=%d upserted=%d",
-				bwRes.MatchedCount, bwRes.ModifiedCount, bwRes.UpsertedCount)
-		}
+	items := []models.ScanJobDoc{}
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	for i := range items {
+		maskJobSecrets(&items[i])
 	}
+	total, _ := models.ScanJobsColl().CountDocuments(ctx, filter)
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	_ = json.NewEncoder(w).Encode(resp)
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// maskJobSecrets: رمز پروکسی درخواست در پاسخ API برنمی‌گردد
+func maskJobSecrets(job *models.ScanJobDoc) {
+	if job.Request.Proxy != nil {
+		px := *job.Request.Proxy
+		px.Password = maskSecret(px.Password)
+		job.Request.Proxy = &px
+	}
 }
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/auth-profiles?site_id=
+func AuthProfilesListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if siteID := strings.TrimSpace(r.URL.Query().Get("site_id")); siteID != "" {
+		filter["site_id"] = siteID
+	}
+	cur, err := models.AuthProfilesColl().Find(ctx, filter, mopts.Find().SetSort(bson.D{{Key: "site_id", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	items := []models.AuthProfileDoc{}
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	for i := range items {
+		items[i] = maskAuthProfile(items[i])
+	}
+	writeJSON(w, http.StatusOK, bson.M{"items": items, "total": len(items)})
+}
+
+// GET /api/auth-profiles/{id}
+func AuthProfileGetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	p, err := functions.GetAuthProfile(ctx, r.PathValue("id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "auth profile not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bson.M{"item": maskAuthProfile(*p)})
+}
+
+// POST /api/auth-profiles/create
+// body: { site_id, name, cookies:[...], bearer_token, headers:{...}, login_steps:[...] }
+func AuthProfileCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var p models.AuthProfileDoc
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	if err := functions.ValidateAuthProfile(&p); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	now := time.Now()
+	p.ID = primitive.NewObjectID().Hex()
+	p.CreatedAt, p.UpdatedAt = now, now
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if _, err := models.AuthProfilesColl().InsertOne(ctx, p); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			badRequest(w, "a profile with this name already exists for the site")
+			return
+		}
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bson.M{"ok": true, "item": maskAuthProfile(p)})
+}
+
+// POST /api/auth-profiles/update
+// body: پروفایل کامل با id؛ مقادیری که به همان شکل ماسک‌شده برگردند دست نمی‌خورند
+func AuthProfileUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var p models.AuthProfileDoc
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	if p.ID == "" {
+		badRequest(w, "id is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	old, err := functions.GetAuthProfile(ctx, p.ID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "auth profile not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	restoreMaskedSecrets(&p, old)
+	if err := functions.ValidateAuthProfile(&p); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	p.CreatedAt = old.CreatedAt
+	p.UpdatedAt = time.Now()
+
+	if _, err := models.AuthProfilesColl().ReplaceOne(ctx, bson.M{"_id": p.ID}, p); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			badRequest(w, "a profile with this name already exists for the site")
+			return
+		}
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bson.M{"ok": true, "item": maskAuthProfile(p)})
+}
+
+// POST /api/auth-profiles/delete  { id }
+func AuthProfileDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		badRequest(w, "POST/DELETE only")
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		badRequest(w, "id is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	res, err := models.AuthProfilesColl().DeleteOne(ctx, bson.M{"_id": req.ID})
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	// watchهایی که به این پروفایل اشاره داشتند بدون احراز هویت ادامه می‌دهند
+	upd, _ := models.WatchesColl().UpdateMany(ctx,
+		bson.M{"auth_profile_id": req.ID},
+		bson.M{"$unset": bson.M{"auth_profile_id": ""}},
+	)
+	var detached int64
+	if upd != nil {
+		detached = upd.ModifiedCount
+	}
+	writeJSON(w, http.StatusOK, bson.M{"ok": true, "deleted": res.DeletedCount, "watches_detached": detached})
+}
+
+// helpers
+
+const secretMask = "****"
+
+// maskSecret: پسورد، bearer token و مقدار کوکی کامل ماسک می‌شوند
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return secretMask
+}
+
+// maskHeaderValue: هدرهای دلخواه؛ فقط توکن‌های طولانی (۳۲+ کاراکتر) ۴ کاراکتر اول را برای تشخیص نشان می‌دهند
+func maskHeaderValue(s string) string {
+	r := []rune(s)
+	if len(r) < 32 {
+		return maskSecret(s)
+	}
+	return string(r[:4]) + "…" + secretMask
+}
+
+// stepValueSecret: مقدار هر fill (نام کاربری، پسورد، OTP) ماسک می‌شود، حتی بدون secret: true
+func stepValueSecret(s models.LoginStep) bool {
+	return s.Secret || s.Action == models.StepFill
+}
+
+// maskAuthProfile: کپی پروفایل با مقادیر حساس ماسک‌شده برای پاسخ API
+func maskAuthProfile(p models.AuthProfileDoc) models.AuthProfileDoc {
+	p.BearerToken = maskSecret(p.BearerToken)
+
+	cookies := make([]models.AuthCookie, len(p.Cookies))
+	for i, c := range p.Cookies {
+		c.Value = maskSecret(c.Value)
+		cookies[i] = c
+	}
+	p.Cookies = cookies
+
+	if p.Headers != nil {
+		h := make(map[string]string, len(p.Headers))
+		for k, v := range p.Headers {
+			h[k] = maskHeaderValue(v)
+		}
+		p.Headers = h
+	}
+
+	steps := make([]models.LoginStep, len(p.LoginSteps))
+	for i, s := range p.LoginSteps {
+		if stepValueSecret(s) {
+			s.Value = maskSecret(s.Value)
+		}
+		steps[i] = s
+	}
+	p.LoginSteps = steps
+	return p
+}
+
+// restoreMaskedSecrets: اگر کلاینت مقدار ماسک‌شده را عیناً برگرداند، مقدار واقعی قبلی حفظ می‌شود
+func restoreMaskedSecrets(p, old *models.AuthProfileDoc) {
+	if p.BearerToken != "" && p.BearerToken == maskSecret(old.BearerToken) {
+		p.BearerToken = old.BearerToken
+	}
+
+	oldCookies := map[string]string{}
+	for _, c := range old.Cookies {
+		oldCookies[c.Name] = c.Value
+	}
+	for i, c := range p.Cookies {
+		if v, ok := oldCookies[c.Name]; ok && c.Value == maskSecret(v) {
+			p.Cookies[i].Value = v
+		}
+	}
+
+	for k, v := range p.Headers {
+		if ov, ok := old.Headers[k]; ok && v == maskHeaderValue(ov) {
+			p.Headers[k] = ov
+		}
+	}
+
+	for i, s := range p.LoginSteps {
+		if i >= len(old.LoginSteps) || !stepValueSecret(s) {
+			continue
+		}
+		os := old.LoginSteps[i]
+		if os.Action == s.Action && os.Selector == s.Selector && s.Value == maskSecret(os.Value) {
+			p.LoginSteps[i].Value = os.Value
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"net/http"
+)
+
+// GET /api/browser/pool
+func BrowserPoolStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, functions.GetBrowserPoolStats())
+}
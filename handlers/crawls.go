@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/crawls?site_id=
+func CrawlsListHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
+	defer cancel()
+
+	filter := bson.M{"site_id": siteID}
+	if state := strings.TrimSpace(r.URL.Query().Get("state")); state != "" {
+		filter["state"] = state
+	}
+
+	// لیست صفحات فقط در جزئیات برگردانده می‌شود
+	opts := mopts.Find().
+		SetSort(qSort(r, "started_at", -1)).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r)).
+		SetProjection(bson.M{"pages": 0})
+
+	cur, err := models.CrawlsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var items []bson.M
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.CrawlsColl().CountDocuments(ctx, filter)
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// GET /api/crawls/{id}
+func CrawlGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		badRequest(w, "id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var crawl models.CrawlDoc
+	err := models.CrawlsColl().FindOne(ctx, bson.M{"_id": id}).Decode(&crawl)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "crawl not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bson.M{"item": crawl})
+}
@@ -41,6 +41,24 @@ func EndpointsListHandler(w http.ResponseWriter, r *http.Request) {
 			filter["last_seen"] = bson.M{"$lte": to}
 		}
 	}
+	if origin := strings.TrimSpace(r.URL.Query().Get("origin")); origin != "" {
+		filter["origins"] = origin
+	}
+	if obs := strings.TrimSpace(r.URL.Query().Get("observed")); obs == "1" || obs == "true" {
+		filter["observed"] = true
+	}
+	if method := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("method"))); method != "" {
+		filter["methods"] = method
+	}
+	if ex := strings.TrimSpace(r.URL.Query().Get("extractor")); ex != "" {
+		filter["extractors"] = ex
+	}
+	if c := strings.TrimSpace(r.URL.Query().Get("context")); c != "" {
+		filter["contexts"] = c
+	}
+	if im := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("inferred_method"))); im != "" {
+		filter["inferred_methods"] = im
+	}
 	if minSeen, _ := strconv.Atoi(r.URL.Query().Get("min_seen")); minSeen > 0 {
 		filter["seen_count"] = bson.M{"$gte": minSeen}
 	}
@@ -52,6 +70,17 @@ func EndpointsListHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if r.URL.Query().Get("group_by") == "template" {
+		endpointsByTemplate(ctx, w, r, filter)
+		return
+	}
+	if t := strings.TrimSpace(r.URL.Query().Get("template")); t != "" {
+		filter["template"] = t
+	}
+	if qp := strings.TrimSpace(r.URL.Query().Get("param")); qp != "" {
+		filter["$or"] = bson.A{bson.M{"query_params": qp}, bson.M{"path_params": qp}}
+	}
+
 	opts := mopts.Find().
 		SetSort(qSort(r, "last_seen", -1)).
 		SetLimit(qLimit(r)).
@@ -64,6 +93,18 @@ func EndpointsListHandler(w http.ResponseWriter, r *http.Request) {
 			"last_seen":   1,
 			"hosts":       1,
 			"source_urls": 1,
+			"methods":     1,
+			"observed":    1,
+			"last_status": 1,
+			"origins":     1,
+
+			"extractors":       1,
+			"inferred_methods": 1,
+			"contexts":         1,
+			"template":         1,
+			"path_params":      1,
+			"query_params":     1,
+			"param_examples":   1,
 		})
 
 	cur, err := models.EndpointsColl().Find(ctx, filter, opts)
@@ -85,6 +126,95 @@ func EndpointsListHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// endpointsByTemplate: group_by=template؛ endpointهای لفظی هم‌قالب یک ردیف می‌شوند
+func endpointsByTemplate(ctx context.Context, w http.ResponseWriter, r *http.Request, filter bson.M) {
+	// اسنادی که قبل از قالب‌بندی ذخیره شده‌اند template ندارند؛ خود endpoint قالبشان است
+	tmpl := bson.M{"$ifNull": bson.A{"$template", "$endpoint"}}
+	unionArrays := func(field string) bson.M {
+		return bson.M{"$reduce": bson.M{
+			"input":        "$" + field,
+			"initialValue": bson.A{},
+			"in":           bson.M{"$setUnion": bson.A{"$$value", bson.M{"$ifNull": bson.A{"$$this", bson.A{}}}}},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: filter}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":            tmpl,
+			"count":          bson.M{"$sum": 1},
+			"seen_count":     bson.M{"$sum": "$seen_count"},
+			"first_seen":     bson.M{"$min": "$first_seen"},
+			"last_seen":      bson.M{"$max": "$last_seen"},
+			"observed":       bson.M{"$max": bson.M{"$ifNull": bson.A{"$observed", false}}},
+			"category":       bson.M{"$first": "$category"},
+			"path_params":    bson.M{"$first": "$path_params"},
+			"examples":       bson.M{"$push": "$endpoint"},
+			"query_params":   bson.M{"$push": "$query_params"},
+			"param_examples": bson.M{"$push": "$param_examples"},
+			"methods":        bson.M{"$push": "$methods"},
+			"inferred":       bson.M{"$push": "$inferred_methods"},
+			"extractors":     bson.M{"$push": "$extractors"},
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"template":         "$_id",
+			"count":            1,
+			"seen_count":       1,
+			"first_seen":       1,
+			"last_seen":        1,
+			"observed":         1,
+			"category":         1,
+			"path_params":      1,
+			"examples":         bson.M{"$slice": bson.A{"$examples", 10}},
+			"query_params":     unionArrays("query_params"),
+			"param_examples":   bson.M{"$slice": bson.A{unionArrays("param_examples"), 20}},
+			"methods":          unionArrays("methods"),
+			"inferred_methods": unionArrays("inferred"),
+			"extractors":       unionArrays("extractors"),
+		}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"items": mongo.Pipeline{
+				bson.D{{Key: "$sort", Value: qSort(r, "last_seen", -1)}},
+				bson.D{{Key: "$skip", Value: qSkip(r)}},
+				bson.D{{Key: "$limit", Value: qLimit(r)}},
+			},
+			"total": mongo.Pipeline{
+				bson.D{{Key: "$count", Value: "n"}},
+			},
+		}}},
+	}
+
+	cur, err := models.EndpointsColl().Aggregate(ctx, pipeline)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var out []struct {
+		Items []bson.M `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cur.All(ctx, &out); err != nil || len(out) == 0 {
+		srvError(w, errors.New("aggregation failed"))
+		return
+	}
+	var total int64
+	if len(out[0].Total) > 0 {
+		total = out[0].Total[0].N
+	}
+	items := out[0].Items
+	if items == nil {
+		items = []bson.M{}
+	}
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r), "group_by": "template",
+	})
+}
+
 func EndpointsStatsHandler(w http.ResponseWriter, r *http.Request) {
 	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
 	if siteID == "" {
@@ -102,6 +232,26 @@ func EndpointsStatsHandler(w http.ResponseWriter, r *http.Request) {
 				bson.D{{Key: "$project", Value: bson.M{"category": "$_id", "count": 1, "_id": 0}}},
 				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
 			},
+			"by_extractor": mongo.Pipeline{
+				bson.D{{Key: "$unwind", Value: "$extractors"}},
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$extractors", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"extractor": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_template": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{
+					"_id":        bson.M{"$ifNull": bson.A{"$template", "$endpoint"}},
+					"count":      bson.M{"$sum": 1},
+					"seen_count": bson.M{"$sum": "$seen_count"},
+				}}},
+				bson.D{{Key: "$project", Value: bson.M{"template": "$_id", "count": 1, "seen_count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+				bson.D{{Key: "$limit", Value: 20}},
+			},
+			"templates": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"$ifNull": bson.A{"$template", "$endpoint"}}}}},
+				bson.D{{Key: "$count", Value: "total"}},
+			},
 			"top_endpoints": mongo.Pipeline{
 				bson.D{{Key: "$sort", Value: bson.M{"seen_count": -1}}},
 				bson.D{{Key: "$limit", Value: 20}},
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/findings?site_id=&type=&severity=&page_url=&url=&sink_sig=&origin_check=&from=&to=
+func FindingsListHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"site_id": siteID}
+	if types := strings.TrimSpace(r.URL.Query().Get("type")); types != "" {
+		arr := strings.Split(types, ",")
+		for i := range arr {
+			arr[i] = strings.TrimSpace(arr[i])
+		}
+		filter["type"] = bson.M{"$in": arr}
+	}
+	if sev := strings.TrimSpace(r.URL.Query().Get("severity")); sev != "" {
+		arr := strings.Split(sev, ",")
+		for i := range arr {
+			arr[i] = strings.TrimSpace(arr[i])
+		}
+		filter["severity"] = bson.M{"$in": arr}
+	}
+	if pageURL := strings.TrimSpace(r.URL.Query().Get("page_url")); pageURL != "" {
+		filter["page_url"] = pageURL
+	}
+	if u := strings.TrimSpace(r.URL.Query().Get("url")); u != "" {
+		filter["url"] = rxContains(u)
+	}
+	// taint_flowهای یک sink
+	if sig := strings.TrimSpace(r.URL.Query().Get("sink_sig")); sig != "" {
+		filter["details.sink_sig"] = sig
+	}
+	// postmessage_handlerها بر اساس نتیجهٔ چک origin (missing,wildcard,bypassable,strict,unknown)
+	if oc := qCSV(r, "origin_check"); len(oc) > 0 {
+		filter["details.origin_check"] = bson.M{"$in": oc}
+	}
+	if from, ok := qTime(r, "from"); ok {
+		filter["last_seen"] = bson.M{"$gte": from}
+	}
+	if to, ok := qTime(r, "to"); ok {
+		if m, ok := filter["last_seen"].(bson.M); ok {
+			m["$lte"] = to
+		} else {
+			filter["last_seen"] = bson.M{"$lte": to}
+		}
+	}
+
+	opts := mopts.Find().
+		SetSort(qSort(r, "last_seen", -1)).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r)).
+		SetProjection(bson.M{"_id": 0})
+
+	cur, err := models.FindingsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var items []bson.M
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.FindingsColl().CountDocuments(ctx, filter)
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// GET /api/findings/stats?site_id=
+func FindingsStatsHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"site_id": siteID}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"by_type": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$type", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"type": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_severity": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$severity", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"severity": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"recent": mongo.Pipeline{
+				bson.D{{Key: "$sort", Value: bson.M{"last_seen": -1}}},
+				bson.D{{Key: "$limit", Value: 20}},
+				bson.D{{Key: "$project", Value: bson.M{"type": 1, "severity": 1, "title": 1, "url": 1, "last_seen": 1, "_id": 0}}},
+			},
+		}}},
+	}
+
+	cur, err := models.FindingsColl().Aggregate(ctx, pipeline)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var out []bson.M
+	if err := cur.All(ctx, &out); err != nil || len(out) == 0 {
+		srvError(w, errors.New("aggregation failed"))
+		return
+	}
+	writeJSON(w, http.StatusOK, out[0])
+}
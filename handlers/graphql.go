@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/graphql/operations?site_id=&type=&name=&hash=&source=&field=
+func GraphQLOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"site_id": siteID}
+	if t := strings.TrimSpace(r.URL.Query().Get("type")); t != "" {
+		filter["type"] = t // query | mutation | subscription
+	}
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		filter["name"] = rxContains(name)
+	}
+	if hash := strings.TrimSpace(r.URL.Query().Get("hash")); hash != "" {
+		filter["hash"] = hash
+	}
+	if src := strings.TrimSpace(r.URL.Query().Get("source")); src != "" {
+		filter["sources"] = src // script | network
+	}
+	if field := strings.TrimSpace(r.URL.Query().Get("field")); field != "" {
+		filter["fields"] = field
+	}
+
+	opts := mopts.Find().
+		SetSort(qSort(r, "last_seen", -1)).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r)).
+		SetProjection(bson.M{"_id": 0})
+
+	cur, err := models.GraphQLOpsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var items []bson.M
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.GraphQLOpsColl().CountDocuments(ctx, filter)
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// GET /api/graphql/schemas?site_id= — خلاصهٔ introspection هر endpoint (بدون schema خام)
+func GraphQLSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"site_id": siteID}
+	if r.URL.Query().Get("enabled") == "1" {
+		filter["enabled"] = true
+	}
+	opts := mopts.Find().
+		SetSort(bson.D{{Key: "fetched_at", Value: -1}}).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r)).
+		SetProjection(bson.M{"schema": 0})
+
+	cur, err := models.GraphQLSchemasColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var items []models.GraphQLSchemaDoc
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.GraphQLSchemasColl().CountDocuments(ctx, filter)
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// GET /api/graphql/schema?id=&download=1 — نتیجهٔ کامل introspection ({"data":{"__schema":...}})
+func GraphQLSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		badRequest(w, "id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var doc models.GraphQLSchemaDoc
+	err := models.GraphQLSchemasColl().FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "schema not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if !doc.Enabled || doc.Schema == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "introspection was not available for this endpoint"})
+		return
+	}
+
+	if r.URL.Query().Get("download") == "1" {
+		name := strings.NewReplacer("/", "_", ":", "_").Replace(strings.TrimPrefix(strings.TrimPrefix(doc.Endpoint, "https://"), "http://"))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+strings.Trim(name, "_")+`.schema.json"`)
+	}
+	// همان شکل خروجی introspection تا ابزارهای GraphQL مستقیم بخوانند
+	writeJSON(w, http.StatusOK, bson.M{"data": bson.M{"__schema": json.RawMessage(doc.Schema)}})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	maxHARUploadBytes = 100 << 20
+	// ReadTimeout/WriteTimeout سرور (30s/120s) کل آپلود را می‌پوشاند؛ HAR صد مگابایتی روی لینک معمولی بیشتر طول می‌کشد
+	harUploadTimeout = 15 * time.Minute
+)
+
+// POST /api/import/har?keep_bodies=1 — بدنه: فایل HAR خام یا multipart با فیلد file
+// import در صف jobهای اسکن اجرا می‌شود؛ وضعیت و نتیجه (import) از status_url
+func ImportHARHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	deadline := time.Now().Add(harUploadTimeout)
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		srvError(w, err)
+		return
+	}
+	_ = rc.SetWriteDeadline(deadline)
+	r.Body = http.MaxBytesReader(w, r.Body, maxHARUploadBytes)
+
+	var (
+		src  io.Reader = r.Body
+		name string
+	)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			badRequest(w, "file is required")
+			return
+		}
+		defer f.Close()
+		src, name = f, hdr.Filename
+	}
+
+	job, err := functions.EnqueueHARImport(r.Context(), src, name, r.URL.Query().Get("keep_bodies") == "1")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		badRequest(w, "HAR file is too large")
+		return
+	case errors.Is(err, functions.ErrScanQueueFull):
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, bson.M{
+		"job_id":     job.ID,
+		"state":      job.State,
+		"status_url": "/api/scan/jobs/" + job.ID,
+	})
+}
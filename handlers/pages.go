@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"SiteChecker/functions"
 	"SiteChecker/models"
 	"context"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	mopts "go.mongodb.org/mongo-driver/mongo/options"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -28,6 +30,9 @@ func PagesListHandler(w http.ResponseWriter, r *http.Request) {
 	if host := strings.TrimSpace(r.URL.Query().Get("host")); host != "" {
 		filter["host"] = host
 	}
+	if crawlID := strings.TrimSpace(r.URL.Query().Get("crawl_id")); crawlID != "" {
+		filter["crawl_id"] = crawlID
+	}
 	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
 		filter["$or"] = bson.A{
 			bson.M{"url": rxContains(q)},
@@ -35,6 +40,9 @@ func PagesListHandler(w http.ResponseWriter, r *http.Request) {
 			bson.M{"path": rxContains(q)},
 		}
 	}
+	if origin := strings.TrimSpace(r.URL.Query().Get("origin")); origin != "" {
+		filter["origin"] = origin
+	}
 	if from, ok := qTime(r, "from"); ok {
 		filter["scanned_at"] = bson.M{"$gte": from}
 	}
@@ -62,6 +70,10 @@ func PagesListHandler(w http.ResponseWriter, r *http.Request) {
 		"groups":          1,
 		"resource_groups": 1,
 		"externals":       1,
+		"crawl_id":        1,
+		"origin":          1,
+		"import_id":       1,
+		"proxy":           1,
 	}
 	opts.SetProjection(proj)
 	cur, err := models.PagesColl().Find(ctx, filter, opts)
@@ -90,16 +102,13 @@ func PageByURLHandler(w http.ResponseWriter, r *http.Request) {
 		badRequest(w, "url is required")
 		return
 	}
-	// نرمال‌سازی ساده مثل SaveScanResults
 	u, err := url.Parse(raw)
 	if err != nil || u.Scheme == "" || u.Host == "" {
 		badRequest(w, "invalid url")
 		return
 	}
-	urlNorm := u.Scheme + "://" + u.Host + u.EscapedPath()
-	if u.RawQuery != "" {
-		urlNorm += "?" + u.RawQuery
-	}
+	// همان url_norm که SaveScanResults و page_link جاب‌ها می‌سازند
+	_, urlNorm, _ := functions.PageKeys(raw)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -117,3 +126,96 @@ func PageByURLHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, bson.M{"item": out})
 
 }
+
+// GET /api/pages/requests?url=&type=&method=&status=
+func PageRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimSpace(r.URL.Query().Get("url"))
+	if raw == "" {
+		badRequest(w, "url is required")
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		badRequest(w, "invalid url")
+		return
+	}
+	_, urlNorm, _ := functions.PageKeys(raw)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var doc models.NetworkLogDoc
+	err = models.NetworkColl().FindOne(ctx, bson.M{"page_url": urlNorm}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusOK, bson.M{"items": []models.NetworkRequest{}, "total": 0})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+
+	typ := strings.TrimSpace(r.URL.Query().Get("type"))
+	method := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("method")))
+	status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+	items := make([]models.NetworkRequest, 0, len(doc.Requests))
+	for _, nr := range doc.Requests {
+		if typ != "" && !strings.EqualFold(nr.Type, typ) {
+			continue
+		}
+		if method != "" && nr.Method != method {
+			continue
+		}
+		if status > 0 && nr.Status != status {
+			continue
+		}
+		if r.URL.Query().Get("bodies") != "1" {
+			nr.Body = ""
+		}
+		items = append(items, nr)
+	}
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"page_url":    doc.PageURL,
+		"captured_at": doc.CapturedAt,
+		"truncated":   doc.Truncated,
+		"items":       items,
+		"total":       len(items),
+	})
+}
+
+// GET /api/pages/har?url=&bodies=0&download=1 — ترافیک آخرین اسکن صفحه به‌صورت HAR 1.2
+func PageHARHandler(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimSpace(r.URL.Query().Get("url"))
+	if raw == "" {
+		badRequest(w, "url is required")
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		badRequest(w, "invalid url")
+		return
+	}
+	_, urlNorm, _ := functions.PageKeys(raw)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var doc models.NetworkLogDoc
+	err = models.NetworkColl().FindOne(ctx, bson.M{"page_url": urlNorm}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no traffic recorded for this page"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+
+	har := functions.BuildHAR(&doc, r.URL.Query().Get("bodies") != "0")
+	if r.URL.Query().Get("download") == "1" {
+		name := strings.NewReplacer("/", "_", ":", "_", "?", "_").Replace(u.Host + u.Path)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+strings.Trim(name, "_")+`.har"`)
+	}
+	writeJSON(w, http.StatusOK, har)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// POST /api/prototype-pollution/check  { url }
+// فقط برای hostهای لیست مجاز verify XSS سایت؛ همزمان اجرا می‌شود؛ آلودگی‌های تأییدشده در findings (type=prototype_pollution) ذخیره و برگردانده می‌شوند
+func ProtoPollutionCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	siteID, urlNorm, err := functions.PageKeys(req.URL)
+	if req.URL == "" || err != nil {
+		badRequest(w, "valid url is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	findings, err := functions.CheckPrototypePollution(ctx, siteID, urlNorm, req.URL)
+	if errors.Is(err, functions.ErrXSSVerifyNoAllowList) || errors.Is(err, functions.ErrXSSVerifyOutOfScope) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if err := functions.PersistFindings(ctx, findings); err != nil {
+		srvError(w, err)
+		return
+	}
+	if findings == nil {
+		findings = []models.FindingDoc{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"site_id": siteID, "page_url": urlNorm, "items": findings, "total": len(findings)})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/settings/proxy?site_id=
+func ProxyGetHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	cfg, err := models.GetProxySetting(r.Context(), siteID)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if cfg == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"site_id": siteID, "enabled": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"site_id":         siteID,
+		"enabled":         cfg.Enabled,
+		"url":             cfg.URL,
+		"username":        cfg.Username,
+		"password_masked": maskSecret(cfg.Password),
+		"bypass":          cfg.Bypass,
+		"ignore_tls":      cfg.IgnoreTLS,
+		"updated_at":      cfg.UpdatedAt,
+	})
+}
+
+// POST /api/settings/proxy/set
+// body: { "site_id": "...", "url": "http://127.0.0.1:8080", "username": "", "password": "", "bypass": [], "ignore_tls": true, "enabled": true }
+func ProxySetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var req struct {
+		SiteID string `json:"site_id"`
+		models.ProxyConfig
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	req.SiteID = strings.TrimSpace(req.SiteID)
+	if req.SiteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	if err := functions.ValidateProxy(&req.ProxyConfig); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	upd := bson.M{
+		"site_id":    req.SiteID,
+		"url":        req.URL,
+		"username":   req.Username,
+		"bypass":     req.Bypass,
+		"ignore_tls": req.IgnoreTLS,
+		"updated_at": time.Now(),
+	}
+	// رمز خالی یعنی رمز قبلی بماند (مثل webhook دیسکورد)
+	if req.Password != "" {
+		upd["password"] = req.Password
+	}
+	if req.Username == "" {
+		upd["password"] = ""
+	}
+	if req.Enabled != nil {
+		upd["enabled"] = *req.Enabled
+	}
+
+	id := models.ProxySettingID(req.SiteID)
+	_, err := models.SettingsColl().UpdateByID(r.Context(), id,
+		bson.M{"$set": upd, "$setOnInsert": bson.M{"_id": id}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /api/settings/proxy/delete  { site_id }
+func ProxyDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		badRequest(w, "POST/DELETE only")
+		return
+	}
+	var req struct {
+		SiteID string `json:"site_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.SiteID) == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	res, err := models.SettingsColl().DeleteOne(r.Context(), bson.M{"_id": models.ProxySettingID(strings.TrimSpace(req.SiteID))})
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "deleted": res.DeletedCount})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"io"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const maxRulesBodyBytes = 1 << 20
+
+// GET /api/rules?reload=1 — قوانین فعال سینک (built-in + فایل SINK_RULES_FILE)
+func RulesListHandler(w http.ResponseWriter, r *http.Request) {
+	set := functions.SinkRules()
+	if r.URL.Query().Get("reload") == "1" {
+		set = functions.ReloadSinkRules()
+	}
+	items := set.Rules
+	if items == nil {
+		items = []models.SinkRule{}
+	}
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": len(items),
+		"user_file": set.UserFile, "errors": set.Errors,
+	})
+}
+
+// POST /api/rules/validate — بدنه: فایل قوانین (YAML یا JSON) با شکل {rules: [...]}
+func RulesValidateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRulesBodyBytes))
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	rules, errs := functions.ParseSinkRules(body, "user")
+	if rules == nil {
+		rules = []models.SinkRule{}
+	}
+	if errs == nil {
+		errs = []string{}
+	}
+	writeJSON(w, http.StatusOK, bson.M{"valid": len(errs) == 0, "rules": rules, "errors": errs})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GET /api/scan/jobs/{id}/events — رویدادهای پیشرفت اسکن به‌صورت Server-Sent Events
+func ScanJobEventsHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		badRequest(w, "id is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	job, err := functions.GetScanJob(ctx, id)
+	cancel()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+
+	// WriteTimeout سرور (120s) نباید stream را ببندد
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	lastID, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	send := func(ev functions.ScanEvent) bool {
+		if ev.Seq != 0 && ev.Seq <= lastID {
+			return true
+		}
+		b, _ := json.Marshal(ev)
+		if ev.Seq != 0 {
+			if _, err := fmt.Fprintf(w, "id: %d\n", ev.Seq); err != nil {
+				return false
+			}
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	history, ch, unsubscribe, ok := functions.SubscribeScanEvents(id)
+	defer unsubscribe()
+
+	if !ok {
+		// این instance رویدادی از job ندارد (مثلاً بعد از ری‌استارت) → فقط state را از Mongo دنبال کن
+		streamJobStateFromDB(r.Context(), id, job, send)
+		return
+	}
+
+	for _, ev := range history {
+		if !send(ev) {
+			return
+		}
+	}
+	if ch == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case ev, open := <-ch:
+			if !open {
+				return
+			}
+			if !send(ev) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func streamJobStateFromDB(ctx context.Context, id string, job *models.ScanJobDoc, send func(functions.ScanEvent) bool) {
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	last := ""
+	for {
+		if job.State != last {
+			last = job.State
+			ev := functions.ScanEvent{JobID: id, Type: functions.EvState, State: job.State, Error: job.Error, At: time.Now()}
+			if !send(ev) {
+				return
+			}
+		}
+		if job.State == models.JobDone || job.State == models.JobFailed {
+			ev := functions.ScanEvent{JobID: id, Type: functions.EvDone, State: job.State, Error: job.Error, At: time.Now()}
+			send(ev)
+			return
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+		qctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		next, err := functions.GetScanJob(qctx, id)
+		cancel()
+		if err != nil {
+			return
+		}
+		job = next
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GET /api/scripts/versions?url=&site_id=&limit=&skip= — نسخه‌های یک URL اسکریپت (جدیدترین اول)
+func ScriptVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	srcURL := strings.TrimSpace(r.URL.Query().Get("url"))
+	if srcURL == "" {
+		badRequest(w, "url is required")
+		return
+	}
+	filter := bson.M{"url": srcURL}
+	if site := strings.TrimSpace(r.URL.Query().Get("site_id")); site != "" {
+		filter["site_id"] = site
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "first_seen", Value: -1}}}},
+		{{Key: "$skip", Value: qSkip(r)}},
+		{{Key: "$limit", Value: qLimit(r)}},
+		{{Key: "$project", Value: bson.M{
+			"_id": 0, "id": "$_id", "site_id": 1, "url": 1, "sha256": 1, "size": 1,
+			"first_seen": 1, "last_seen": 1, "page_url": 1,
+			"endpoints": bson.M{"$size": bson.M{"$ifNull": bson.A{"$endpoints", bson.A{}}}},
+			"sinks":     bson.M{"$size": bson.M{"$ifNull": bson.A{"$sinks", bson.A{}}}},
+		}}},
+	}
+	cur, err := models.ScriptVersionsColl().Aggregate(ctx, pipeline)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	items := []bson.M{}
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.ScriptVersionsColl().CountDocuments(ctx, filter)
+	writeJSON(w, http.StatusOK, bson.M{"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r)})
+}
+
+// GET /api/scripts/get?sha256=&download=1 — محتوای یک نسخه (همان hash داخل page_snapshots)
+func ScriptGetHandler(w http.ResponseWriter, r *http.Request) {
+	sha := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("sha256")))
+	if sha == "" {
+		badRequest(w, "sha256 is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	body, err := functions.LoadScriptBody(ctx, sha)
+	if errors.Is(err, functions.ErrScriptNotStored) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if r.URL.Query().Get("download") == "1" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+sha[:min(12, len(sha))]+`.js"`)
+	}
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(body))
+}
+
+type scriptDiffResp struct {
+	From   models.ScriptVersionDoc    `json:"from"`
+	To     models.ScriptVersionDoc    `json:"to"`
+	Change models.ScriptVersionChange `json:"change"`
+	Diff   string                     `json:"diff"`
+	Exact  bool                       `json:"exact"` // false: تغییرات زیاد بود و بخش میانی یک‌جا آمد
+	Error  string                     `json:"diff_error,omitempty"`
+}
+
+// GET /api/scripts/diff?url=&site_id=&from=&to=&context=3&format=text
+// from/to: sha256 نسخه؛ پیش‌فرض to = آخرین نسخه و from = نسخهٔ قبل از to
+func ScriptDiffHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	srcURL := strings.TrimSpace(q.Get("url"))
+	if srcURL == "" {
+		badRequest(w, "url is required")
+		return
+	}
+	siteID := strings.TrimSpace(q.Get("site_id"))
+	ctxLines := 3
+	if v, err := strconv.Atoi(q.Get("context")); err == nil && v >= 0 && v <= 50 {
+		ctxLines = v
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	to, err := functions.FindScriptVersion(ctx, siteID, srcURL, strings.ToLower(q.Get("to")), time.Time{})
+	if err != nil {
+		scriptVersionError(w, err, "to")
+		return
+	}
+	from, err := functions.FindScriptVersion(ctx, siteID, srcURL, strings.ToLower(q.Get("from")), to.FirstSeen)
+	if err != nil {
+		scriptVersionError(w, err, "from")
+		return
+	}
+
+	out := scriptDiffResp{From: *from, To: *to, Change: functions.CompareScriptVersions(from, to), Exact: true}
+	a, errA := functions.LoadScriptBody(ctx, from.SHA256)
+	b, errB := functions.LoadScriptBody(ctx, to.SHA256)
+	if err := errors.Join(errA, errB); err != nil {
+		out.Error = err.Error()
+	} else {
+		name := path.Base(srcURL)
+		out.Diff, out.Exact = functions.UnifiedDiff(name+"@"+from.SHA256[:12], name+"@"+to.SHA256[:12], a, b, ctxLines)
+	}
+
+	if q.Get("format") == "text" {
+		if out.Error != "" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": out.Error})
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(out.Diff))
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func scriptVersionError(w http.ResponseWriter, err error, key string) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no script version for " + key})
+		return
+	}
+	srvError(w, err)
+}
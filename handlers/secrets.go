@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/secrets?site_id=&rule=&severity=&source_type=&source_url=&page_url=&value_hash=&from=&to=
+func SecretsListHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"site_id": siteID}
+	if rules := qCSV(r, "rule"); len(rules) > 0 {
+		filter["rule_id"] = bson.M{"$in": rules}
+	}
+	if sev := qCSV(r, "severity"); len(sev) > 0 {
+		filter["severity"] = bson.M{"$in": sev}
+	}
+	if st := strings.TrimSpace(r.URL.Query().Get("source_type")); st != "" {
+		filter["source_type"] = st
+	}
+	if su := strings.TrimSpace(r.URL.Query().Get("source_url")); su != "" {
+		filter["source_url"] = rxContains(su)
+	}
+	if pageURL := strings.TrimSpace(r.URL.Query().Get("page_url")); pageURL != "" {
+		filter["page_url"] = pageURL
+	}
+	if vh := strings.TrimSpace(r.URL.Query().Get("value_hash")); vh != "" {
+		filter["value_hash"] = vh
+	}
+	if from, ok := qTime(r, "from"); ok {
+		filter["first_seen"] = bson.M{"$gte": from}
+	}
+	if to, ok := qTime(r, "to"); ok {
+		if m, ok := filter["first_seen"].(bson.M); ok {
+			m["$lte"] = to
+		} else {
+			filter["first_seen"] = bson.M{"$lte": to}
+		}
+	}
+
+	opts := mopts.Find().
+		SetSort(qSort(r, "last_seen", -1)).
+		SetLimit(qLimit(r)).
+		SetSkip(qSkip(r)).
+		SetProjection(bson.M{"_id": 0})
+
+	cur, err := models.SecretsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var items []bson.M
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.SecretsColl().CountDocuments(ctx, filter)
+
+	writeJSON(w, http.StatusOK, bson.M{
+		"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r),
+	})
+}
+
+// GET /api/secrets/stats?site_id=
+func SecretsStatsHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"site_id": siteID}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"by_rule": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$rule_id", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"rule_id": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_severity": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$severity", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"severity": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_source": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$source_url", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"source_url": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+				bson.D{{Key: "$limit", Value: 20}},
+			},
+			// یک مقدار در چند جا = یک secret
+			"distinct_values": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$value_hash"}}},
+				bson.D{{Key: "$count", Value: "count"}},
+			},
+			"recent": mongo.Pipeline{
+				bson.D{{Key: "$sort", Value: bson.M{"first_seen": -1}}},
+				bson.D{{Key: "$limit", Value: 20}},
+				bson.D{{Key: "$project", Value: bson.M{"rule_id": 1, "severity": 1, "masked": 1, "source_url": 1, "first_seen": 1, "_id": 0}}},
+			},
+		}}},
+	}
+
+	cur, err := models.SecretsColl().Aggregate(ctx, pipeline)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var out []bson.M
+	if err := cur.All(ctx, &out); err != nil || len(out) == 0 {
+		srvError(w, errors.New("aggregation failed"))
+		return
+	}
+	writeJSON(w, http.StatusOK, out[0])
+}
@@ -40,6 +40,18 @@ func SinksListHandler(w http.ResponseWriter, r *http.Request) {
 	if fn := strings.TrimSpace(r.URL.Query().Get("func")); fn != "" {
 		filter["func"] = rxContains(fn)
 	}
+	if origin := strings.TrimSpace(r.URL.Query().Get("origin")); origin != "" {
+		filter["origin"] = origin
+	}
+	if st := strings.TrimSpace(r.URL.Query().Get("source_type")); st != "" {
+		filter["source_type"] = st
+	}
+	if bundle := strings.TrimSpace(r.URL.Query().Get("bundle_url")); bundle != "" {
+		filter["bundle_url"] = rxContains(bundle)
+	}
+	if !sinkClassFilter(w, r, filter) {
+		return
+	}
 	if from, ok := qTime(r, "from"); ok {
 		filter["last_detected_at"] = bson.M{"$gte": from}
 	}
@@ -56,6 +68,7 @@ func SinksListHandler(w http.ResponseWriter, r *http.Request) {
 		SetLimit(qLimit(r)).
 		SetSkip(qSkip(r)).
 		SetProjection(bson.M{
+			"sig":               1, // برای /api/findings?sink_sig=
 			"site_id":           1,
 			"page_url":          1,
 			"source_type":       1,
@@ -68,6 +81,14 @@ func SinksListHandler(w http.ResponseWriter, r *http.Request) {
 			"hits":              1,
 			"first_detected_at": 1,
 			"last_detected_at":  1,
+			"origin":            1,
+			"import_id":         1,
+			"bundle_url":        1,
+			"severity":          1,
+			"confidence":        1,
+			"cwe":               1,
+			"owasp":             1,
+			"verification":      1,
 		})
 
 	cur, err := models.SinksColl().Find(ctx, filter, opts)
@@ -102,6 +123,9 @@ func SinksStatsHandler(w http.ResponseWriter, r *http.Request) {
 	if pageURL := strings.TrimSpace(r.URL.Query().Get("page_url")); pageURL != "" {
 		match["page_url"] = pageURL
 	}
+	if !sinkClassFilter(w, r, match) {
+		return
+	}
 
 	pipeline := mongo.Pipeline{
 		bson.D{{Key: "$match", Value: match}},
@@ -111,10 +135,38 @@ func SinksStatsHandler(w http.ResponseWriter, r *http.Request) {
 				bson.D{{Key: "$project", Value: bson.M{"kind": "$_id", "count": 1, "_id": 0}}},
 				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
 			},
+			// به ترتیب شدت (نه تعداد)
+			"by_severity": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$severity", "rank": bson.M{"$max": "$severity_rank"}, "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$sort", Value: bson.M{"rank": -1}}},
+				bson.D{{Key: "$project", Value: bson.M{"severity": "$_id", "count": 1, "_id": 0}}},
+			},
+			"by_confidence": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$confidence", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"confidence": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_cwe": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$cwe", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"cwe": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			"by_owasp": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": "$owasp", "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$project", Value: bson.M{"owasp": "$_id", "count": 1, "_id": 0}}},
+				bson.D{{Key: "$sort", Value: bson.M{"count": -1}}},
+			},
+			// شدیدترین kindها برای اولویت‌بندی
+			"top": mongo.Pipeline{
+				bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"kind": "$kind", "severity": "$severity"}, "rank": bson.M{"$max": "$severity_rank"}, "count": bson.M{"$sum": 1}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "rank", Value: -1}, {Key: "count", Value: -1}}}},
+				bson.D{{Key: "$limit", Value: 10}},
+				bson.D{{Key: "$project", Value: bson.M{"kind": "$_id.kind", "severity": "$_id.severity", "count": 1, "_id": 0}}},
+			},
 			"recent": mongo.Pipeline{
 				bson.D{{Key: "$sort", Value: bson.M{"last_detected_at": -1}}},
 				bson.D{{Key: "$limit", Value: 20}},
-				bson.D{{Key: "$project", Value: bson.M{"kind": 1, "last_detected_at": 1, "source_url": 1, "_id": 0}}},
+				bson.D{{Key: "$project", Value: bson.M{"kind": 1, "severity": 1, "confidence": 1, "last_detected_at": 1, "source_url": 1, "_id": 0}}},
 			},
 		}}},
 	}
@@ -133,3 +185,32 @@ func SinksStatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	writeJSON(w, http.StatusOK, out[0])
 }
+
+// sinkClassFilter: severity / min_severity / confidence / cwe / owasp (مشترک list و stats)
+func sinkClassFilter(w http.ResponseWriter, r *http.Request, filter bson.M) bool {
+	if sev := qCSV(r, "severity"); len(sev) > 0 {
+		filter["severity"] = bson.M{"$in": sev}
+	}
+	if minSev := strings.TrimSpace(r.URL.Query().Get("min_severity")); minSev != "" {
+		rank := models.SeverityRank(minSev)
+		if rank < 0 {
+			badRequest(w, "min_severity must be one of info, low, medium, high, critical")
+			return false
+		}
+		filter["severity_rank"] = bson.M{"$gte": rank}
+	}
+	if conf := qCSV(r, "confidence"); len(conf) > 0 {
+		filter["confidence"] = bson.M{"$in": conf}
+	}
+	if cwe := qCSV(r, "cwe"); len(cwe) > 0 {
+		filter["cwe"] = bson.M{"$in": cwe}
+	}
+	if owasp := qCSV(r, "owasp"); len(owasp) > 0 {
+		filter["owasp"] = bson.M{"$in": owasp}
+	}
+	// verified=1: فقط sinkهایی که با payload canary تأیید شده‌اند
+	if v := r.URL.Query().Get("verified"); v == "1" || v == "true" {
+		filter["verification"] = bson.M{"$exists": true}
+	}
+	return true
+}
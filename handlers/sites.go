@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"SiteChecker/functions"
 	"SiteChecker/models"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	mopts "go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
 )
 
 func SitesListHandler(w http.ResponseWriter, r *http.Request) {
@@ -88,8 +92,37 @@ func SiteDeleteHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 4. حذف Watches
 	watchesResult, _ := models.WatchesColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+	watchRunsResult, _ := models.WatchRunsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
 
-	// 5. حذف Site اصلی
+	// 5. حذف Crawlها
+	crawlsResult, _ := models.CrawlsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 6. حذف لاگ‌های شبکه
+	networkResult, _ := models.NetworkColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 7. حذف پروفایل‌های احراز هویت
+	authResult, _ := models.AuthProfilesColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 8. حذف تنظیم پروکسی سایت
+	_, _ = models.SettingsColl().DeleteOne(ctx, bson.M{"_id": models.ProxySettingID(siteID)})
+	_, _ = models.SettingsColl().DeleteOne(ctx, bson.M{"_id": models.XSSVerifySettingID(siteID)})
+
+	// 9. حذف findingها
+	findingsResult, _ := models.FindingsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 10. حذف عملیات و schemaهای GraphQL
+	gqlOpsResult, _ := models.GraphQLOpsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+	gqlSchemasResult, _ := models.GraphQLSchemasColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 11. حذف secretها
+	secretsResult, _ := models.SecretsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 12. حذف تاریخچهٔ snapshotها
+	snapshotsResult, _ := models.PageSnapshotsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+	// محتوای اسکریپت‌ها (scripts) با hash بین سایت‌ها مشترک است و می‌ماند
+	scriptVersionsResult, _ := models.ScriptVersionsColl().DeleteMany(ctx, bson.M{"site_id": siteID})
+
+	// 13. حذف Site اصلی
 	siteResult, err := models.SitesColl().DeleteOne(ctx, bson.M{"_id": siteID})
 	if err != nil {
 		srvError(w, err)
@@ -100,11 +133,68 @@ func SiteDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		"ok":      true,
 		"site_id": siteID,
 		"deleted": bson.M{
-			"site":      siteResult.DeletedCount,
-			"pages":     pagesResult.DeletedCount,
-			"endpoints": endpointsResult.DeletedCount,
-			"sinks":     sinksResult.DeletedCount,
-			"watches":   watchesResult.DeletedCount,
+			"site":               siteResult.DeletedCount,
+			"pages":              pagesResult.DeletedCount,
+			"endpoints":          endpointsResult.DeletedCount,
+			"sinks":              sinksResult.DeletedCount,
+			"watches":            watchesResult.DeletedCount,
+			"watch_runs":         watchRunsResult.DeletedCount,
+			"crawls":             crawlsResult.DeletedCount,
+			"network":            networkResult.DeletedCount,
+			"auth":               authResult.DeletedCount,
+			"findings":           findingsResult.DeletedCount,
+			"graphql_operations": gqlOpsResult.DeletedCount,
+			"graphql_schemas":    gqlSchemasResult.DeletedCount,
+			"secrets":            secretsResult.DeletedCount,
+			"page_snapshots":     snapshotsResult.DeletedCount,
+			"script_versions":    scriptVersionsResult.DeletedCount,
 		},
 	})
 }
+
+// GET /api/sites/openapi?site_id=&format=yaml|json&download=1 — سند OpenAPI 3.1 از endpointهای کشف‌شده
+func SiteOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	if format == "" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "json" {
+		badRequest(w, "format must be yaml or json")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	doc, err := functions.BuildOpenAPI(ctx, siteID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "site not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("download") == "1" {
+		name := strings.NewReplacer("/", "_", ":", "_").Replace(siteID)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.openapi.`+format+`"`)
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, doc)
+		return
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// snapshotURLNorm: url صفحه (خام یا url_norm) → url_norm
+func snapshotURLNorm(w http.ResponseWriter, r *http.Request) (string, bool) {
+	raw := strings.TrimSpace(r.URL.Query().Get("url"))
+	if raw == "" {
+		badRequest(w, "url is required")
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		badRequest(w, "invalid url")
+		return "", false
+	}
+	_, urlNorm, _ := functions.PageKeys(raw)
+	return urlNorm, true
+}
+
+// GET /api/pages/snapshots?url=&limit=&skip= — تاریخچهٔ اسکن‌های صفحه (جدیدترین اول، بدون لیست‌ها)
+func PageSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	urlNorm, ok := snapshotURLNorm(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"url_norm": urlNorm}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "scanned_at", Value: -1}}}},
+		{{Key: "$skip", Value: qSkip(r)}},
+		{{Key: "$limit", Value: qLimit(r)}},
+		{{Key: "$project", Value: bson.M{
+			"_id": 0, "id": "$_id", "scanned_at": 1, "origin": 1, "crawl_id": 1, "import_id": 1, "title": 1,
+			"html_hash": 1, "html_size": 1, "sinks_at": 1,
+			"endpoints":      bson.M{"$size": bson.M{"$ifNull": bson.A{"$endpoints", bson.A{}}}},
+			"scripts":        bson.M{"$size": bson.M{"$ifNull": bson.A{"$scripts", bson.A{}}}},
+			"external_hosts": bson.M{"$size": bson.M{"$ifNull": bson.A{"$external_hosts", bson.A{}}}},
+			"sinks":          bson.M{"$size": bson.M{"$ifNull": bson.A{"$sinks", bson.A{}}}},
+			"secrets":        bson.M{"$size": bson.M{"$ifNull": bson.A{"$secrets", bson.A{}}}},
+		}}},
+	}
+	cur, err := models.PageSnapshotsColl().Aggregate(ctx, pipeline)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	items := []bson.M{}
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.PageSnapshotsColl().CountDocuments(ctx, filter)
+	writeJSON(w, http.StatusOK, bson.M{"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r)})
+}
+
+// GET /api/pages/snapshots/get?id= — یک snapshot کامل
+func PageSnapshotGetHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		badRequest(w, "id is required")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var snap models.PageSnapshot
+	err := models.PageSnapshotsColl().FindOne(ctx, bson.M{"_id": id}).Decode(&snap)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "snapshot not found"})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+// GET /api/pages/diff?url=&from=&to=
+// from/to: شناسهٔ snapshot یا زمان RFC3339 (آخرین snapshot تا آن زمان)؛
+// پیش‌فرض to = آخرین اسکن و from = اسکن قبل از to
+func PageDiffHandler(w http.ResponseWriter, r *http.Request) {
+	urlNorm, ok := snapshotURLNorm(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	to, err := findSnapshot(ctx, r, urlNorm, "to", time.Time{})
+	if err != nil {
+		snapshotError(w, err, "to")
+		return
+	}
+	from, err := findSnapshot(ctx, r, urlNorm, "from", to.ScannedAt)
+	if err != nil {
+		snapshotError(w, err, "from")
+		return
+	}
+	writeJSON(w, http.StatusOK, functions.DiffSnapshots(from, to))
+}
+
+// findSnapshot: مقدار پارامتر key (شناسه یا زمان)؛ خالی یعنی آخرین snapshot (یا آخرین قبل از before)
+func findSnapshot(ctx context.Context, r *http.Request, urlNorm, key string, before time.Time) (*models.PageSnapshot, error) {
+	val := strings.TrimSpace(r.URL.Query().Get(key))
+	filter := bson.M{"url_norm": urlNorm}
+	switch t, isTime := qTime(r, key); {
+	case isTime:
+		filter["scanned_at"] = bson.M{"$lte": t}
+	case val != "":
+		filter["_id"] = val
+	case !before.IsZero():
+		filter["scanned_at"] = bson.M{"$lt": before}
+	}
+	var snap models.PageSnapshot
+	opts := mopts.FindOne().SetSort(bson.D{{Key: "scanned_at", Value: -1}})
+	if err := models.PageSnapshotsColl().FindOne(ctx, filter, opts).Decode(&snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func snapshotError(w http.ResponseWriter, err error, key string) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no snapshot for " + key})
+		return
+	}
+	srvError(w, err)
+}
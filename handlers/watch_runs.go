@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/watches/runs?site_id=&url_norm=|url=&status=&trigger=&changed=1&since=&until=&limit=&skip=
+func WatchRunsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := bson.M{}
+	if site := strings.TrimSpace(q.Get("site_id")); site != "" {
+		filter["site_id"] = site
+	}
+	urlNorm := strings.TrimSpace(q.Get("url_norm"))
+	if urlNorm == "" && q.Get("url") != "" {
+		var siteID string
+		siteID, urlNorm = deriveSiteAndNorm(q.Get("url"))
+		if _, ok := filter["site_id"]; !ok {
+			filter["site_id"] = siteID
+		}
+	}
+	if urlNorm != "" {
+		filter["url_norm"] = urlNorm
+	}
+	if st := qCSV(r, "status"); len(st) > 0 {
+		filter["status"] = bson.M{"$in": st}
+	}
+	if tr := strings.TrimSpace(q.Get("trigger")); tr != "" {
+		filter["trigger"] = tr
+	}
+	if q.Get("changed") == "1" {
+		filter["changed"] = true
+	}
+	started := bson.M{}
+	if t, ok := qTime(r, "since"); ok {
+		started["$gte"] = t
+	}
+	if t, ok := qTime(r, "until"); ok {
+		started["$lte"] = t
+	}
+	if len(started) > 0 {
+		filter["started_at"] = started
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetSkip(qSkip(r)).
+		SetLimit(qLimit(r))
+	cur, err := models.WatchRunsColl().Find(ctx, filter, opts)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	items := []models.WatchRunDoc{}
+	if err := cur.All(ctx, &items); err != nil {
+		srvError(w, err)
+		return
+	}
+	total, _ := models.WatchRunsColl().CountDocuments(ctx, filter)
+	writeJSON(w, http.StatusOK, bson.M{"items": items, "total": total, "limit": qLimit(r), "skip": qSkip(r)})
+}
+
+// GET /api/settings/watch-runs
+func WatchRunsSettingGetHandler(w http.ResponseWriter, r *http.Request) {
+	cfg, err := models.GetWatchRunsSetting(r.Context())
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// POST /api/settings/watch-runs/set
+// body: { "retention_days": 30 } — روی اجراهای قبلی هم اعمال می‌شود
+func WatchRunsSettingSetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var req struct {
+		RetentionDays int `json:"retention_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	if req.RetentionDays < 1 || req.RetentionDays > models.MaxWatchRunRetentionDays {
+		badRequest(w, "retention_days must be between 1 and 365")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	_, err := models.SettingsColl().UpdateByID(ctx, models.WatchRunsSettingID, bson.M{
+		"$set": bson.M{"retention_days": req.RetentionDays, "updated_at": now},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if err := functions.ApplyWatchRunRetention(ctx, req.RetentionDays); err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.WatchRunsSetting{ID: models.WatchRunsSettingID, RetentionDays: req.RetentionDays, UpdatedAt: now})
+}
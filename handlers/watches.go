@@ -6,6 +6,7 @@ import (
 	"SiteChecker/models"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"strings"
@@ -67,6 +68,12 @@ type watchCreateReq struct {
 	SiteID  string `json:"site_id"`
 	FreqMin int    `json:"freq_min"`
 	Enabled bool   `json:"enabled"`
+
+	AuthProfileID string `json:"auth_profile_id"` // اختیاری؛ "" یعنی بدون احراز هویت
+	// اختیاری؛ info..critical یا "" برای حذف. نبودن فیلد = بدون تغییر
+	MinSeverity *string `json:"min_severity"`
+	// اختیاری؛ URL اسکریپت‌هایی که تغییرشان جدا اعلان شود ("*" = همه). نبودن فیلد = بدون تغییر
+	Scripts *[]string `json:"scripts"`
 }
 
 // POST /api/watches/create
@@ -93,6 +100,37 @@ func WatchCreateHandler(w http.ResponseWriter, r *http.Request) {
 	if req.SiteID != "" {
 		siteID = req.SiteID
 	}
+	if req.AuthProfileID != "" {
+		if _, err := functions.AuthProfileForURL(r.Context(), req.AuthProfileID, req.URL); err != nil {
+			if errors.Is(err, functions.ErrAuthProfileSite) {
+				badRequest(w, err.Error())
+				return
+			}
+			badRequest(w, "unknown auth_profile_id")
+			return
+		}
+	}
+
+	if req.MinSeverity != nil && *req.MinSeverity != "" && models.SeverityRank(*req.MinSeverity) < 0 {
+		badRequest(w, "min_severity must be one of info, low, medium, high, critical")
+		return
+	}
+
+	var scripts []string
+	if req.Scripts != nil {
+		for _, su := range *req.Scripts {
+			su = strings.TrimSpace(su)
+			if su == "" {
+				continue
+			}
+			if u, err := url.Parse(su); su != "*" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+				badRequest(w, "scripts must be absolute http(s) URLs or \"*\"")
+				return
+			}
+			scripts = append(scripts, su)
+		}
+	}
+
 	now := time.Now()
 	next := now.Add(time.Duration(req.FreqMin) * time.Minute)
 
@@ -100,19 +138,27 @@ func WatchCreateHandler(w http.ResponseWriter, r *http.Request) {
 
 	update := bson.M{
 		"$set": bson.M{
-			"site_id":     siteID, // در هر حالتی ست کنیم تا همواره درست بماند
-			"url":         req.URL,
-			"url_norm":    urlNorm,
-			"enabled":     req.Enabled,
-			"freq_min":    req.FreqMin,
-			"next_run_at": next,
-			"updated_at":  now,
+			"site_id":         siteID, // در هر حالتی ست کنیم تا همواره درست بماند
+			"url":             req.URL,
+			"url_norm":        urlNorm,
+			"enabled":         req.Enabled,
+			"freq_min":        req.FreqMin,
+			"next_run_at":     next,
+			"updated_at":      now,
+			"auth_profile_id": req.AuthProfileID,
 		},
 		"$setOnInsert": bson.M{
 			"created_at": now, // فقط فیلدهای مخصوص insert
 		},
 	}
 
+	if req.MinSeverity != nil {
+		update["$set"].(bson.M)["min_severity"] = *req.MinSeverity
+	}
+	if req.Scripts != nil {
+		update["$set"].(bson.M)["scripts"] = scripts
+	}
+
 	_, err := models.WatchesColl().UpdateOne(r.Context(), filter, update, options.Update().SetUpsert(true))
 	if err != nil {
 		srvError(w, err)
@@ -175,32 +221,14 @@ func WatchScanNowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// اسکن فوری
-	resp, err := functions.RunScan(models.ScanRequest{
-		URL:            wdoc.URL,
-		WaitSec:        7,
-		JSFetchTimeout: 8,
-	})
-	if err != nil {
-		srvError(w, err)
+	// اسکن فوری (مثل اجرای زمان‌بندی؛ در watch_runs ثبت می‌شود)
+	run := functions.RunWatch(ctx, wdoc, models.WatchTriggerManual)
+	if run.Status == models.WatchRunFailed {
+		srvError(w, errors.New(run.Error))
 		return
 	}
-	if resp != nil {
-		_ = functions.SaveScanResults(ctx, wdoc.URL, resp.Resources, resp.UniquePaths, resp.AllScripts)
-	}
 
-	now := time.Now()
-	next := now.Add(time.Duration(maxInt(5, wdoc.FreqMin)) * time.Minute)
-	_, _ = models.WatchesColl().UpdateOne(ctx,
-		bson.M{"site_id": siteID, "url_norm": urlNorm},
-		bson.M{"$set": bson.M{
-			"last_run_at": now,
-			"next_run_at": next,
-			"updated_at":  time.Now(),
-		}},
-	)
-
-	writeJSON(w, http.StatusOK, bson.M{"ok": true, "site_id": siteID, "url_norm": urlNorm})
+	writeJSON(w, http.StatusOK, bson.M{"ok": true, "site_id": siteID, "url_norm": urlNorm, "run": run})
 }
 
 // POST /api/watches/delete  { url_norm | url }
@@ -235,12 +263,6 @@ func WatchDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		srvError(w, err)
 		return
 	}
+	_, _ = models.WatchRunsColl().DeleteMany(r.Context(), bson.M{"site_id": siteID, "url_norm": urlNorm})
 	writeJSON(w, http.StatusOK, bson.M{"ok": true, "deleted": res.DeletedCount, "site_id": siteID, "url_norm": urlNorm})
 }
-
-func maxInt(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
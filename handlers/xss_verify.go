@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"SiteChecker/functions"
+	"SiteChecker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GET /api/settings/xss-verify?site_id=
+func XSSVerifyGetHandler(w http.ResponseWriter, r *http.Request) {
+	siteID := strings.TrimSpace(r.URL.Query().Get("site_id"))
+	if siteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	cfg, err := models.GetXSSVerifySetting(r.Context(), siteID)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	if cfg == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"site_id": siteID, "allow_hosts": []string{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// POST /api/settings/xss-verify/set
+// body: { "site_id": "...", "allow_hosts": ["example.com", "*.staging.example.com"] } — لیست خالی یعنی غیرفعال
+func XSSVerifySetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var req struct {
+		SiteID     string   `json:"site_id"`
+		AllowHosts []string `json:"allow_hosts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	req.SiteID = strings.TrimSpace(req.SiteID)
+	if req.SiteID == "" {
+		badRequest(w, "site_id is required")
+		return
+	}
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, h := range req.AllowHosts {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		h, err := functions.NormalizeAllowHost(h)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+
+	id := models.XSSVerifySettingID(req.SiteID)
+	_, err := models.SettingsColl().UpdateByID(r.Context(), id,
+		bson.M{
+			"$set":         bson.M{"site_id": req.SiteID, "allow_hosts": hosts, "updated_at": time.Now()},
+			"$setOnInsert": bson.M{"_id": id},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "allow_hosts": hosts})
+}
+
+// POST /api/xss/verify  { site_id, page_url, url? }
+// page_url همان url_norm صفحه (کلید taint flowها)؛ url آدرس ناوبری (پیش‌فرض page_url)
+func XSSVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		badRequest(w, "POST only")
+		return
+	}
+	var req struct {
+		SiteID  string `json:"site_id"`
+		PageURL string `json:"page_url"`
+		URL     string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	req.SiteID = strings.TrimSpace(req.SiteID)
+	req.PageURL = strings.TrimSpace(req.PageURL)
+	if req.SiteID == "" || req.PageURL == "" {
+		badRequest(w, "site_id and page_url are required")
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		req.URL = req.PageURL
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Minute)
+	defer cancel()
+	res, err := functions.VerifyDOMXSS(ctx, req.SiteID, req.PageURL, strings.TrimSpace(req.URL))
+	if errors.Is(err, functions.ErrXSSVerifyNoAllowList) || errors.Is(err, functions.ErrXSSVerifyOutOfScope) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		srvError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
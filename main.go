@@ -30,23 +30,56 @@ func main() {
 		defer cancel()
 		_ = models.Mongo.Disconnect(ctx)
 	}()
+	functions.StartBrowserPool(rootCtx)
+	functions.StartScanWorkers(rootCtx, 0)
+	functions.StartWatchScheduler(rootCtx)
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/scan", handlers.ScanHandler)
 	mux.HandleFunc("/api/scan", handlers.ScanHandler)
+	mux.HandleFunc("/api/scan/jobs", handlers.WithCORS(handlers.ScanJobsListHandler))
+	mux.HandleFunc("/api/scan/jobs/{id}", handlers.WithCORS(handlers.ScanJobHandler))
+	mux.HandleFunc("/api/scan/jobs/{id}/events", handlers.WithCORS(handlers.ScanJobEventsHandler)) // SSE
 
 	mux.HandleFunc("/api/health", handlers.WithCORS(handlers.HealthHandler))
+	mux.HandleFunc("/api/browser/pool", handlers.WithCORS(handlers.BrowserPoolStatsHandler))
 
 	mux.HandleFunc("/api/sites", handlers.WithCORS(handlers.SitesListHandler))
 	mux.HandleFunc("/api/sites/delete", handlers.WithCORS(handlers.SiteDeleteHandler))
+	mux.HandleFunc("/api/sites/openapi", handlers.WithCORS(handlers.SiteOpenAPIHandler)) // ?format=yaml|json
 
 	mux.HandleFunc("/api/pages", handlers.WithCORS(handlers.PagesListHandler))
 	mux.HandleFunc("/api/pages/by-url", handlers.WithCORS(handlers.PageByURLHandler))
+	mux.HandleFunc("/api/pages/requests", handlers.WithCORS(handlers.PageRequestsHandler))
+	mux.HandleFunc("/api/pages/har", handlers.WithCORS(handlers.PageHARHandler))
+	mux.HandleFunc("/api/pages/snapshots", handlers.WithCORS(handlers.PageSnapshotsHandler))
+	mux.HandleFunc("/api/pages/snapshots/get", handlers.WithCORS(handlers.PageSnapshotGetHandler))
+	mux.HandleFunc("/api/pages/diff", handlers.WithCORS(handlers.PageDiffHandler))
+	mux.HandleFunc("/api/scripts/versions", handlers.WithCORS(handlers.ScriptVersionsHandler))
+	mux.HandleFunc("/api/scripts/get", handlers.WithCORS(handlers.ScriptGetHandler))
+	mux.HandleFunc("/api/scripts/diff", handlers.WithCORS(handlers.ScriptDiffHandler))
+	mux.HandleFunc("/api/import/har", handlers.WithCORS(handlers.ImportHARHandler))
 
 	mux.HandleFunc("/api/endpoints", handlers.WithCORS(handlers.EndpointsListHandler))
 	mux.HandleFunc("/api/endpoints/stats", handlers.WithCORS(handlers.EndpointsStatsHandler))
 
 	mux.HandleFunc("/api/sinks", handlers.WithCORS(handlers.SinksListHandler))
 	mux.HandleFunc("/api/sinks/stats", handlers.WithCORS(handlers.SinksStatsHandler))
+	mux.HandleFunc("/api/rules", handlers.WithCORS(handlers.RulesListHandler))              // GET ?reload=1
+	mux.HandleFunc("/api/rules/validate", handlers.WithCORS(handlers.RulesValidateHandler)) // POST
+
+	mux.HandleFunc("/api/findings", handlers.WithCORS(handlers.FindingsListHandler))
+	mux.HandleFunc("/api/findings/stats", handlers.WithCORS(handlers.FindingsStatsHandler))
+
+	mux.HandleFunc("/api/secrets", handlers.WithCORS(handlers.SecretsListHandler))
+	mux.HandleFunc("/api/secrets/stats", handlers.WithCORS(handlers.SecretsStatsHandler))
+
+	mux.HandleFunc("/api/graphql/operations", handlers.WithCORS(handlers.GraphQLOperationsHandler))
+	mux.HandleFunc("/api/graphql/schemas", handlers.WithCORS(handlers.GraphQLSchemasHandler))
+	mux.HandleFunc("/api/graphql/schema", handlers.WithCORS(handlers.GraphQLSchemaHandler)) // ?id=&download=1
+
+	mux.HandleFunc("/api/crawls", handlers.WithCORS(handlers.CrawlsListHandler))
+	mux.HandleFunc("/api/crawls/{id}", handlers.WithCORS(handlers.CrawlGetHandler))
 
 	mux.HandleFunc("/api/externals", handlers.WithCORS(handlers.ExternalsListHandler))
 	mux.HandleFunc("/api/search", handlers.WithCORS(handlers.SearchHandler))
@@ -55,10 +88,27 @@ func main() {
 	mux.HandleFunc("/api/watches/create", handlers.WithCORS(handlers.WatchCreateHandler))    // POST
 	mux.HandleFunc("/api/watches/scan-now", handlers.WithCORS(handlers.WatchScanNowHandler)) // POST
 	mux.HandleFunc("/api/watches/delete", handlers.WithCORS(handlers.WatchDeleteHandler))
+	mux.HandleFunc("/api/watches/runs", handlers.WithCORS(handlers.WatchRunsHandler)) // GET
+
+	mux.HandleFunc("/api/auth-profiles", handlers.WithCORS(handlers.AuthProfilesListHandler))         // GET
+	mux.HandleFunc("/api/auth-profiles/{id}", handlers.WithCORS(handlers.AuthProfileGetHandler))      // GET
+	mux.HandleFunc("/api/auth-profiles/create", handlers.WithCORS(handlers.AuthProfileCreateHandler)) // POST
+	mux.HandleFunc("/api/auth-profiles/update", handlers.WithCORS(handlers.AuthProfileUpdateHandler)) // POST
+	mux.HandleFunc("/api/auth-profiles/delete", handlers.WithCORS(handlers.AuthProfileDeleteHandler))
 
 	mux.HandleFunc("/api/settings/discord", handlers.WithCORS(handlers.DiscordGetHandler))     // GET
 	mux.HandleFunc("/api/settings/discord/set", handlers.WithCORS(handlers.DiscordSetHandler)) // POST
 	mux.HandleFunc("/api/settings/discord/test", handlers.WithCORS(handlers.DiscordTestHandler))
+	mux.HandleFunc("/api/settings/watch-runs", handlers.WithCORS(handlers.WatchRunsSettingGetHandler))     // GET
+	mux.HandleFunc("/api/settings/watch-runs/set", handlers.WithCORS(handlers.WatchRunsSettingSetHandler)) // POST
+
+	mux.HandleFunc("/api/settings/proxy", handlers.WithCORS(handlers.ProxyGetHandler))     // GET
+	mux.HandleFunc("/api/settings/proxy/set", handlers.WithCORS(handlers.ProxySetHandler)) // POST
+	mux.HandleFunc("/api/settings/proxy/delete", handlers.WithCORS(handlers.ProxyDeleteHandler))
+	mux.HandleFunc("/api/settings/xss-verify", handlers.WithCORS(handlers.XSSVerifyGetHandler))              // GET
+	mux.HandleFunc("/api/settings/xss-verify/set", handlers.WithCORS(handlers.XSSVerifySetHandler))          // POST
+	mux.HandleFunc("/api/xss/verify", handlers.WithCORS(handlers.XSSVerifyHandler))                          // POST (همزمان)
+	mux.HandleFunc("/api/prototype-pollution/check", handlers.WithCORS(handlers.ProtoPollutionCheckHandler)) // POST (همزمان)
 
 	srv := &http.Server{
 		Addr:         ":8050",
@@ -77,9 +127,6 @@ func main() {
 	<-rootCtx.Done()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	functions.StartWatchScheduler(ctx)
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Printf("http shutdown error: %v", err)
 	}
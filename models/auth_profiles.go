@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// اکشن‌های مجاز در دستور لاگین
+const (
+	StepNavigate = "navigate" // url
+	StepFill     = "fill"     // selector + value
+	StepClick    = "click"    // selector
+	StepWait     = "wait"     // selector (تا visible شود)
+	StepSleep    = "sleep"    // timeout_sec
+)
+
+type AuthCookie struct {
+	Name     string `bson:"name"               json:"name"`
+	Value    string `bson:"value"              json:"value"`
+	Domain   string `bson:"domain,omitempty"   json:"domain,omitempty"` // خالی = هاست URL اسکن
+	Path     string `bson:"path,omitempty"     json:"path,omitempty"`
+	Secure   bool   `bson:"secure,omitempty"   json:"secure,omitempty"`
+	HTTPOnly bool   `bson:"http_only,omitempty" json:"http_only,omitempty"`
+	SameSite string `bson:"same_site,omitempty" json:"same_site,omitempty"` // Strict | Lax | None
+}
+
+// LoginStep: یک قدم از دستور لاگین که قبل از Navigate اصلی اجرا می‌شود
+type LoginStep struct {
+	Action     string `bson:"action"                json:"action"`
+	URL        string `bson:"url,omitempty"         json:"url,omitempty"`
+	Selector   string `bson:"selector,omitempty"    json:"selector,omitempty"`
+	Value      string `bson:"value,omitempty"       json:"value,omitempty"`
+	Secret     bool   `bson:"secret,omitempty"      json:"secret,omitempty"` // مقدار در API ماسک می‌شود؛ مقدار قدم fill همیشه ماسک می‌شود
+	TimeoutSec int    `bson:"timeout_sec,omitempty" json:"timeout_sec,omitempty"`
+}
+
+type AuthProfileDoc struct {
+	ID          string            `bson:"_id"                    json:"id"`
+	SiteID      string            `bson:"site_id"                json:"site_id"`
+	Name        string            `bson:"name"                   json:"name"`
+	Cookies     []AuthCookie      `bson:"cookies,omitempty"      json:"cookies,omitempty"`
+	BearerToken string            `bson:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	Headers     map[string]string `bson:"headers,omitempty"      json:"headers,omitempty"`
+	LoginSteps  []LoginStep       `bson:"login_steps,omitempty"  json:"login_steps,omitempty"`
+	CreatedAt   time.Time         `bson:"created_at"             json:"created_at"`
+	UpdatedAt   time.Time         `bson:"updated_at"             json:"updated_at"`
+}
+
+func AuthProfilesColl() *mongo.Collection { return DB.Collection("auth_profiles") }
+
+func EnsureAuthProfileIndexes(ctx context.Context) error {
+	_, err := AuthProfilesColl().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_site_name"),
+	})
+	return err
+}
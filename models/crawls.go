@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// محدودهٔ crawl: فقط همان host یا کل eTLD+1
+const (
+	CrawlScopeHost = "host"
+	CrawlScopeSite = "site"
+)
+
+type CrawlOptions struct {
+	MaxDepth int      `json:"max_depth,omitempty" bson:"max_depth,omitempty"`
+	MaxPages int      `json:"max_pages,omitempty" bson:"max_pages,omitempty"`
+	Include  []string `json:"include,omitempty"   bson:"include,omitempty"` // regex روی path
+	Exclude  []string `json:"exclude,omitempty"   bson:"exclude,omitempty"`
+	Scope    string   `json:"scope,omitempty"     bson:"scope,omitempty"` // host | site
+}
+
+type CrawlPage struct {
+	URL        string `bson:"url"                  json:"url"`
+	URLNorm    string `bson:"url_norm"             json:"url_norm"`
+	Parent     string `bson:"parent,omitempty"     json:"parent,omitempty"`
+	Depth      int    `bson:"depth"                json:"depth"`
+	Status     string `bson:"status"               json:"status"` // done | failed
+	Error      string `bson:"error,omitempty"      json:"error,omitempty"`
+	Endpoints  int    `bson:"endpoints"            json:"endpoints"`
+	Found      int    `bson:"found"                json:"found"` // لینک‌های جدید صف‌شده از این صفحه
+	DurationMs int64  `bson:"duration_ms"          json:"duration_ms"`
+}
+
+type CrawlDoc struct {
+	ID          string       `bson:"_id"                   json:"id"`
+	JobID       string       `bson:"job_id,omitempty"      json:"job_id,omitempty"`
+	SiteID      string       `bson:"site_id"               json:"site_id"`
+	RootURL     string       `bson:"root_url"              json:"root_url"`
+	Options     CrawlOptions `bson:"options"               json:"options"`
+	State       string       `bson:"state"                 json:"state"` // running | done | failed
+	Pages       []CrawlPage  `bson:"pages"                 json:"pages"`
+	PagesDone   int          `bson:"pages_done"            json:"pages_done"`
+	PagesFailed int          `bson:"pages_failed"          json:"pages_failed"`
+	Discovered  int          `bson:"discovered"            json:"discovered"`
+	Skipped     int          `bson:"skipped"               json:"skipped"` // به‌خاطر max_pages / max_depth
+	Error       string       `bson:"error,omitempty"       json:"error,omitempty"`
+	StartedAt   time.Time    `bson:"started_at"            json:"started_at"`
+	FinishedAt  time.Time    `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+func CrawlsColl() *mongo.Collection { return DB.Collection("crawls") }
+
+func EnsureCrawlIndexes(ctx context.Context) error {
+	_, err := CrawlsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "started_at", Value: -1}},
+			Options: options.Index().SetName("q_site_recent"),
+		},
+		{
+			// job صف‌شدهٔ دوباره CrawlDoc خودش را با job_id پیدا می‌کند
+			Keys:    bson.D{{Key: "job_id", Value: 1}},
+			Options: options.Index().SetName("q_job"),
+		},
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// انواع finding (یافته‌هایی که sink یا endpoint نیستند)
+const (
+	FindingExposedSourceMap      = "exposed_sourcemap"
+	FindingGraphQLIntrospection  = "graphql_introspection"
+	FindingTaintFlow             = "taint_flow"   // داده source به sink رسید (details.sink_sig → SinkDoc)
+	FindingXSSVerified           = "xss_verified" // payload canary در مرورگر اجرا/رندر شد (details.verification)
+	FindingPostMessageHandler    = "postmessage_handler"
+	FindingProtoPollution        = "prototype_pollution"         // Object.prototype با payload URL آلوده شد (details.repro_url)
+	FindingProtoPollutionPattern = "prototype_pollution_pattern" // الگوی استاتیک merge/extend/deparam
+)
+
+// نتیجهٔ بررسی event.origin در handler پیام (details.origin_check)
+const (
+	OriginCheckMissing    = "missing"    // origin اصلاً خوانده نمی‌شود
+	OriginCheckWildcard   = "wildcard"   // مقایسه با "*"
+	OriginCheckBypassable = "bypassable" // indexOf/includes/startsWith یا regex بدون anchor/escape
+	OriginCheckStrict     = "strict"     // مقایسهٔ دقیق یا allow-list
+	OriginCheckUnknown    = "unknown"    // origin خوانده می‌شود ولی الگو شناخته نشد (یا سورس در دسترس نیست)
+)
+
+// سطح اهمیت
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank: ترتیب عددی severity برای فیلتر «حداقل سطح» (نامعتبر = -1)
+func SeverityRank(sev string) int {
+	switch sev {
+	case SeverityInfo:
+		return 0
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return -1
+}
+
+// SeveritiesAtLeast: همهٔ سطح‌های >= min (برای $in روی کالکشن‌هایی که rank ندارند)
+func SeveritiesAtLeast(minSev string) []string {
+	var out []string
+	for _, s := range []string{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical} {
+		if SeverityRank(s) >= SeverityRank(minSev) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type FindingDoc struct {
+	Sig       string         `bson:"sig"                  json:"sig"` // کلید ددوپ (site + type + target)
+	SiteID    string         `bson:"site_id"              json:"site_id"`
+	PageURL   string         `bson:"page_url"             json:"page_url"`
+	Type      string         `bson:"type"                 json:"type"`
+	Severity  string         `bson:"severity"             json:"severity"`
+	Title     string         `bson:"title"                json:"title"`
+	URL       string         `bson:"url,omitempty"        json:"url,omitempty"` // منبعی که finding به آن اشاره دارد
+	Details   map[string]any `bson:"details,omitempty"    json:"details,omitempty"`
+	Origin    string         `bson:"origin,omitempty"     json:"origin,omitempty"`
+	FirstSeen time.Time      `bson:"first_seen,omitempty" json:"first_seen,omitempty"`
+	LastSeen  time.Time      `bson:"last_seen,omitempty"  json:"last_seen,omitempty"`
+	Hits      int64          `bson:"hits,omitempty"       json:"hits,omitempty"`
+}
+
+func FindingsColl() *mongo.Collection { return DB.Collection("findings") }
+
+func EnsureFindingIndexes(ctx context.Context) error {
+	_, err := FindingsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sig", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_sig"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "type", Value: 1}, {Key: "last_seen", Value: -1}},
+			Options: options.Index().SetName("q_site_type_recent"),
+		},
+	})
+	return err
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GraphQLOperation: عملیات GraphQL کشف‌شده در کد یا ترافیک
+type GraphQLOperation struct {
+	Name      string   `bson:"name,omitempty"       json:"name,omitempty"`
+	Type      string   `bson:"type"                 json:"type"`             // query | mutation | subscription
+	Fields    []string `bson:"fields,omitempty"     json:"fields,omitempty"` // فیلدهای سطح اول selection set
+	Hash      string   `bson:"hash,omitempty"       json:"hash,omitempty"`   // persisted query (Apollo sha256 / Relay id)
+	Source    string   `bson:"source"               json:"source"`           // script | network
+	SourceURL string   `bson:"source_url,omitempty" json:"source_url,omitempty"`
+	Endpoint  string   `bson:"endpoint,omitempty"   json:"endpoint,omitempty"`
+}
+
+// GraphQLResult: خروجی تحلیل GraphQL یک اسکن
+type GraphQLResult struct {
+	Endpoints  []string           `json:"endpoints,omitempty"`
+	Operations []GraphQLOperation `json:"operations,omitempty"`
+	Schemas    []GraphQLSchemaDoc `json:"schemas,omitempty"` // فقط وقتی introspection خواسته شده باشد
+}
+
+// GraphQLOperationDoc: یک عملیات در سطح سایت (ددوپ با sig)
+type GraphQLOperationDoc struct {
+	Sig        string    `bson:"sig"                  json:"sig"` // site + type + name + hash
+	SiteID     string    `bson:"site_id"              json:"site_id"`
+	Name       string    `bson:"name,omitempty"       json:"name,omitempty"`
+	Type       string    `bson:"type"                 json:"type"`
+	Hash       string    `bson:"hash,omitempty"       json:"hash,omitempty"`
+	Fields     []string  `bson:"fields,omitempty"     json:"fields,omitempty"`
+	Sources    []string  `bson:"sources,omitempty"    json:"sources,omitempty"`
+	SourceURLs []string  `bson:"source_urls,omitempty" json:"source_urls,omitempty"`
+	Endpoints  []string  `bson:"endpoints,omitempty"  json:"endpoints,omitempty"`
+	FirstSeen  time.Time `bson:"first_seen,omitempty" json:"first_seen,omitempty"`
+	LastSeen   time.Time `bson:"last_seen,omitempty"  json:"last_seen,omitempty"`
+	Hits       int64     `bson:"hits,omitempty"       json:"hits,omitempty"`
+}
+
+// GraphQLSchemaDoc: نتیجهٔ introspection یک endpoint (یک سند برای هر site + endpoint)
+type GraphQLSchemaDoc struct {
+	ID                 string    `bson:"_id"                           json:"id"`
+	SiteID             string    `bson:"site_id"                       json:"site_id"`
+	Endpoint           string    `bson:"endpoint"                      json:"endpoint"`
+	Enabled            bool      `bson:"enabled"                       json:"enabled"` // introspection باز است
+	Status             int       `bson:"status,omitempty"              json:"status,omitempty"`
+	Error              string    `bson:"error,omitempty"               json:"error,omitempty"`
+	TypeCount          int       `bson:"type_count,omitempty"          json:"type_count,omitempty"`
+	QueryFields        []string  `bson:"query_fields,omitempty"        json:"query_fields,omitempty"`
+	MutationFields     []string  `bson:"mutation_fields,omitempty"     json:"mutation_fields,omitempty"`
+	SubscriptionFields []string  `bson:"subscription_fields,omitempty" json:"subscription_fields,omitempty"`
+	Schema             string    `bson:"schema,omitempty"              json:"-"` // JSON خام __schema
+	FetchedAt          time.Time `bson:"fetched_at"                    json:"fetched_at"`
+}
+
+func GraphQLOpsColl() *mongo.Collection     { return DB.Collection("graphql_operations") }
+func GraphQLSchemasColl() *mongo.Collection { return DB.Collection("graphql_schemas") }
+
+func EnsureGraphQLIndexes(ctx context.Context) error {
+	_, err := GraphQLOpsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sig", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_sig"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "type", Value: 1}, {Key: "last_seen", Value: -1}},
+			Options: options.Index().SetName("q_site_type_recent"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GraphQLSchemasColl().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "fetched_at", Value: -1}},
+		Options: options.Index().SetName("q_site_recent"),
+	})
+	return err
+}
@@ -0,0 +1,129 @@
+package models
+
+// ساختار HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/) برای خروجی ترافیک یک صفحه
+
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HARPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+type HAREntry struct {
+	PageRef         string      `json:"pageref,omitempty"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+
+	// فیلدهای سفارشی (با _ طبق spec)
+	ResourceType string `json:"_resourceType,omitempty"`
+	Initiator    string `json:"_initiator,omitempty"`
+	Error        string `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []HARCookie  `json:"cookies"`
+	Headers     []HTTPHeader `json:"headers"`
+	QueryString []HTTPHeader `json:"queryString"`
+	PostData    *HARPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARResponse struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []HARCookie  `json:"cookies"`
+	Headers     []HTTPHeader `json:"headers"`
+	Content     HARContent   `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARImportSpec: job import فایل HAR؛ فایل آپلود تا اجرای job روی دیسک سرور می‌ماند
+type HARImportSpec struct {
+	File       string `json:"-"                     bson:"file"` // مسیر روی سرور؛ از API قابل تنظیم نیست
+	Name       string `json:"name,omitempty"        bson:"name,omitempty"`
+	KeepBodies bool   `json:"keep_bodies,omitempty" bson:"keep_bodies,omitempty"`
+}
+
+type HARImportPage struct {
+	URL       string `json:"url"                bson:"url"`
+	URLNorm   string `json:"url_norm"           bson:"url_norm"`
+	SiteID    string `json:"site_id"            bson:"site_id"`
+	Requests  int    `json:"requests"           bson:"requests"`
+	Scripts   int    `json:"scripts"            bson:"scripts"`
+	Endpoints int    `json:"endpoints"          bson:"endpoints"`
+	Sinks     int    `json:"sinks"              bson:"sinks"`
+	Error     string `json:"error,omitempty"    bson:"error,omitempty"`
+}
+
+type HARImportResult struct {
+	ImportID string          `json:"import_id" bson:"import_id"`
+	Entries  int             `json:"entries"   bson:"entries"`
+	Skipped  int             `json:"skipped"   bson:"skipped"`
+	Pages    []HARImportPage `json:"pages"     bson:"pages"`
+}
@@ -40,7 +40,9 @@ func InitMongo(ctx context.Context) error {
 		)
 		if err == nil {
 			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			if err = c.Ping(pingCtx, nil); err == nil {
+			err = c.Ping(pingCtx, nil)
+			cancel()
+			if err == nil {
 				Mongo = c
 
 				dbName := os.Getenv("MONGO_DB")
@@ -55,7 +57,6 @@ func InitMongo(ctx context.Context) error {
 				log.Printf("[mongo] connected db=%s uri=%s", DB.Name(), u)
 				return nil
 			}
-			cancel()
 			_ = c.Disconnect(context.Background())
 		}
 		lastErr = err
@@ -86,9 +87,9 @@ func EnsureIndexes(ctx context.Context) error {
 	})
 
 	// endpoints
-	_, _ = EndpointsColl().Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "endpoint", Value: 1}},
-		Options: options.Index().SetUnique(true).SetName("uniq_site_endpoint"),
+	_, _ = EndpointsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "site_id", Value: 1}, {Key: "endpoint", Value: 1}}, Options: options.Index().SetUnique(true).SetName("uniq_site_endpoint")},
+		{Keys: bson.D{{Key: "site_id", Value: 1}, {Key: "template", Value: 1}}, Options: options.Index().SetName("q_site_template")},
 	})
 
 	// sinks — حذف ایندکس قدیمی اگر وجود داشت
@@ -115,5 +116,48 @@ func EnsureIndexes(ctx context.Context) error {
 		Options: options.Index().SetName("q_site_page_kind"),
 	})
 
+	// 4) فیلتر/مرتب‌سازی بر اساس severity
+	_, _ = iv.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "severity_rank", Value: -1}, {Key: "last_detected_at", Value: -1}},
+		Options: options.Index().SetName("q_site_severity_recent"),
+	})
+
+	// سینک‌های قدیمی بدون confidence (severity با اسکن بعدی از قوانین پر می‌شود)
+	_, _ = SinksColl().UpdateMany(ctx, bson.M{"confidence": bson.M{"$exists": false}}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"confidence": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$source_type", "runtime"}}, ConfidenceRuntime, ConfidenceStatic,
+		}}}}},
+	})
+
+	// scan_jobs
+	_ = EnsureScanJobIndexes(ctx)
+
+	// crawls
+	_ = EnsureCrawlIndexes(ctx)
+
+	// network
+	_ = EnsureNetworkIndexes(ctx)
+
+	// auth_profiles
+	_ = EnsureAuthProfileIndexes(ctx)
+
+	// findings
+	_ = EnsureFindingIndexes(ctx)
+
+	// graphql
+	_ = EnsureGraphQLIndexes(ctx)
+
+	// secrets
+	_ = EnsureSecretIndexes(ctx)
+
+	// page_snapshots
+	_ = EnsurePageSnapshotIndexes(ctx)
+
+	// scripts / script_versions
+	_ = EnsureScriptIndexes(ctx)
+
+	// watch_runs
+	_ = EnsureWatchRunIndexes(ctx)
+
 	return nil
 }
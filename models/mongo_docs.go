@@ -11,7 +11,15 @@ type SiteDoc struct {
 	LastScanAt   time.Time `bson:"last_scan_at,omitempty"`
 	PagesCount   int64     `bson:"pages_count,omitempty"`
 	EndpointsCnt int64     `bson:"endpoints_count,omitempty"`
+	Origins      []string  `bson:"origins,omitempty"` // scan / har_import
 }
+
+// منشأ داده‌ها: اسکن زنده با مرورگر یا import آفلاین
+const (
+	OriginScan      = "scan"
+	OriginHARImport = "har_import"
+)
+
 type ExternalGroup struct {
 	SiteID    string   `bson:"site_id,omitempty"`
 	Hosts     []string `bson:"hosts,omitempty"`
@@ -35,6 +43,10 @@ type PageDoc struct {
 	Groups         map[string][]string      `bson:"groups,omitempty"`
 	ResourceGroups map[string][]string      `bson:"resource_groups,omitempty"`
 	Externals      map[string]ExternalGroup `bson:"externals,omitempty"`
+	CrawlID        string                   `bson:"crawl_id,omitempty"`
+	Origin         string                   `bson:"origin,omitempty"`
+	ImportID       string                   `bson:"import_id,omitempty"`
+	Proxy          string                   `bson:"proxy,omitempty"`
 }
 
 type EndpointDoc struct {
@@ -46,6 +58,19 @@ type EndpointDoc struct {
 	SourceURLs []string  `bson:"source_urls,omitempty"`
 	SeenCount  int64     `bson:"seen_count,omitempty"`
 	Category   string    `bson:"category,omitempty"`
+	Methods    []string  `bson:"methods,omitempty"`     // فقط برای درخواست‌های مشاهده‌شده در شبکه
+	Observed   bool      `bson:"observed,omitempty"`    // حداقل یک بار واقعاً از مرورگر ارسال شده
+	LastStatus int       `bson:"last_status,omitempty"` // آخرین status مشاهده‌شده
+	Origins    []string  `bson:"origins,omitempty"`
+
+	Extractors      []string `bson:"extractors,omitempty"`       // regex / ast / network
+	InferredMethods []string `bson:"inferred_methods,omitempty"` // متد حدس‌زده از کد (نه مشاهده‌شده)
+	Contexts        []string `bson:"contexts,omitempty"`         // fetch / xhr / axios / jquery / client
+
+	Template      string   `bson:"template,omitempty"`       // مثل /api/users/{id}
+	PathParams    []string `bson:"path_params,omitempty"`    // نام پارامترهای {…} در template
+	QueryParams   []string `bson:"query_params,omitempty"`   // نام پارامترهای query دیده‌شده
+	ParamExamples []string `bson:"param_examples,omitempty"` // "name=value" (حداکثر ۲۰)
 }
 
 type SinkDoc struct {
@@ -59,4 +84,21 @@ type SinkDoc struct {
 	Func       string    `bson:"func,omitempty"`
 	Snippet    string    `bson:"snippet,omitempty"`
 	DetectedAt time.Time `bson:"detected_at"`
+	Origin     string    `bson:"origin,omitempty"`
+	ImportID   string    `bson:"import_id,omitempty"`
+	BundleURL  string    `bson:"bundle_url,omitempty"` // برای سینک‌های source map: فایل bundle که کد اصلی در آن بود
+	// از قانون سینک (SinkRules) پر می‌شوند
+	Severity   string `bson:"severity,omitempty"`
+	Confidence string `bson:"confidence,omitempty"` // static | runtime | taint
+	CWE        string `bson:"cwe,omitempty"`
+	OWASP      string `bson:"owasp,omitempty"`
+	// فقط با تأیید فعال (canary) پر می‌شود
+	Verification *SinkVerification `bson:"verification,omitempty"`
 }
+
+// اطمینان سینک: الگوی استاتیک < مشاهده در runtime < تأیید با taint flow
+const (
+	ConfidenceStatic  = "static"
+	ConfidenceRuntime = "runtime"
+	ConfidenceTaint   = "taint"
+)
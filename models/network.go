@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NetworkRequest: یک درخواست مشاهده‌شده از طریق رویدادهای Network در CDP
+type NetworkRequest struct {
+	RequestID    string    `bson:"request_id"              json:"request_id"`
+	Method       string    `bson:"method"                  json:"method"`
+	URL          string    `bson:"url"                     json:"url"`
+	Type         string    `bson:"type,omitempty"          json:"type,omitempty"` // Document, XHR, Fetch, Script, ...
+	Status       int       `bson:"status,omitempty"        json:"status,omitempty"`
+	StatusText   string    `bson:"status_text,omitempty"   json:"status_text,omitempty"`
+	MIMEType     string    `bson:"mime_type,omitempty"     json:"mime_type,omitempty"`
+	Initiator    string    `bson:"initiator,omitempty"     json:"initiator,omitempty"` // parser, script, preflight, ...
+	InitiatorURL string    `bson:"initiator_url,omitempty" json:"initiator_url,omitempty"`
+	InitiatorLn  int       `bson:"initiator_line,omitempty" json:"initiator_line,omitempty"`
+	PostData     string    `bson:"post_data,omitempty"     json:"post_data,omitempty"`
+	RedirectTo   string    `bson:"redirect_to,omitempty"   json:"redirect_to,omitempty"`
+	RemoteIP     string    `bson:"remote_ip,omitempty"     json:"remote_ip,omitempty"`
+	Protocol     string    `bson:"protocol,omitempty"      json:"protocol,omitempty"`
+	FromCache    bool      `bson:"from_cache,omitempty"    json:"from_cache,omitempty"`
+	Failed       bool      `bson:"failed,omitempty"        json:"failed,omitempty"`
+	ErrorText    string    `bson:"error_text,omitempty"    json:"error_text,omitempty"`
+	StartedAt    time.Time `bson:"started_at"              json:"started_at"`
+	DurationMs   float64   `bson:"duration_ms,omitempty"   json:"duration_ms,omitempty"`
+	EncodedSize  int64     `bson:"encoded_size,omitempty"  json:"encoded_size,omitempty"`
+
+	// برای خروجی HAR
+	RequestHeaders  []HTTPHeader   `bson:"request_headers,omitempty"  json:"request_headers,omitempty"`
+	ResponseHeaders []HTTPHeader   `bson:"response_headers,omitempty" json:"response_headers,omitempty"`
+	Timing          *NetworkTiming `bson:"timing,omitempty"           json:"timing,omitempty"`
+	BodySize        int64          `bson:"body_size,omitempty"        json:"body_size,omitempty"`
+	Body            string         `bson:"body,omitempty"             json:"body,omitempty"`
+	BodyBase64      bool           `bson:"body_base64,omitempty"      json:"body_base64,omitempty"`
+	BodyTruncated   bool           `bson:"body_truncated,omitempty"   json:"body_truncated,omitempty"`
+}
+
+type HTTPHeader struct {
+	Name  string `bson:"name"  json:"name"`
+	Value string `bson:"value" json:"value"`
+}
+
+// NetworkTiming: فازهای درخواست به میلی‌ثانیه با همان معنای HAR (‎-1 یعنی نامعلوم)
+type NetworkTiming struct {
+	Blocked float64 `bson:"blocked" json:"blocked"`
+	DNS     float64 `bson:"dns"     json:"dns"`
+	Connect float64 `bson:"connect" json:"connect"`
+	SSL     float64 `bson:"ssl"     json:"ssl"`
+	Send    float64 `bson:"send"    json:"send"`
+	Wait    float64 `bson:"wait"    json:"wait"`
+	Receive float64 `bson:"receive" json:"receive"`
+}
+
+// PageTimings: زمان رویدادهای DOMContentLoaded و load نسبت به شروع سند
+type PageTimings struct {
+	OnContentLoadMs float64 `bson:"on_content_load_ms,omitempty" json:"on_content_load_ms,omitempty"`
+	OnLoadMs        float64 `bson:"on_load_ms,omitempty"         json:"on_load_ms,omitempty"`
+}
+
+// NetworkLogDoc: لاگ درخواست‌های آخرین اسکن هر صفحه (یک سند به ازای url_norm)
+type NetworkLogDoc struct {
+	SiteID     string           `bson:"site_id"    json:"site_id"`
+	PageURL    string           `bson:"page_url"   json:"page_url"`
+	Requests   []NetworkRequest `bson:"requests"   json:"requests"`
+	Count      int              `bson:"count"      json:"count"`
+	Truncated  bool             `bson:"truncated"  json:"truncated"`
+	Title      string           `bson:"title,omitempty"  json:"title,omitempty"`
+	Timings    PageTimings      `bson:"timings"    json:"timings"`
+	HasBodies  bool             `bson:"has_bodies" json:"has_bodies"`
+	CapturedAt time.Time        `bson:"captured_at" json:"captured_at"`
+}
+
+func NetworkColl() *mongo.Collection { return DB.Collection("network") }
+
+func EnsureNetworkIndexes(ctx context.Context) error {
+	_, err := NetworkColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "page_url", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_page_url"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}},
+			Options: options.Index().SetName("q_site"),
+		},
+	})
+	return err
+}
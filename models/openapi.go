@@ -0,0 +1,61 @@
+package models
+
+// انواع حداقلی OpenAPI 3.1 برای خروجی endpointهای کشف‌شده (ترتیب فیلدها همان ترتیب خروجی است)
+
+type OpenAPIDoc struct {
+	OpenAPI string                     `json:"openapi"           yaml:"openapi"`
+	Info    OpenAPIInfo                `json:"info"              yaml:"info"`
+	Servers []OpenAPIServer            `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Paths   map[string]OpenAPIPathItem `json:"paths"             yaml:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"                 yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version"               yaml:"version"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url" yaml:"url"`
+}
+
+// OpenAPIPathItem: کلید = متد با حروف کوچک (get, post, ...)
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"            yaml:"operationId"`
+	Tags        []string                   `json:"tags,omitempty"         yaml:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"   yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"  yaml:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"              yaml:"responses"`
+
+	// اطلاعات کشف (extension)
+	XObserved   bool     `json:"x-observed,omitempty"   yaml:"x-observed,omitempty"`
+	XExtractors []string `json:"x-extractors,omitempty" yaml:"x-extractors,omitempty"`
+	XSeenCount  int64    `json:"x-seen-count,omitempty" yaml:"x-seen-count,omitempty"`
+	XExamples   []string `json:"x-examples,omitempty"   yaml:"x-examples,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string        `json:"name"              yaml:"name"`
+	In       string        `json:"in"                yaml:"in"` // path | query
+	Required bool          `json:"required"          yaml:"required"`
+	Schema   OpenAPISchema `json:"schema"            yaml:"schema"`
+	Example  any           `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+type OpenAPISchema struct {
+	Type   string `json:"type"             yaml:"type"`
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Content map[string]OpenAPIMediaType `json:"content" yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"       yaml:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct{}
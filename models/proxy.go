@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ProxyConfig: پروکسی upstream برای Chromium و fetchهای سمت Go (مثلاً Burp/ZAP)
+type ProxyConfig struct {
+	URL       string   `bson:"url"                  json:"url"` // http://host:port | https://… | socks5://host:port
+	Username  string   `bson:"username,omitempty"   json:"username,omitempty"`
+	Password  string   `bson:"password,omitempty"   json:"password,omitempty"`
+	Bypass    []string `bson:"bypass,omitempty"     json:"bypass,omitempty"`     // مثل "localhost", "*.internal"
+	IgnoreTLS bool     `bson:"ignore_tls,omitempty" json:"ignore_tls,omitempty"` // برای CA پروکسی intercepting
+}
+
+// ProxySetting: تنظیم پروکسی هر سایت در settings با _id = "proxy:<site_id>"
+type ProxySetting struct {
+	ID          string `bson:"_id"        json:"id"`
+	SiteID      string `bson:"site_id"    json:"site_id"`
+	Enabled     bool   `bson:"enabled"    json:"enabled"`
+	ProxyConfig `bson:",inline"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+func ProxySettingID(siteID string) string { return "proxy:" + siteID }
+
+// GetProxySetting: اگر برای سایت تنظیمی نبود (nil, nil)
+func GetProxySetting(ctx context.Context, siteID string) (*ProxySetting, error) {
+	var out ProxySetting
+	err := SettingsColl().FindOne(ctx, bson.M{"_id": ProxySettingID(siteID)}).Decode(&out)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
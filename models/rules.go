@@ -0,0 +1,38 @@
+package models
+
+// SinkRule: تعریف یک سینک برای هر سه اسکنر (regex در Go، regex درون صفحه، hook در runtime)
+type SinkRule struct {
+	ID          string     `yaml:"id"                    json:"id"`
+	Kind        string     `yaml:"kind,omitempty"        json:"kind"` // در SinkDoc.kind ذخیره می‌شود؛ پیش‌فرض = id
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Severity    string     `yaml:"severity,omitempty"    json:"severity,omitempty"`
+	CWE         string     `yaml:"cwe,omitempty"         json:"cwe,omitempty"`
+	OWASP       string     `yaml:"owasp,omitempty"       json:"owasp,omitempty"`      // مثل A03:2021
+	Regex       string     `yaml:"regex,omitempty"       json:"regex,omitempty"`      // باید هم در RE2 و هم در RegExp مرورگر معتبر باشد
+	Flags       string     `yaml:"flags,omitempty"       json:"flags,omitempty"`      // فقط "i"
+	AppliesTo   []string   `yaml:"applies_to,omitempty"  json:"applies_to,omitempty"` // html | inline | script
+	Runtime     []RuleHook `yaml:"runtime,omitempty"     json:"runtime,omitempty"`
+	Taint       bool       `yaml:"taint,omitempty"       json:"taint,omitempty"` // hookهای runtime در taint tracking (فقط sinkهای XSS) شرکت می‌کنند
+	Disabled    bool       `yaml:"disabled,omitempty"    json:"disabled,omitempty"`
+	Source      string     `yaml:"-"                     json:"source"` // builtin | user
+}
+
+// RuleHook: نقطهٔ hook در runtime
+//
+//	call      → تابع target (مثل window.eval یا XMLHttpRequest.prototype.open) wrap می‌شود
+//	setter    → setter ویژگی target (مثل Element.prototype.innerHTML)
+//	attribute → setAttribute با نامی که با regex target جور باشد (مثل ^on[a-z]+$)
+//	listener  → addEventListener با نوع رویداد target (مثل message)
+type RuleHook struct {
+	Hook       string        `yaml:"hook"                  json:"hook"`
+	Target     string        `yaml:"target"                json:"target"`
+	SnippetArg int           `yaml:"snippet_arg,omitempty" json:"snippet_arg,omitempty"` // کدام آرگومان به‌عنوان snippet ثبت شود
+	StringOnly bool          `yaml:"string_only,omitempty" json:"string_only,omitempty"` // فقط وقتی آن آرگومان رشته است
+	ArgEquals  *RuleArgEqual `yaml:"arg_equals,omitempty"  json:"arg_equals,omitempty"`
+}
+
+// RuleArgEqual: شرط روی یک آرگومان (مثلاً async === false برای syncXHR)
+type RuleArgEqual struct {
+	Index int `yaml:"index" json:"index"`
+	Value any `yaml:"value" json:"value"`
+}
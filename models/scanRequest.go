@@ -1,7 +1,31 @@
 package models
 
 type ScanRequest struct {
-	URL            string `json:"url"`
-	WaitSec        int    `json:"wait_sec,omitempty"`
-	JSFetchTimeout int    `json:"js_fetch_timeout,omitempty"`
+	URL            string `json:"url"                        bson:"url"`
+	WaitSec        int    `json:"wait_sec,omitempty"         bson:"wait_sec,omitempty"`
+	JSFetchTimeout int    `json:"js_fetch_timeout,omitempty" bson:"js_fetch_timeout,omitempty"`
+
+	// بدنهٔ پاسخ‌ها هم (با سقف حجم) برای خروجی HAR نگه داشته شود
+	HARBodies bool `json:"har_bodies,omitempty" bson:"har_bodies,omitempty"`
+
+	// روی endpointهای GraphQL هم‌سایتِ کشف‌شده کوئری introspection فرستاده شود (پیش‌فرض خاموش)
+	GraphQLIntrospect bool `json:"graphql_introspect,omitempty" bson:"graphql_introspect,omitempty"`
+
+	// برای صفحاتی که taint flow دارند payload canary فرستاده و sink تأیید شود؛ فقط روی hostهای مجاز سایت (پیش‌فرض خاموش)
+	VerifyXSS bool `json:"verify_xss,omitempty" bson:"verify_xss,omitempty"`
+
+	// ناوبری با payloadهای __proto__/constructor.prototype در query و hash و بررسی Object.prototype (پیش‌فرض خاموش)
+	PrototypePollution bool `json:"prototype_pollution,omitempty" bson:"prototype_pollution,omitempty"`
+
+	// شناسهٔ پروفایل احراز هویت (کوکی/هدر/دستور لاگین) برای اسکن صفحات پشت لاگین
+	AuthProfileID string `json:"auth_profile_id,omitempty" bson:"auth_profile_id,omitempty"`
+
+	// پروکسی همین اسکن؛ اگر نباشد تنظیم پروکسی سایت (در صورت وجود) استفاده می‌شود
+	Proxy *ProxyConfig `json:"proxy,omitempty" bson:"proxy,omitempty"`
+
+	// اگر ست باشد به‌جای یک صفحه، مسیرهای هم‌سایت کشف‌شده هم BFS پیمایش می‌شوند
+	Crawl *CrawlOptions `json:"crawl,omitempty" bson:"crawl,omitempty"`
+
+	// import فایل HAR به‌جای اسکن با مرورگر؛ فقط /api/import/har آن را می‌سازد
+	HARImport *HARImportSpec `json:"har_import,omitempty" bson:"har_import,omitempty"`
 }
@@ -8,4 +8,41 @@ type ScanResponse struct {
 	Errors       []string `json:"errors,omitempty"`
 	ProcessedAt  string   `json:"processed_at"`
 	PageDuration string   `json:"page_duration"`
+	CrawlID      string   `json:"crawl_id,omitempty"`
+
+	Requests    []NetworkRequest `json:"requests,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	PageTimings *PageTimings     `json:"page_timings,omitempty"`
+
+	EndpointHints map[string]*EndpointHint `json:"endpoint_hints,omitempty"` // کلید: مسیر داخل UniquePaths
+	SourceMaps    []SourceMapInfo          `json:"source_maps,omitempty"`
+	Findings      []FindingDoc             `json:"findings,omitempty"`
+	MappedSinks   []SinkDoc                `json:"-"`                     // سینک‌های کد اصلی (از source map)؛ در مرحلهٔ sinks ذخیره می‌شوند
+	HTML          string                   `json:"-"`                     // DOM نهایی؛ فقط برای hash در page_snapshots
+	ScriptBodies  map[string]string        `json:"-"`                     // اسکریپت‌های اجراشده (کلید CollectScripts)
+	SnapshotID    string                   `json:"snapshot_id,omitempty"` // سند page_snapshots این اسکن (SaveScanResponse پر می‌کند)
+	GraphQL       *GraphQLResult           `json:"graphql,omitempty"`
+	Secrets       []SecretDoc              `json:"secrets,omitempty"` // مقدارها ماسک‌شده‌اند
+
+	// برای داده‌های import‌شده (خالی یعنی اسکن زنده)
+	Proxy    string `json:"proxy,omitempty"` // پروکسی استفاده‌شده (بدون رمز)
+	Origin   string `json:"origin,omitempty"`
+	ImportID string `json:"import_id,omitempty"`
+}
+
+// SourceMapInfo: source map کشف‌شده برای یک اسکریپت
+type SourceMapInfo struct {
+	ScriptURL   string `json:"script_url"`
+	MapURL      string `json:"map_url"`         // برای map درون‌خطی: "data:" (بدون محتوا)
+	Via         string `json:"via"`             // debugger | comment | header
+	Sources     int    `json:"sources"`         // تعداد فایل‌های اصلی
+	WithContent int    `json:"with_content"`    // فایل‌هایی که sourcesContent دارند
+	Error       string `json:"error,omitempty"` // دریافت یا parse ناموفق
+}
+
+// EndpointHint: کدام extractor مسیر را پیدا کرد و (برای AST) با چه فراخوانی و متدی
+type EndpointHint struct {
+	Extractors []string `json:"extractors"`
+	Methods    []string `json:"methods,omitempty"`
+	Contexts   []string `json:"contexts,omitempty"` // fetch | xhr | axios | jquery | client
 }
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// وضعیت‌های یک job اسکن
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobSaving  = "saving"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+type ScanJobTimings struct {
+	QueuedMs int64 `bson:"queued_ms,omitempty" json:"queued_ms,omitempty"`
+	ScanMs   int64 `bson:"scan_ms,omitempty"   json:"scan_ms,omitempty"`
+	SaveMs   int64 `bson:"save_ms,omitempty"   json:"save_ms,omitempty"`
+	TotalMs  int64 `bson:"total_ms,omitempty"  json:"total_ms,omitempty"`
+}
+
+type ScanJobResult struct {
+	Resources int    `bson:"resources"               json:"resources"`
+	Endpoints int    `bson:"endpoints"               json:"endpoints"`
+	Scripts   int    `bson:"scripts"                 json:"scripts"`
+	Sinks     int    `bson:"sinks"                   json:"sinks"`
+	Verified  int    `bson:"xss_verified,omitempty"  json:"xss_verified,omitempty"`              // sinkهای تأییدشده با canary
+	Polluted  int    `bson:"prototype_pollution,omitempty" json:"prototype_pollution,omitempty"` // آلودگی‌های تأییدشدهٔ Object.prototype
+	Duration  string `bson:"page_duration,omitempty" json:"page_duration,omitempty"`
+}
+
+type ScanJobDoc struct {
+	ID         string           `bson:"_id"                   json:"id"`
+	Request    ScanRequest      `bson:"request"               json:"request"`
+	SiteID     string           `bson:"site_id"               json:"site_id"`
+	URLNorm    string           `bson:"url_norm"              json:"url_norm"`
+	State      string           `bson:"state"                 json:"state"`
+	Attempts   int              `bson:"attempts"              json:"attempts"`
+	Error      string           `bson:"error,omitempty"       json:"error,omitempty"`
+	ScanErrors []string         `bson:"scan_errors,omitempty" json:"scan_errors,omitempty"`
+	Result     *ScanJobResult   `bson:"result,omitempty"      json:"result,omitempty"`
+	Timings    ScanJobTimings   `bson:"timings"               json:"timings"`
+	PageLink   string           `bson:"page_link,omitempty"   json:"page_link,omitempty"`
+	CrawlID    string           `bson:"crawl_id,omitempty"    json:"crawl_id,omitempty"`
+	Import     *HARImportResult `bson:"import,omitempty"    json:"import,omitempty"`
+	CreatedAt  time.Time        `bson:"created_at"            json:"created_at"`
+	StartedAt  time.Time        `bson:"started_at,omitempty"  json:"started_at,omitempty"`
+	FinishedAt time.Time        `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	UpdatedAt  time.Time        `bson:"updated_at"            json:"updated_at"`
+}
+
+func ScanJobsColl() *mongo.Collection { return DB.Collection("scan_jobs") }
+
+func EnsureScanJobIndexes(ctx context.Context) error {
+	_, err := ScanJobsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("q_state_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("q_site_recent"),
+		},
+	})
+	return err
+}
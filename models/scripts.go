@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScriptBlobDoc: محتوای اسکریپت (gzip) با کلید sha256؛ SnapshotScript.SHA256 به همین _id اشاره می‌کند
+type ScriptBlobDoc struct {
+	SHA256    string    `bson:"_id"        json:"sha256"`
+	Size      int       `bson:"size"       json:"size"`
+	GzipSize  int       `bson:"gzip_size"  json:"gzip_size"`
+	Body      []byte    `bson:"body"       json:"-"` // gzip
+	FirstSeen time.Time `bson:"first_seen" json:"first_seen"`
+}
+
+// ScriptVersionDoc: یک نسخه (hash) از یک URL اسکریپت خارجی در یک سایت
+type ScriptVersionDoc struct {
+	ID        string          `bson:"_id"        json:"id"` // sha256(site_id|url|sha256)
+	SiteID    string          `bson:"site_id"    json:"site_id"`
+	URL       string          `bson:"url"        json:"url"`
+	SHA256    string          `bson:"sha256"     json:"sha256"`
+	Size      int             `bson:"size"       json:"size"`
+	FirstSeen time.Time       `bson:"first_seen" json:"first_seen"`
+	LastSeen  time.Time       `bson:"last_seen"  json:"last_seen"`
+	PageURL   string          `bson:"page_url"   json:"page_url"` // اولین صفحه‌ای که این نسخه را اجرا کرد
+	Endpoints []string        `bson:"endpoints"  json:"endpoints"`
+	Sinks     []ScriptSinkRef `bson:"sinks"      json:"sinks"`
+}
+
+// ScriptSinkRef: sink استاتیک درون یک نسخه؛ Key بدون line تا جابه‌جایی کد در diff تغییر حساب نشود
+type ScriptSinkRef struct {
+	Key      string `bson:"key"                json:"key"`
+	Kind     string `bson:"kind"               json:"kind"`
+	Line     int    `bson:"line"               json:"line"`
+	Severity string `bson:"severity,omitempty" json:"severity,omitempty"`
+	Snippet  string `bson:"snippet,omitempty"  json:"snippet,omitempty"`
+}
+
+// ScriptVersionChange: خلاصهٔ تغییر یک URL اسکریپت بین دو نسخه (diff و اعلان واچ)
+type ScriptVersionChange struct {
+	URL              string          `bson:"url"                         json:"url"`
+	FromSHA256       string          `bson:"from_sha256"                 json:"from_sha256"`
+	ToSHA256         string          `bson:"to_sha256"                   json:"to_sha256"`
+	FromSize         int             `bson:"from_size,omitempty"         json:"from_size,omitempty"`
+	ToSize           int             `bson:"to_size,omitempty"           json:"to_size,omitempty"`
+	AddedEndpoints   []string        `bson:"added_endpoints,omitempty"   json:"added_endpoints"`
+	RemovedEndpoints []string        `bson:"removed_endpoints,omitempty" json:"removed_endpoints"`
+	AddedSinks       []ScriptSinkRef `bson:"added_sinks,omitempty"       json:"added_sinks"`
+	RemovedSinks     []ScriptSinkRef `bson:"removed_sinks,omitempty"     json:"removed_sinks"`
+}
+
+func ScriptsColl() *mongo.Collection        { return DB.Collection("scripts") }
+func ScriptVersionsColl() *mongo.Collection { return DB.Collection("script_versions") }
+
+func EnsureScriptIndexes(ctx context.Context) error {
+	_, err := ScriptVersionsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "url", Value: 1}, {Key: "first_seen", Value: -1}},
+			Options: options.Index().SetName("q_url_versions"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "url", Value: 1}, {Key: "first_seen", Value: -1}},
+			Options: options.Index().SetName("q_site_url_versions"),
+		},
+	})
+	return err
+}
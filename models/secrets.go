@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SecretDoc: کلید/توکن نشت‌کرده در HTML یا اسکریپت؛ مقدار خام هیچ‌جا ذخیره نمی‌شود
+type SecretDoc struct {
+	Sig         string    `bson:"sig"                   json:"sig"` // مثل sinkSig: site + page + source + rule + line:col + hash مقدار
+	SiteID      string    `bson:"site_id"               json:"site_id"`
+	PageURL     string    `bson:"page_url"              json:"page_url"`
+	SourceType  string    `bson:"source_type"           json:"source_type"` // html | inline | script
+	SourceURL   string    `bson:"source_url"            json:"source_url"`
+	RuleID      string    `bson:"rule_id"               json:"rule_id"`
+	Description string    `bson:"description"           json:"description"`
+	Severity    string    `bson:"severity"              json:"severity"`
+	Masked      string    `bson:"masked"                json:"masked"`
+	ValueHash   string    `bson:"value_hash"            json:"value_hash"` // sha256 مقدار؛ برای پیدا کردن همان کلید در جاهای دیگر
+	Entropy     float64   `bson:"entropy,omitempty"     json:"entropy,omitempty"`
+	Line        int       `bson:"line"                  json:"line"`
+	Col         int       `bson:"col"                   json:"col"`
+	Context     string    `bson:"context,omitempty"     json:"context,omitempty"` // اطراف مقدار، با مقدار ماسک‌شده
+	Origin      string    `bson:"origin,omitempty"      json:"origin,omitempty"`
+	FirstSeen   time.Time `bson:"first_seen,omitempty"  json:"first_seen,omitempty"`
+	LastSeen    time.Time `bson:"last_seen,omitempty"   json:"last_seen,omitempty"`
+	Hits        int64     `bson:"hits,omitempty"        json:"hits,omitempty"`
+}
+
+func SecretsColl() *mongo.Collection { return DB.Collection("secrets") }
+
+func EnsureSecretIndexes(ctx context.Context) error {
+	_, err := SecretsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sig", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("uniq_sig"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "rule_id", Value: 1}, {Key: "last_seen", Value: -1}},
+			Options: options.Index().SetName("q_site_rule_recent"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "page_url", Value: 1}, {Key: "first_seen", Value: -1}},
+			Options: options.Index().SetName("q_site_page_first"),
+		},
+		{
+			Keys:    bson.D{{Key: "value_hash", Value: 1}},
+			Options: options.Index().SetName("q_value_hash"),
+		},
+	})
+	return err
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PageSnapshot: وضعیت صفحه در یک اسکن (append-only)؛ pages فقط آخرین وضعیت را نگه می‌دارد
+type PageSnapshot struct {
+	ID            string           `bson:"_id"                      json:"id"`
+	SiteID        string           `bson:"site_id"                  json:"site_id"`
+	URLNorm       string           `bson:"url_norm"                 json:"url_norm"`
+	URL           string           `bson:"url"                      json:"url"`
+	ScannedAt     time.Time        `bson:"scanned_at"               json:"scanned_at"`
+	Origin        string           `bson:"origin,omitempty"         json:"origin,omitempty"`
+	CrawlID       string           `bson:"crawl_id,omitempty"       json:"crawl_id,omitempty"`
+	ImportID      string           `bson:"import_id,omitempty"      json:"import_id,omitempty"`
+	Title         string           `bson:"title,omitempty"          json:"title,omitempty"`
+	HTMLHash      string           `bson:"html_hash,omitempty"      json:"html_hash,omitempty"` // sha256 HTML نهایی (DOM)
+	HTMLSize      int              `bson:"html_size,omitempty"      json:"html_size,omitempty"`
+	Endpoints     []string         `bson:"endpoints"                json:"endpoints"`
+	Scripts       []SnapshotScript `bson:"scripts"                  json:"scripts"`
+	ExternalHosts []string         `bson:"external_hosts"           json:"external_hosts"`
+	Secrets       []SnapshotSecret `bson:"secrets,omitempty"        json:"secrets,omitempty"`
+	// بعد از مرحلهٔ sinks پر می‌شود؛ nil یعنی sinkها برای این اسکن اجرا نشد
+	Sinks   []SnapshotSink `bson:"sinks,omitempty"    json:"sinks,omitempty"`
+	SinksAt *time.Time     `bson:"sinks_at,omitempty" json:"sinks_at,omitempty"`
+}
+
+// SnapshotScript: اسکریپت اجراشده؛ inlineها URL مشترک pageURL#inline دارند و با hash از هم جدا می‌شوند
+type SnapshotScript struct {
+	URL    string `bson:"url"    json:"url"`
+	SHA256 string `bson:"sha256" json:"sha256"`
+	Size   int    `bson:"size"   json:"size"`
+	Inline bool   `bson:"inline,omitempty" json:"inline,omitempty"`
+}
+
+// SnapshotSink: کلید بدون line/col تا جابه‌جایی کد (فرمت/minify) در diff تغییر حساب نشود
+type SnapshotSink struct {
+	Key       string `bson:"key"                json:"key"`
+	Sig       string `bson:"sig"                json:"sig"`
+	Kind      string `bson:"kind"               json:"kind"`
+	SourceURL string `bson:"source_url"         json:"source_url"`
+	Line      int    `bson:"line,omitempty"     json:"line,omitempty"`
+	Severity  string `bson:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// SnapshotSecret: secret با کلید value_hash (نه sig) تا جابه‌جایی در کد secret جدید حساب نشود
+type SnapshotSecret struct {
+	ValueHash string `bson:"value_hash" json:"value_hash"`
+	RuleID    string `bson:"rule_id"    json:"rule_id"`
+	Severity  string `bson:"severity"   json:"severity"`
+	Masked    string `bson:"masked"     json:"masked"`
+	SourceURL string `bson:"source_url" json:"source_url"`
+}
+
+func PageSnapshotsColl() *mongo.Collection { return DB.Collection("page_snapshots") }
+
+func EnsurePageSnapshotIndexes(ctx context.Context) error {
+	_, err := PageSnapshotsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "url_norm", Value: 1}, {Key: "scanned_at", Value: -1}},
+			Options: options.Index().SetName("q_url_recent"),
+		},
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "scanned_at", Value: -1}},
+			Options: options.Index().SetName("q_site_recent"),
+		},
+	})
+	return err
+}
+
+// SnapshotDiff: خروجی /api/pages/diff
+type SnapshotDiff struct {
+	From          SnapshotRef `json:"from"`
+	To            SnapshotRef `json:"to"`
+	HTMLChanged   bool        `json:"html_changed"`
+	Endpoints     StringsDiff `json:"endpoints"`
+	ExternalHosts StringsDiff `json:"external_hosts"`
+	Scripts       ScriptsDiff `json:"scripts"`
+	SinksCompared bool        `json:"sinks_compared"` // false: یکی از دو اسکن sink نداشت
+	Sinks         struct {
+		Added   []SnapshotSink `json:"added"`
+		Removed []SnapshotSink `json:"removed"`
+	} `json:"sinks"`
+	Secrets struct {
+		Added   []SnapshotSecret `json:"added"`
+		Removed []SnapshotSecret `json:"removed"`
+	} `json:"secrets"`
+}
+
+type SnapshotRef struct {
+	ID        string    `json:"id"`
+	ScannedAt time.Time `json:"scanned_at"`
+	Origin    string    `json:"origin,omitempty"`
+	HTMLHash  string    `json:"html_hash,omitempty"`
+}
+
+type StringsDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type ScriptsDiff struct {
+	Added   []SnapshotScript `json:"added"`
+	Removed []SnapshotScript `json:"removed"`
+	Changed []ScriptChange   `json:"changed"` // همان URL با محتوای متفاوت
+}
+
+type ScriptChange struct {
+	URL        string `json:"url"`
+	FromSHA256 string `json:"from_sha256"`
+	ToSHA256   string `json:"to_sha256"`
+	FromSize   int    `json:"from_size"`
+	ToSize     int    `json:"to_size"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	WatchRunOK      = "ok"
+	WatchRunPartial = "partial" // اسکن انجام شد ولی مرحله‌ای (ذخیره، delta، دریافت اسکریپت) خطا داشت
+	WatchRunFailed  = "failed"  // اسکن صفحه انجام نشد
+
+	WatchTriggerSchedule = "schedule"
+	WatchTriggerManual   = "manual" // /api/watches/scan-now
+
+	NotifySent    = "sent"
+	NotifySkipped = "skipped"
+	NotifyFailed  = "failed"
+)
+
+// WatchRunDoc: یک اجرای واچ (زمان‌بندی یا scan-now)؛ با TTL روی expire_at پاک می‌شود
+type WatchRunDoc struct {
+	ID         string    `bson:"_id"         json:"id"`
+	SiteID     string    `bson:"site_id"     json:"site_id"`
+	URLNorm    string    `bson:"url_norm"    json:"url_norm"`
+	URL        string    `bson:"url"         json:"url"`
+	Trigger    string    `bson:"trigger"     json:"trigger"`
+	StartedAt  time.Time `bson:"started_at"  json:"started_at"`
+	EndedAt    time.Time `bson:"ended_at"    json:"ended_at"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+	Status     string    `bson:"status"      json:"status"`
+
+	Error      string   `bson:"error,omitempty"       json:"error,omitempty"`       // خطای اسکن (RunScan)
+	Errors     []string `bson:"errors,omitempty"      json:"errors,omitempty"`      // خطای مراحل بعد از اسکن
+	ScanErrors []string `bson:"scan_errors,omitempty" json:"scan_errors,omitempty"` // ScanResponse.Errors
+
+	SnapshotID    string              `bson:"snapshot_id,omitempty"   json:"snapshot_id,omitempty"`
+	Changed       bool                `bson:"changed"                 json:"changed"`
+	Delta         *WatchDelta         `bson:"delta,omitempty"         json:"delta,omitempty"`
+	Summary       WatchSummary        `bson:"summary"                 json:"summary"`
+	Notifications []WatchNotification `bson:"notifications,omitempty" json:"notifications,omitempty"`
+
+	ExpireAt time.Time `bson:"expire_at" json:"expire_at"`
+}
+
+// WatchNotification: نتیجهٔ ارسال یک اعلان در یک کانال
+type WatchNotification struct {
+	Channel string    `bson:"channel"          json:"channel"` // discord
+	Kind    string    `bson:"kind"             json:"kind"`    // changes | scripts
+	Status  string    `bson:"status"           json:"status"`  // sent | skipped | failed
+	Reason  string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	Error   string    `bson:"error,omitempty"  json:"error,omitempty"`
+	At      time.Time `bson:"at"               json:"at"`
+}
+
+func WatchRunsColl() *mongo.Collection { return DB.Collection("watch_runs") }
+
+func EnsureWatchRunIndexes(ctx context.Context) error {
+	_, err := WatchRunsColl().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "site_id", Value: 1}, {Key: "url_norm", Value: 1}, {Key: "started_at", Value: -1}},
+			Options: options.Index().SetName("q_watch_recent"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "started_at", Value: -1}},
+			Options: options.Index().SetName("q_status_recent"),
+		},
+		{
+			// هر سند تاریخ انقضای خودش را دارد تا تغییر retention نیازی به ساخت دوبارهٔ ایندکس نداشته باشد
+			Keys:    bson.D{{Key: "expire_at", Value: 1}},
+			Options: options.Index().SetName("ttl_expire").SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// WatchRunsSetting: نگه‌داری تاریخچهٔ اجراها در settings با _id = "watch_runs"
+type WatchRunsSetting struct {
+	ID            string    `bson:"_id"            json:"id"`
+	RetentionDays int       `bson:"retention_days" json:"retention_days"`
+	UpdatedAt     time.Time `bson:"updated_at"     json:"updated_at"`
+}
+
+const (
+	WatchRunsSettingID           = "watch_runs"
+	DefaultWatchRunRetentionDays = 30
+	MaxWatchRunRetentionDays     = 365
+)
+
+// GetWatchRunsSetting: اگر ذخیره نشده بود، مقدار پیش‌فرض
+func GetWatchRunsSetting(ctx context.Context) (WatchRunsSetting, error) {
+	out := WatchRunsSetting{ID: WatchRunsSettingID, RetentionDays: DefaultWatchRunRetentionDays}
+	err := SettingsColl().FindOne(ctx, bson.M{"_id": WatchRunsSettingID}).Decode(&out)
+	if err == mongo.ErrNoDocuments {
+		return out, nil
+	}
+	if out.RetentionDays <= 0 {
+		out.RetentionDays = DefaultWatchRunRetentionDays
+	}
+	return out, err
+}
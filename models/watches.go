@@ -10,11 +10,47 @@ import (
 )
 
 type WatchSummary struct {
-	Endpoints int       `bson:"endpoints,omitempty" json:"endpoints,omitempty"`
-	Sinks     int       `bson:"sinks,omitempty"     json:"sinks,omitempty"`
-	LastEP    time.Time `bson:"last_ep,omitempty"   json:"last_ep,omitempty"`
-	LastSink  time.Time `bson:"last_sink,omitempty" json:"last_sink,omitempty"`
-	Digest    string    `bson:"digest,omitempty"    json:"digest,omitempty"`
+	Endpoints  int       `bson:"endpoints,omitempty" json:"endpoints,omitempty"`
+	Sinks      int       `bson:"sinks,omitempty"     json:"sinks,omitempty"`
+	LastEP     time.Time `bson:"last_ep,omitempty"   json:"last_ep,omitempty"`
+	LastSink   time.Time `bson:"last_sink,omitempty" json:"last_sink,omitempty"`
+	Secrets    int       `bson:"secrets,omitempty"   json:"secrets,omitempty"`
+	LastSecret time.Time `bson:"last_secret,omitempty" json:"last_secret,omitempty"` // first_seen جدیدترین secret
+	// sinkها به تفکیک severity (برای اولویت‌بندی در اعلان)
+	SinkSeverity map[string]int `bson:"sink_severity,omitempty" json:"sink_severity,omitempty"`
+	// sink + secret با severity >= min_severity واچ؛ LastSevere = جدیدترین first seen
+	Severe     int       `bson:"severe,omitempty"      json:"severe,omitempty"`
+	LastSevere time.Time `bson:"last_severe,omitempty" json:"last_severe,omitempty"`
+	Digest     string    `bson:"digest,omitempty"    json:"digest,omitempty"`
+	// اسکریپت‌های واچ‌شده‌ای که در همین اجرا hash جدید داشتند
+	ScriptChanges []ScriptVersionChange `bson:"script_changes,omitempty" json:"script_changes,omitempty"`
+}
+
+// WatchDelta: تفاضل مجموعه‌ای این اجرا با اجرای قبلی واچ (بر اساس page_snapshots)
+type WatchDelta struct {
+	FromSnapshot string `bson:"from_snapshot,omitempty" json:"from_snapshot,omitempty"`
+	ToSnapshot   string `bson:"to_snapshot,omitempty"   json:"to_snapshot,omitempty"`
+	Baseline     bool   `bson:"baseline,omitempty"      json:"baseline,omitempty"` // اجرای اول؛ چیزی برای مقایسه نبود
+
+	EndpointsAdded     []string         `bson:"endpoints_added,omitempty"      json:"endpoints_added,omitempty"`
+	EndpointsRemoved   []string         `bson:"endpoints_removed,omitempty"    json:"endpoints_removed,omitempty"`
+	SinksCompared      bool             `bson:"sinks_compared"                 json:"sinks_compared"`
+	SinksAdded         []SnapshotSink   `bson:"sinks_added,omitempty"          json:"sinks_added,omitempty"`
+	ExternalHostsAdded []string         `bson:"external_hosts_added,omitempty" json:"external_hosts_added,omitempty"`
+	ScriptsAdded       []SnapshotScript `bson:"scripts_added,omitempty"        json:"scripts_added,omitempty"` // فقط اسکریپت‌های خارجی
+	ScriptsChanged     []ScriptChange   `bson:"scripts_changed,omitempty"      json:"scripts_changed,omitempty"`
+	SecretsAdded       []SnapshotSecret `bson:"secrets_added,omitempty"        json:"secrets_added,omitempty"`
+}
+
+func (d *WatchDelta) Empty() bool {
+	return len(d.EndpointsAdded) == 0 && len(d.EndpointsRemoved) == 0 && len(d.SinksAdded) == 0 &&
+		len(d.ExternalHostsAdded) == 0 && len(d.ScriptsAdded) == 0 && len(d.ScriptsChanged) == 0 && len(d.SecretsAdded) == 0
+}
+
+// WatchScript: hash آخرین نسخهٔ دیده‌شدهٔ یک اسکریپت واچ‌شده
+type WatchScript struct {
+	URL    string `bson:"url"    json:"url"`
+	SHA256 string `bson:"sha256" json:"sha256"`
 }
 
 type WatchDoc struct {
@@ -28,8 +64,16 @@ type WatchDoc struct {
 	LastRunAt   time.Time    `bson:"last_run_at"     json:"last_run_at"`
 	LastChange  time.Time    `bson:"last_change_at,omitempty" json:"last_change_at,omitempty"`
 	LastSummary WatchSummary `bson:"last_summary,omitempty"   json:"last_summary,omitempty"`
-	CreatedAt   time.Time    `bson:"created_at"      json:"created_at"`
-	UpdatedAt   time.Time    `bson:"updated_at"      json:"updated_at"`
+	AuthProfile string       `bson:"auth_profile_id,omitempty" json:"auth_profile_id,omitempty"`
+	MinSeverity string       `bson:"min_severity,omitempty"    json:"min_severity,omitempty"` // اگر ست باشد فقط یافته‌های جدید >= این سطح اعلان می‌شوند
+	// URL اسکریپت‌هایی که تغییرشان جدا اعلان می‌شود؛ "*" یعنی همهٔ اسکریپت‌های خارجی صفحه
+	Scripts     []string      `bson:"scripts,omitempty"      json:"scripts,omitempty"`
+	LastScripts []WatchScript `bson:"last_scripts,omitempty" json:"last_scripts,omitempty"`
+	// snapshot آخرین اجرا؛ مبنای delta اجرای بعد
+	LastSnapshotID string      `bson:"last_snapshot_id,omitempty" json:"last_snapshot_id,omitempty"`
+	LastDelta      *WatchDelta `bson:"last_delta,omitempty"       json:"last_delta,omitempty"`
+	CreatedAt      time.Time   `bson:"created_at"      json:"created_at"`
+	UpdatedAt      time.Time   `bson:"updated_at"      json:"updated_at"`
 }
 
 // ⬅️ اینجا هم از DB.Collection استفاده کن
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// XSSVerifySetting: لیست hostهای مجاز برای تأیید فعال XSS هر سایت در settings با _id = "xss_verify:<site_id>"
+// payload فقط به hostهای این لیست فرستاده می‌شود؛ لیست خالی یعنی تأیید غیرفعال
+type XSSVerifySetting struct {
+	ID         string    `bson:"_id"         json:"id"`
+	SiteID     string    `bson:"site_id"     json:"site_id"`
+	AllowHosts []string  `bson:"allow_hosts" json:"allow_hosts"` // "example.com" یا "*.example.com"
+	UpdatedAt  time.Time `bson:"updated_at"  json:"updated_at"`
+}
+
+func XSSVerifySettingID(siteID string) string { return "xss_verify:" + siteID }
+
+// GetXSSVerifySetting: اگر برای سایت تنظیمی نبود (nil, nil)
+func GetXSSVerifySetting(ctx context.Context, siteID string) (*XSSVerifySetting, error) {
+	var out XSSVerifySetting
+	err := SettingsColl().FindOne(ctx, bson.M{"_id": XSSVerifySettingID(siteID)}).Decode(&out)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SinkVerification: اجرای payload canary که sink را تأیید کرد (قابل تکرار با URL/payload)
+type SinkVerification struct {
+	Source     string    `bson:"source"      json:"source"` // location.hash | location.search | window.name | postMessage
+	Method     string    `bson:"method"      json:"method"` // executed | rendered
+	URL        string    `bson:"url"         json:"url"`
+	Payload    string    `bson:"payload"     json:"payload"`
+	VerifiedAt time.Time `bson:"verified_at" json:"verified_at"`
+}
+
+// XSSVerifyAttempt: نتیجهٔ یک ناوبری با payload
+type XSSVerifyAttempt struct {
+	Source   string `json:"source"`
+	Payload  string `json:"payload"`
+	URL      string `json:"url"`
+	Verified bool   `json:"verified"`
+	Method   string `json:"method,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	Sinks []XSSFiredSink `json:"sinks,omitempty"` // sinkهایی که payload واقعاً به آن‌ها رسید
+}
+
+// XSSFiredSink: callsite یک sink که آرگومانش payload این تلاش را داشت
+type XSSFiredSink struct {
+	Kind      string `json:"kind"`
+	SourceURL string `json:"source_url"`
+	Line      int    `json:"line"`
+	Col       int    `json:"col"`
+}
+
+type XSSVerifyResult struct {
+	PageURL  string             `json:"page_url"`
+	Attempts []XSSVerifyAttempt `json:"attempts"`
+	Skipped  []string           `json:"skipped,omitempty"` // sourceهایی که قابل کاشت نیستند (cookie، storage، referrer)
+	Verified int                `json:"verified"`          // تعداد sinkهای علامت‌خورده (تلاش موفق بدون sink منطبق: ۱)
+}
@@ -10,6 +10,8 @@ export const api = {
     // basics
     health: () => req("/api/health"),
     sites:  (limit = 200) => req(`/api/sites?limit=${limit}`),
+    siteOpenapiUrl: (siteId, format = "yaml") =>
+        `${API_BASE}/api/sites/openapi?download=1&format=${format}&site_id=${encodeURIComponent(siteId)}`,
 
     // scan now (one-off)
     scan: (payload) => req("/api/scan", {
@@ -18,13 +20,72 @@ export const api = {
         body: JSON.stringify(payload),
     }),
 
+    scanJob: (id) => req(`/api/scan/jobs/${encodeURIComponent(id)}`),
+    scanJobs: (siteId = "") => req(`/api/scan/jobs?site_id=${encodeURIComponent(siteId)}`),
+
+    // page traffic
+    pageRequests: (url) => req(`/api/pages/requests?url=${encodeURIComponent(url)}`),
+    importHar: (file, keepBodies = false) => {
+        const fd = new FormData();
+        fd.append("file", file);
+        return req(`/api/import/har${keepBodies ? "?keep_bodies=1" : ""}`, { method: "POST", body: fd });
+    },
+    pageHarUrl: (url) => `${API_BASE}/api/pages/har?download=1&url=${encodeURIComponent(url)}`,
+
+    // page snapshots (تاریخچهٔ اسکن‌ها و diff)
+    pageSnapshots: (url, skip = 0) =>
+        req(`/api/pages/snapshots?url=${encodeURIComponent(url)}&skip=${skip}`),
+    pageSnapshot: (id) => req(`/api/pages/snapshots/get?id=${encodeURIComponent(id)}`),
+    pageDiff: (url, from = "", to = "") =>
+        req(`/api/pages/diff?url=${encodeURIComponent(url)}${from ? `&from=${encodeURIComponent(from)}` : ""}${to ? `&to=${encodeURIComponent(to)}` : ""}`),
+
+    // script versions (محتوای ذخیره‌شده و diff بین نسخه‌ها)
+    scriptVersions: (url, siteId = "") =>
+        req(`/api/scripts/versions?url=${encodeURIComponent(url)}${siteId ? `&site_id=${encodeURIComponent(siteId)}` : ""}`),
+    scriptUrl: (sha256) => `${API_BASE}/api/scripts/get?sha256=${encodeURIComponent(sha256)}`,
+    scriptDiff: (url, from = "", to = "") =>
+        req(`/api/scripts/diff?url=${encodeURIComponent(url)}${from ? `&from=${encodeURIComponent(from)}` : ""}${to ? `&to=${encodeURIComponent(to)}` : ""}`),
+
+    // findings (exposed source maps, ...)
+    findings: (siteId, type = "") =>
+        req(`/api/findings?site_id=${encodeURIComponent(siteId)}${type ? `&type=${encodeURIComponent(type)}` : ""}`),
+    findingsStats: (siteId) => req(`/api/findings/stats?site_id=${encodeURIComponent(siteId)}`),
+
+    // secrets (مقدارها ماسک‌شده)
+    secrets: (siteId, rule = "") =>
+        req(`/api/secrets?site_id=${encodeURIComponent(siteId)}${rule ? `&rule=${encodeURIComponent(rule)}` : ""}`),
+    secretsStats: (siteId) => req(`/api/secrets/stats?site_id=${encodeURIComponent(siteId)}`),
+
+    // sink rules (built-in + SINK_RULES_FILE)
+    rules: (reload = false) => req(`/api/rules${reload ? "?reload=1" : ""}`),
+    rulesValidate: (yamlText) => req("/api/rules/validate", {
+        method: "POST",
+        headers: { "Content-Type": "application/yaml" },
+        body: yamlText,
+    }),
+
+    // graphql
+    graphqlOperations: (siteId, type = "") =>
+        req(`/api/graphql/operations?site_id=${encodeURIComponent(siteId)}${type ? `&type=${encodeURIComponent(type)}` : ""}`),
+    graphqlSchemas: (siteId) => req(`/api/graphql/schemas?site_id=${encodeURIComponent(siteId)}`),
+    graphqlSchemaUrl: (id) => `${API_BASE}/api/graphql/schema?download=1&id=${encodeURIComponent(id)}`,
+
     // watches
     watchesList: (siteId) => req(`/api/watches?site_id=${encodeURIComponent(siteId)}`),
-    watchCreate: ({ url, freq_min = 1440, enabled = true }) =>
+    watchCreate: ({ url, freq_min = 1440, enabled = true, auth_profile_id = "", min_severity, scripts }) =>
         req("/api/watches/create", {
             method: "POST",
             headers: { "Content-Type": "application/json" },
-            body: JSON.stringify({ url, freq_min, enabled }),
+            body: JSON.stringify({ url, freq_min, enabled, auth_profile_id, min_severity, scripts }),
+        }),
+    watchRuns: ({ siteId = "", urlNorm = "", status = "", skip = 0 } = {}) =>
+        req(`/api/watches/runs?site_id=${encodeURIComponent(siteId)}&url_norm=${encodeURIComponent(urlNorm)}${status ? `&status=${encodeURIComponent(status)}` : ""}&skip=${skip}`),
+    watchRunsSettings: () => req("/api/settings/watch-runs"),
+    watchRunsSettingsSet: (retention_days) =>
+        req("/api/settings/watch-runs/set", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ retention_days }),
         }),
     watchDelete: (url_norm) =>
         req("/api/watches/delete", {
@@ -39,6 +100,59 @@ export const api = {
             body: JSON.stringify({ url_norm }),
         }),
 
+    // auth profiles
+    authProfiles: (siteId = "") => req(`/api/auth-profiles?site_id=${encodeURIComponent(siteId)}`),
+    authProfileCreate: (profile) =>
+        req("/api/auth-profiles/create", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify(profile),
+        }),
+    authProfileUpdate: (profile) =>
+        req("/api/auth-profiles/update", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify(profile),
+        }),
+    authProfileDelete: (id) =>
+        req("/api/auth-profiles/delete", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ id }),
+        }),
+
+    // proxy settings (per site)
+    proxyGet: (siteId) => req(`/api/settings/proxy?site_id=${encodeURIComponent(siteId)}`),
+    proxySet: (cfg) =>
+        req("/api/settings/proxy/set", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify(cfg),
+        }),
+
+    // active DOM XSS verification (allow-listed hosts per site)
+    xssVerifyGet: (siteId) => req(`/api/settings/xss-verify?site_id=${encodeURIComponent(siteId)}`),
+    xssVerifySet: (siteId, allowHosts) =>
+        req("/api/settings/xss-verify/set", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ site_id: siteId, allow_hosts: allowHosts }),
+        }),
+    xssVerify: (siteId, pageUrl, url = "") =>
+        req("/api/xss/verify", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ site_id: siteId, page_url: pageUrl, url }),
+        }),
+
+    // prototype pollution (active check of one page)
+    protoPollutionCheck: (url) =>
+        req("/api/prototype-pollution/check", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ url }),
+        }),
+
     // discord settings
     discordGet: () => req("/api/settings/discord"),
     discordSet: (webhook_url, enabled) =>
@@ -1,13 +1,88 @@
-import React, {useRef, useState} from "react";
+import React, {useEffect, useRef, useState} from "react";
 
 const API_BASE = import.meta.env.VITE_API_BASE || "";
 const SCAN_API = `${API_BASE}/api/scan`;
 
+const STAGE_LABELS = {
+    browser: "راه‌اندازی مرورگر",
+    login: "ورود با پروفایل احراز هویت",
+    navigate: "بارگذاری صفحه",
+    wait: "انتظار برای اجرای اسکریپت‌ها",
+    collect: "جمع‌آوری منابع و اسکریپت‌ها",
+    extract: "استخراج مسیرها",
+    sourcemaps: "تحلیل source mapها",
+    fetch_scripts: "دریافت و تحلیل فایل‌های JS",
+    secrets: "جستجوی کلیدها و توکن‌ها",
+    graphql: "تشخیص GraphQL",
+    prototype: "الگوهای prototype pollution",
+    save: "ذخیره در دیتابیس",
+    sinks: "اسکن سینک‌ها",
+    verify: "تأیید DOM XSS با payload",
+    prototype_pollution: "آزمون prototype pollution",
+};
+
+function formatEvent(ev) {
+    const stage = STAGE_LABELS[ev.stage] || ev.stage;
+    switch (ev.type) {
+        case "stage_start":
+            return {text: `▶ ${stage}`};
+        case "stage_end":
+            return ev.error
+                ? {text: `✖ ${stage} (${ev.duration_ms}ms): ${ev.error}`, error: true}
+                : {text: `✔ ${stage} (${ev.duration_ms}ms)`};
+        case "script_error":
+            return {text: `⚠ ${ev.url}: ${ev.error}`, error: true};
+        case "state":
+            return {text: `• state: ${ev.state}${ev.error ? " — " + ev.error : ""}`, error: !!ev.error};
+        default:
+            return null;
+    }
+}
+
 export default function DomainScanner({onScanned}) {
     const [domain, setDomain] = useState("");
     const [status, setStatus] = useState("idle"); // idle | loading | success | error
     const [message, setMessage] = useState("");
+    const [log, setLog] = useState([]);
+    const [gqlIntrospect, setGqlIntrospect] = useState(false);
+    const [verifyXSS, setVerifyXSS] = useState(false);
+    const [protoPollution, setProtoPollution] = useState(false);
     const inputRef = useRef(null);
+    const esRef = useRef(null);
+
+    useEffect(() => () => esRef.current?.close(), []);
+
+    const followJob = (jobId) => {
+        esRef.current?.close();
+        const es = new EventSource(`${API_BASE}/api/scan/jobs/${encodeURIComponent(jobId)}/events`);
+        esRef.current = es;
+
+        const onEvent = (e) => {
+            let ev;
+            try { ev = JSON.parse(e.data); } catch { return; }
+            const line = formatEvent(ev);
+            if (line) setLog((prev) => [...prev, line]);
+        };
+        ["stage_start", "stage_end", "script_error", "state"].forEach((t) => es.addEventListener(t, onEvent));
+        es.addEventListener("done", (e) => {
+            es.close();
+            let ev = {};
+            try { ev = JSON.parse(e.data); } catch { /* ignore */ }
+            if (ev.state === "failed") {
+                setStatus("error");
+                setMessage(ev.error || "❌ Scan failed");
+            } else {
+                setStatus("success");
+                setMessage("اسکن کامل شد ✅");
+            }
+            onScanned && onScanned();
+        });
+        es.onerror = () => {
+            if (es.readyState === EventSource.CLOSED) {
+                setStatus((s) => (s === "loading" ? "error" : s));
+            }
+        };
+    };
 
     const isValidDomain = (v) => {
         try {
@@ -34,9 +109,10 @@ export default function DomainScanner({onScanned}) {
 
         setStatus("loading");
         setMessage("");
+        setLog([]);
 
         try {
-            const body = { url: domain.trim(), wait_sec: 7, js_fetch_timeout: 8 };
+            const body = { url: domain.trim(), wait_sec: 7, js_fetch_timeout: 8, graphql_introspect: gqlIntrospect, verify_xss: verifyXSS, prototype_pollution: protoPollution };
             const res = await fetch(SCAN_API, {
                 method: "POST",
                 headers: {"Content-Type": "application/json"},
@@ -46,12 +122,17 @@ export default function DomainScanner({onScanned}) {
                 const txt = await res.text();
                 throw new Error(txt || `HTTP ${res.status}`);
             }
-            // پاسخ سرور
-            await res.json().catch(() => ({}));
-            setStatus("success");
-            setMessage("اسکن شروع شد ✅");
+            // پاسخ سرور: شناسهٔ job
+            const data = await res.json().catch(() => ({}));
             setDomain("");
-            onScanned && onScanned();
+            if (data.job_id) {
+                setMessage("اسکن در صف قرار گرفت…");
+                followJob(data.job_id);
+            } else {
+                setStatus("success");
+                setMessage("اسکن شروع شد ✅");
+                onScanned && onScanned();
+            }
         } catch (err) {
             setStatus("error");
             setMessage(err?.message || "❌ Error starting scan");
@@ -85,12 +166,50 @@ export default function DomainScanner({onScanned}) {
                 </button>
             </div>
 
+            <label style={styles.option}>
+                <input
+                    type="checkbox"
+                    checked={gqlIntrospect}
+                    onChange={(e) => setGqlIntrospect(e.target.checked)}
+                    disabled={status === "loading"}
+                />
+                ارسال کوئری introspection به endpointهای GraphQL
+            </label>
+
+            <label style={styles.option}>
+                <input
+                    type="checkbox"
+                    checked={verifyXSS}
+                    onChange={(e) => setVerifyXSS(e.target.checked)}
+                    disabled={status === "loading"}
+                />
+                تأیید فعال DOM XSS (فقط روی hostهای مجاز سایت)
+            </label>
+
+            <label style={styles.option}>
+                <input
+                    type="checkbox"
+                    checked={protoPollution}
+                    onChange={(e) => setProtoPollution(e.target.checked)}
+                    disabled={status === "loading"}
+                />
+                آزمون prototype pollution با payload در query و hash
+            </label>
+
             {status === "loading" && (
                 <div style={styles.progressBar}>
                     <div style={styles.progressIndeterminate}/>
                 </div>
             )}
 
+            {log.length > 0 && (
+                <div style={styles.log}>
+                    {log.map((l, i) => (
+                        <div key={i} style={l.error ? styles.logError : undefined}>{l.text}</div>
+                    ))}
+                </div>
+            )}
+
             {status === "success" && (
                 <div style={{...styles.note, color: "#0a7"}}>{message}</div>
             )}
@@ -104,6 +223,7 @@ export default function DomainScanner({onScanned}) {
 const styles = {
     wrap: {display: "grid", gap: 10, maxWidth: 720, margin: "12px auto"},
     row: {display: "grid", gridTemplateColumns: "1fr 120px", gap: 8},
+    option: {display: "flex", alignItems: "center", gap: 6, fontSize: 13, color: "#555"},
     inputWrap: {
         position: "relative",
         display: "flex",
@@ -128,6 +248,12 @@ const styles = {
         background: "#2b6ff7", borderRadius: 999,
     },
     note: {fontSize: 13},
+    log: {
+        maxHeight: 220, overflowY: "auto", direction: "ltr", textAlign: "left",
+        fontFamily: "ui-monospace, SFMono-Regular, Menlo, monospace", fontSize: 12,
+        background: "#f7f9fc", border: "1px solid #e1e5ea", borderRadius: 8, padding: "8px 10px",
+    },
+    logError: {color: "#c33"},
 };
 
 if (typeof document !== "undefined" && !document.getElementById("indet-kf")) {
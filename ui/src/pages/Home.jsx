@@ -189,8 +189,8 @@ export default function Home(){
         }
     }
 
-    async function createWatch(url, freq_min=1440){
-        await postJSON('/api/watches/create', { url, freq_min, enabled:true })
+    async function createWatch(url, freq_min=1440, min_severity='', scripts=[]){
+        await postJSON('/api/watches/create', { url, freq_min, enabled:true, min_severity, scripts })
     }
     async function deleteWatch(url_norm){
         await postJSON('/api/watches/delete', { url_norm })
@@ -399,13 +399,26 @@ export default function Home(){
                                                     <option value="1440">24h</option>
                                                     <option value="10080">1w</option>
                                                 </select>
+                                                <select id={`watch-sev-${siteId}`} className="border border-zinc-300 rounded-xl px-2 py-2 text-sm" defaultValue="" title="Alert only on new findings at or above">
+                                                    <option value="">any change</option>
+                                                    <option value="low">≥ low</option>
+                                                    <option value="medium">≥ medium</option>
+                                                    <option value="high">≥ high</option>
+                                                    <option value="critical">critical</option>
+                                                </select>
+                                                <label className="flex items-center gap-1 text-sm" title="Separate alert when any script on the page changes">
+                                                    <input id={`watch-scripts-${siteId}`} type="checkbox" />
+                                                    scripts
+                                                </label>
                                                 <button
                                                     className="px-3 py-2 rounded-lg bg-zinc-900 text-white text-sm"
                                                     onClick={async()=>{
                                                         const url = document.getElementById(`watch-url-${siteId}`).value.trim();
                                                         const freq = +document.getElementById(`watch-freq-${siteId}`).value;
+                                                        const sev = document.getElementById(`watch-sev-${siteId}`).value;
+                                                        const scripts = document.getElementById(`watch-scripts-${siteId}`).checked ? ['*'] : [];
                                                         if(!url) return;
-                                                        await createWatch(url, freq);
+                                                        await createWatch(url, freq, sev, scripts);
                                                         document.getElementById(`watch-url-${siteId}`).value='';
                                                         await loadWatchesMerged(siteId);
                                                     }}
@@ -109,12 +109,13 @@ function SinksTab({ items }){
         <div className="rounded-2xl overflow-hidden border border-zinc-200 bg-white">
             <table className="w-full text-sm">
                 <thead className="bg-zinc-50 text-zinc-600">
-                <tr><th className="text-left p-2">Kind</th><th className="text-left p-2">Source</th><th className="text-left p-2">Func</th><th className="text-left p-2">Loc</th><th className="text-left p-2">Last</th></tr>
+                <tr><th className="text-left p-2">Kind</th><th className="text-left p-2">Severity</th><th className="text-left p-2">Source</th><th className="text-left p-2">Func</th><th className="text-left p-2">Loc</th><th className="text-left p-2">Last</th></tr>
                 </thead>
                 <tbody>
                 {items.map((x,i)=> (
                     <tr key={i} className="hover:bg-zinc-50">
                         <td className="p-2">{x.kind}</td>
+                        <td className="p-2" title={[x.confidence, x.cwe, x.owasp].filter(Boolean).join(' · ')}>{x.severity || 'info'}</td>
                         <td className="p-2 break-all"><a href={x.source_url} target="_blank" rel="noreferrer" className="underline decoration-dotted">{x.source_url}</a></td>
                         <td className="p-2">{x.func}</td>
                         <td className="p-2">{x.line}:{x.col}</td>